@@ -23,25 +23,41 @@ import (
 
 	osquery "github.com/Uptycs/basequery-go"
 	"github.com/Uptycs/cloudquery/extension"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 )
 
 var (
-	socket   = flag.String("socket", "", "Path to the extensions UNIX domain socket")
-	verbose  = flag.Bool("verbose", false, "Enable verbose logging")
-	timeout  = flag.Int("timeout", 10, "Seconds to wait for autoloaded extensions")
-	interval = flag.Int("interval", 10, "Seconds delay between connectivity checks")
+	socket         = flag.String("socket", "", "Path to the extensions UNIX domain socket")
+	verbose        = flag.Bool("verbose", false, "Enable verbose logging")
+	timeout        = flag.Int("timeout", 10, "Seconds to wait for autoloaded extensions")
+	interval       = flag.Int("interval", 10, "Seconds delay between connectivity checks")
+	reloadInterval = flag.Int("reload_interval", 30, "Seconds delay between checks for configuration changes, 0 to reload on SIGHUP only")
+	checkConfig    = flag.Bool("check-config", false, "Validate configuration files, print a report and exit (non-zero on errors)")
 )
 
 func main() {
 	flag.Parse()
-	if *socket == "" {
-		log.Fatalln("Missing required --socket argument")
-	}
 
 	homeDirectory := os.Getenv("CLOUDQUERY_EXT_HOME")
 	if homeDirectory == "" {
 		homeDirectory = "/opt/cloudquery"
 	}
+	utilities.HomeDirectory = homeDirectory
+
+	if *checkConfig {
+		// Log to stdout, extension configuration may not be valid
+		utilities.CreateLogger(*verbose, 0, 0, 0)
+		report := extension.CheckConfigurations(homeDirectory)
+		report.Print(os.Stdout)
+		if report.HasErrors() {
+			os.Exit(1)
+		}
+		return
+	}
+	if *socket == "" {
+		log.Fatalln("Missing required --socket argument")
+	}
 
 	server, err := osquery.NewExtensionManagerServer(
 		"cloudquery_extension",
@@ -55,7 +71,7 @@ func main() {
 		log.Fatalf("Error creating extension: %s\n", err)
 	}
 
-	extension.ReadExtensionConfigurations(homeDirectory+string(os.PathSeparator)+"config"+string(os.PathSeparator)+"extension_config.json", *verbose)
+	extension.ReadExtensionConfigurations(extension.GetExtensionConfigPath(homeDirectory), *verbose)
 	extension.ReadTableConfigurations(homeDirectory)
 	extension.RegisterPlugins(server)
 
@@ -83,6 +99,15 @@ func main() {
 		go eventTable.Start(ctx, wg, *socket, time.Second*time.Duration(*timeout))
 	}
 
+	// Start listing member accounts of AWS organizations and projects of GCP organizations and folders
+	extaws.StartOrganizationDiscovery(ctx, wg)
+	extgcp.StartProjectDiscovery(ctx, wg)
+
+	// Reload configuration on SIGHUP (kill -1) or when config files change
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	extension.WatchConfigurations(ctx, wg, homeDirectory, time.Second*time.Duration(*reloadInterval), hup)
+
 	<-quit
 	utilities.GetLogger().Info("Shutting down cloudquery")
 
@@ -12,7 +12,6 @@ package acm
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,49 +23,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// ListCertificatesColumns returns the list of columns in the table
-func ListCertificatesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("certificate_arn"),
-		table.TextColumn("domain_name"),
-		//table.TextColumn("values"),
-
-	}
-}
-
 // ListCertificatesGenerate returns the rows in the table for all configured accounts
 func ListCertificatesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_acm_certificate", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_acm_certificate",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountListCertificates(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_acm_certificate", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_acm_certificate",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountListCertificates(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_acm_certificate", processRegionListCertificates)
 }
 
 func processRegionListCertificates(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -129,37 +88,3 @@ func processRegionListCertificates(osqCtx context.Context, queryContext table.Qu
 	}
 	return resultMap, nil
 }
-
-func processAccountListCertificates(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_acm_certificate"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_acm_certificate",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_acm_certificate", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionListCertificates(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
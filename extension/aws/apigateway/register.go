@@ -0,0 +1,25 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package apigateway
+
+import (
+	_ "embed"
+
+	"github.com/Uptycs/cloudquery/utilities"
+)
+
+//go:embed table_config.json
+var defaultTableConfig []byte
+
+func init() {
+	utilities.RegisterTableConfigFile("aws/apigateway/table_config.json", defaultTableConfig)
+
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_apigateway_rest_api", Generate: GetRestApisGenerate})
+}
@@ -10,13 +10,10 @@
 package cloudtrail
 
 import (
-	"bufio"
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"sort"
 	"strings"
 	"sync"
@@ -25,7 +22,6 @@ import (
 	osquery "github.com/Uptycs/basequery-go"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
-	"github.com/patrickmn/go-cache"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Uptycs/cloudquery/utilities"
@@ -34,26 +30,17 @@ import (
 	extaws "github.com/Uptycs/cloudquery/extension/aws"
 )
 
-type ObjectMarker struct {
-	modifiedTime time.Time
-	key          string
-	prefix       string
-}
-
 // CloudTrailEventTable implements EventTable interface
 type CloudTrailEventTable struct {
 	// Marker will always be atleast markerDelayMinutes prior to current time
 	markerDelayMinutes int
-	// Map of bucketName => ObjectMarker
-	markerMap map[string]*ObjectMarker
-	// objects which we have processed in last 1 hour
-	objectCache *cache.Cache
+	// Markers (bucketName => CheckpointMarker) and objects processed in last CACHE_TIMEOUT_MINUTES
+	checkpoints *utilities.CheckpointStore
 	client      *osquery.ExtensionManagerClient
 	ctx         context.Context
-}
-
-type CloudTrailEventRecords struct {
-	Records []map[string]interface{} `json:"Records"`
+	// reload is signalled by ConfigurationChanged
+	reload     chan struct{}
+	reloadOnce sync.Once
 }
 
 var (
@@ -61,6 +48,7 @@ var (
 	LOOKBACK_MINUTES      = 20
 	CACHE_TIMEOUT_MINUTES = 120
 	LOOP_TIMER_SECONDS    = 120
+	EVENT_BATCH_SIZE      = 500 // maximum number of events sent to osquery at a time
 	TABLE_NAME            = "aws_cloudtrail_events"
 )
 
@@ -112,15 +100,14 @@ func (ct *CloudTrailEventTable) GetGenFunction() table.GenerateFunc {
 func (ct *CloudTrailEventTable) initialize(ctx context.Context, socket string, timeout time.Duration) {
 	ct.ctx = ctx
 	ct.markerDelayMinutes = MARKER_DELAY_MINUTES
-	ct.objectCache = cache.New(time.Duration(CACHE_TIMEOUT_MINUTES)*time.Minute, time.Duration(CACHE_TIMEOUT_MINUTES)*time.Minute)
-	ct.markerMap = make(map[string]*ObjectMarker)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) > 0 {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			for _, bucket := range account.CtS3Buckets {
-				ct.markerMap[bucket.Name] = nil
-			}
-		}
+	checkpoints, err := utilities.NewCheckpointStore(utilities.GetCheckpointPath(TABLE_NAME), time.Duration(CACHE_TIMEOUT_MINUTES)*time.Minute)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": TABLE_NAME,
+			"errString": err.Error(),
+		}).Error("failed to load checkpoints")
 	}
+	ct.checkpoints = checkpoints
 	ct.client, _ = osquery.NewClient(socket, timeout)
 }
 
@@ -139,6 +126,11 @@ func (ct *CloudTrailEventTable) Start(ctx context.Context, wg *sync.WaitGroup, s
 			// Shutdown
 			timer1.Stop()
 			return
+		case <-ct.getReloadChannel():
+			timer1.Stop()
+			ct.pruneCheckpoints()
+			ct.runEventLoop()
+			timer1 = time.NewTimer(time.Duration(LOOP_TIMER_SECONDS) * time.Second)
 		case <-timer1.C:
 			ct.runEventLoop()
 			timer1 = time.NewTimer(time.Duration(LOOP_TIMER_SECONDS) * time.Second)
@@ -146,6 +138,39 @@ func (ct *CloudTrailEventTable) Start(ctx context.Context, wg *sync.WaitGroup, s
 	}
 }
 
+func (ct *CloudTrailEventTable) getReloadChannel() chan struct{} {
+	ct.reloadOnce.Do(func() {
+		ct.reload = make(chan struct{}, 1)
+	})
+	return ct.reload
+}
+
+// ConfigurationChanged makes the event loop drop the checkpoints of buckets which are no longer configured
+// and collect events right away, so that new buckets don't wait for the next loop
+func (ct *CloudTrailEventTable) ConfigurationChanged() {
+	select {
+	case ct.getReloadChannel() <- struct{}{}:
+	default:
+		// Already pending
+	}
+}
+
+func (ct *CloudTrailEventTable) pruneCheckpoints() {
+	buckets := make(map[string]bool)
+	for _, account := range utilities.CurrentConfiguration().Extension.ExtConfAws.Accounts {
+		for _, bucket := range account.CtS3Buckets {
+			buckets[bucket.Name] = true
+		}
+	}
+	for _, bucket := range ct.checkpoints.PruneMarkers(buckets) {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": TABLE_NAME,
+			"bucket":    bucket,
+		}).Info("bucket is no longer configured")
+	}
+	ct.flushCheckpoints()
+}
+
 // DescribeInstancesGenerate returns the rows in the table for all configured accounts
 func (ct *CloudTrailEventTable) LookupEventsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	return nil, nil
@@ -153,50 +178,39 @@ func (ct *CloudTrailEventTable) LookupEventsGenerate(osqCtx context.Context, que
 
 func (ct *CloudTrailEventTable) runEventLoop() {
 	utilities.GetLogger().Info("Collecting events")
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) > 0 {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_acm_certificate", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": TABLE_NAME,
-				"account":   account.ID,
-			}).Info("processing account")
-			ct.processAccountLookupEvents(&account)
-		}
+	config := utilities.CurrentConfiguration()
+	for _, account := range config.Extension.ExtConfAws.Accounts {
+		ct.processAccount(config, account)
 	}
 }
 
+// processAccount collects the events of an account using given configuration, even if it is replaced meanwhile
+func (ct *CloudTrailEventTable) processAccount(config *utilities.Configuration, account utilities.ExtensionConfigurationAwsAccount) {
+	osqCtx := utilities.ContextWithConfiguration(ct.ctx, config)
+	if !extaws.ShouldProcessAccount(osqCtx, table.QueryContext{}, TABLE_NAME, account.ID) {
+		return
+	}
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": TABLE_NAME,
+		"account":   account.ID,
+	}).Info("processing account")
+	ct.processAccountLookupEvents(osqCtx, &account)
+}
+
 func (ct *CloudTrailEventTable) getPrefix(account *utilities.ExtensionConfigurationAwsAccount, bucket utilities.CtS3Bucket, startTime time.Time) string {
 	// currentTime := time.Now()
 	// pastHour := currentTime.Add(-time.Duration(1 * time.Hour))
 	return bucket.Prefix + "/" + bucket.Region + "/" + fmt.Sprintf("%04d", startTime.Year()) + "/" + fmt.Sprintf("%02d", startTime.Month()) + "/" + fmt.Sprintf("%02d", startTime.Day())
 }
 
-func (ct *CloudTrailEventTable) processRecords(account *utilities.ExtensionConfigurationAwsAccount, tableConfig *utilities.TableConfig, bucket utilities.CtS3Bucket, key string, jsonData string) error {
-	jsonObj := CloudTrailEventRecords{}
-	err := json.Unmarshal([]byte(jsonData), &jsonObj)
-	if err != nil {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": TABLE_NAME,
-			"account":   account.ID,
-			"region":    bucket.Region,
-			"task":      "LookupEvents",
-			"bucket":    bucket.Name,
-			"prefix":    bucket.Prefix,
-			"key":       key,
-			"errString": err.Error(),
-		}).Error("failed to parse S3 object data")
-		return err
-	}
-	records := jsonObj.Records
-	events := make([]map[string]string, 0)
+func (ct *CloudTrailEventTable) processRecords(osqCtx context.Context, account *utilities.ExtensionConfigurationAwsAccount, tableConfig *utilities.TableConfig, bucket utilities.CtS3Bucket, key string, records []map[string]interface{}) error {
+	events := make([]map[string]string, 0, len(records))
 	for _, record := range records {
 		event := make(map[string]string)
 		for key, value := range record {
 			event[utilities.GetSnakeCase(key)] = utilities.GetStringValue(value)
 		}
-		if !extaws.ShouldProcessEvent(TABLE_NAME, account.ID, bucket.Region, event) {
+		if !extaws.ShouldProcessEvent(osqCtx, TABLE_NAME, account.ID, bucket.Region, event) {
 			continue
 		}
 		events = append(events, event)
@@ -210,29 +224,17 @@ func (ct *CloudTrailEventTable) processRecords(account *utilities.ExtensionConfi
 		"prefix":    bucket.Prefix,
 		"key":       key,
 	}).Debug("Added events ", len(events))
-	ct.client.StreamEvents(TABLE_NAME, events)
+	if len(events) > 0 {
+		ct.client.StreamEvents(TABLE_NAME, events)
+	}
 	return nil
 }
 
-func (ct *CloudTrailEventTable) getObjectReader(account *utilities.ExtensionConfigurationAwsAccount, bucket utilities.CtS3Bucket, obj types.Object, output *s3.GetObjectOutput) (io.Reader, error) {
-	s3objectBytes, err := ioutil.ReadAll(output.Body)
-	if err != nil {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": TABLE_NAME,
-			"account":   account.ID,
-			"region":    bucket.Region,
-			"task":      "LookupEvents",
-			"bucket":    bucket.Name,
-			"prefix":    bucket.Prefix,
-			"key":       obj.Key,
-			"errString": err.Error(),
-		}).Error("failed to read S3 object data")
-		return nil, err
-	}
-
-	stringData := string(s3objectBytes[:])
+// getObjectReader returns a reader streaming (and decompressing if needed) the object body.
+// Closing it doesn't close the body
+func (ct *CloudTrailEventTable) getObjectReader(account *utilities.ExtensionConfigurationAwsAccount, bucket utilities.CtS3Bucket, obj types.Object, output *s3.GetObjectOutput) (io.ReadCloser, error) {
 	if strings.HasSuffix(*obj.Key, "gz") {
-		reader, err := gzip.NewReader(strings.NewReader(stringData))
+		reader, err := gzip.NewReader(output.Body)
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": TABLE_NAME,
@@ -247,14 +249,13 @@ func (ct *CloudTrailEventTable) getObjectReader(account *utilities.ExtensionConf
 			return nil, err
 		}
 		return reader, nil
-	} else {
-		return strings.NewReader(stringData), nil
 	}
+	return io.NopCloser(output.Body), nil
 }
 
-func (ct *CloudTrailEventTable) processSingleObject(svc *s3.Client, account *utilities.ExtensionConfigurationAwsAccount, tableConfig *utilities.TableConfig, bucket utilities.CtS3Bucket, obj types.Object) error {
-	_, found := ct.objectCache.Get(bucket.Name + *obj.Key)
-	if found {
+func (ct *CloudTrailEventTable) processSingleObject(osqCtx context.Context, svc *s3.Client, account *utilities.ExtensionConfigurationAwsAccount, tableConfig *utilities.TableConfig, bucket utilities.CtS3Bucket, obj types.Object) error {
+	object := bucket.Name + *obj.Key
+	if ct.checkpoints.IsObjectProcessed(object) {
 		// we have already processed this file
 		return nil
 	}
@@ -276,18 +277,24 @@ func (ct *CloudTrailEventTable) processSingleObject(svc *s3.Client, account *uti
 		}).Error("failed to process S3 object")
 		return err
 	}
+	defer output.Body.Close()
 	reader, err := ct.getObjectReader(account, bucket, obj, output)
 	if err != nil {
 		return err
 	}
-	r := bufio.NewReaderSize(reader, 1024*1024)
-	line, isPrefix, err := r.ReadLine()
-	for err == nil && !isPrefix {
-		lineStr := string(line)
-		ct.processRecords(account, tableConfig, bucket, *obj.Key, lineStr)
-		line, isPrefix, err = r.ReadLine()
-	}
-	if isPrefix {
+	defer reader.Close()
+	// Records emitted by a previous attempt which failed part way through the object are not streamed again
+	emitted := ct.checkpoints.GetObjectOffset(object)
+	err = decodeRecords(reader, EVENT_BATCH_SIZE, emitted, func(records []map[string]interface{}) error {
+		if err := ct.processRecords(osqCtx, account, tableConfig, bucket, *obj.Key, records); err != nil {
+			return err
+		}
+		emitted += len(records)
+		ct.checkpoints.SetObjectOffset(object, emitted)
+		ct.flushCheckpoints()
+		return nil
+	})
+	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": TABLE_NAME,
 			"account":   account.ID,
@@ -296,32 +303,32 @@ func (ct *CloudTrailEventTable) processSingleObject(svc *s3.Client, account *uti
 			"bucket":    bucket.Name,
 			"prefix":    bucket.Prefix,
 			"key":       *obj.Key,
-			"errString": "buffer size too small",
-		}).Error("failed to read S3 object data")
-		return fmt.Errorf("buffer size too small")
+			"errString": err.Error(),
+		}).Error("failed to parse S3 object data")
+		return err
 	}
-	if err != nil && err != io.EOF {
+	utilities.GetLogger().Info("Processed file ", object)
+	ct.checkpoints.AddProcessedObject(object)
+	ct.flushCheckpoints()
+	return nil
+}
+
+func (ct *CloudTrailEventTable) flushCheckpoints() {
+	if err := ct.checkpoints.Flush(); err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": TABLE_NAME,
-			"account":   account.ID,
-			"region":    bucket.Region,
-			"task":      "LookupEvents",
-			"bucket":    bucket.Name,
-			"prefix":    bucket.Prefix,
-			"key":       *obj.Key,
 			"errString": err.Error(),
-		}).Error("failed to read S3 object data")
-		return err
+		}).Error("failed to save checkpoints")
 	}
-	utilities.GetLogger().Info("Processed file ", bucket.Name+*obj.Key)
-	ct.objectCache.Add(bucket.Name+*obj.Key, true, 0)
-	return nil
 }
 
-func (ct *CloudTrailEventTable) processObjects(svc *s3.Client, account *utilities.ExtensionConfigurationAwsAccount, tableConfig *utilities.TableConfig, bucket utilities.CtS3Bucket, objs []types.Object, prefix string) {
+func (ct *CloudTrailEventTable) processObjects(osqCtx context.Context, svc *s3.Client, account *utilities.ExtensionConfigurationAwsAccount, tableConfig *utilities.TableConfig, bucket utilities.CtS3Bucket, objs []types.Object, prefix string) {
 	currentTime := time.Now()
-	currentMarker := ct.markerMap[bucket.Name]
-	if currentMarker != nil && currentMarker.prefix != prefix {
+	var currentMarker *utilities.CheckpointMarker
+	if marker, found := ct.checkpoints.GetMarker(bucket.Name); found {
+		currentMarker = &marker
+	}
+	if currentMarker != nil && currentMarker.Prefix != prefix {
 		// this marker is for different prefix
 		currentMarker = nil
 	}
@@ -335,31 +342,32 @@ func (ct *CloudTrailEventTable) processObjects(svc *s3.Client, account *utilitie
 			continue
 		}
 		// Process object
-		ct.processSingleObject(svc, account, tableConfig, bucket, obj)
+		ct.processSingleObject(osqCtx, svc, account, tableConfig, bucket, obj)
 		// if object is not within latest ct.markerDelayMinutes
 		// and if it is modified after current marker, update the marker
 		if currentTime.Sub(*obj.LastModified) >= time.Duration(time.Duration(ct.markerDelayMinutes)*time.Minute) {
-			if currentMarker == nil || currentMarker.modifiedTime.Before(*obj.LastModified) {
+			if currentMarker == nil || currentMarker.ModifiedTime.Before(*obj.LastModified) {
 				// update marker
-				newMarker := ObjectMarker{
-					modifiedTime: *obj.LastModified,
-					key:          *obj.Key,
-					prefix:       prefix,
+				newMarker := utilities.CheckpointMarker{
+					ModifiedTime: *obj.LastModified,
+					Key:          *obj.Key,
+					Prefix:       prefix,
 				}
 				currentMarker = &newMarker
 			}
 		}
 	}
 	if currentMarker != nil {
-		ct.markerMap[bucket.Name] = currentMarker
+		ct.checkpoints.SetMarker(bucket.Name, *currentMarker)
+		ct.flushCheckpoints()
 	}
 }
 
 func (ct *CloudTrailEventTable) getS3Objects(svc *s3.Client, accountId string, bucket utilities.CtS3Bucket, prefix string) []types.Object {
 	s3Objects := make([]types.Object, 0)
 	var startAfter *string = nil
-	if ct.markerMap[bucket.Name] != nil {
-		startAfter = &ct.markerMap[bucket.Name].key
+	if marker, found := ct.checkpoints.GetMarker(bucket.Name); found {
+		startAfter = &marker.Key
 	}
 	params := s3.ListObjectsV2Input{
 		Bucket:            &bucket.Name,
@@ -389,7 +397,7 @@ func (ct *CloudTrailEventTable) getS3Objects(svc *s3.Client, accountId string, b
 	return s3Objects
 }
 
-func (ct *CloudTrailEventTable) processBucket(account *utilities.ExtensionConfigurationAwsAccount, tableConfig *utilities.TableConfig, bucket utilities.CtS3Bucket) {
+func (ct *CloudTrailEventTable) processBucket(osqCtx context.Context, account *utilities.ExtensionConfigurationAwsAccount, tableConfig *utilities.TableConfig, bucket utilities.CtS3Bucket) {
 	utilities.GetLogger().Info("Processing bucket ", account.ID, ":", bucket.Name)
 	sess, err := extaws.GetAwsConfig(account, bucket.Region)
 	if err != nil {
@@ -400,25 +408,30 @@ func (ct *CloudTrailEventTable) processBucket(account *utilities.ExtensionConfig
 	currentTime := time.Now()
 	prefix := ct.getPrefix(account, bucket, currentTime)
 	pastPrefix := ct.getPrefix(account, bucket, currentTime.Add(-time.Duration(time.Duration(ct.markerDelayMinutes)*time.Minute)))
+	if marker, found := ct.checkpoints.GetMarker(bucket.Name); found && marker.Prefix != prefix && marker.Prefix != pastPrefix {
+		// we were stopped on an earlier day, finish the files of that day first
+		results := ct.getS3Objects(svc, accountId, bucket, marker.Prefix)
+		ct.processObjects(osqCtx, svc, account, tableConfig, bucket, results, marker.Prefix)
+	}
 	if prefix != pastPrefix {
 		// we just moved to new day, but we need to process last few files in past day as well
 		s3Objects := make([]types.Object, 0)
 		results := ct.getS3Objects(svc, accountId, bucket, pastPrefix)
 		s3Objects = append(s3Objects, results...)
-		ct.processObjects(svc, account, tableConfig, bucket, s3Objects, pastPrefix)
+		ct.processObjects(osqCtx, svc, account, tableConfig, bucket, s3Objects, pastPrefix)
 	}
 	// process current day
 	s3Objects := make([]types.Object, 0)
 	results := ct.getS3Objects(svc, accountId, bucket, prefix)
 	s3Objects = append(s3Objects, results...)
-	ct.processObjects(svc, account, tableConfig, bucket, s3Objects, prefix)
+	ct.processObjects(osqCtx, svc, account, tableConfig, bucket, s3Objects, prefix)
 }
 
-func (ct *CloudTrailEventTable) processAccountLookupEvents(account *utilities.ExtensionConfigurationAwsAccount) {
+func (ct *CloudTrailEventTable) processAccountLookupEvents(osqCtx context.Context, account *utilities.ExtensionConfigurationAwsAccount) {
 	if account == nil || len(account.CtS3Buckets) == 0 {
 		return
 	}
-	tableConfig, ok := utilities.TableConfigurationMap[TABLE_NAME]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[TABLE_NAME]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": TABLE_NAME,
@@ -426,6 +439,6 @@ func (ct *CloudTrailEventTable) processAccountLookupEvents(account *utilities.Ex
 		return
 	}
 	for _, bucket := range account.CtS3Buckets {
-		ct.processBucket(account, tableConfig, bucket)
+		ct.processBucket(osqCtx, account, tableConfig, bucket)
 	}
 }
@@ -0,0 +1,94 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package cloudtrail
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// decodeRecords walks the "Records" array of CloudTrail log documents read from reader and calls
+// emit with at most batchSize records at a time. Only one record and one batch are kept in memory,
+// so memory use does not depend on the size of the file. Other attributes of the documents are skipped.
+// reader may contain several documents one after the other. The first skip records are decoded but not emitted,
+// e.g. the records emitted before processing of the object failed.
+func decodeRecords(reader io.Reader, batchSize int, skip int, emit func(records []map[string]interface{}) error) error {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	decoder := json.NewDecoder(reader)
+	batch := make([]map[string]interface{}, 0, batchSize)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '{' {
+			return fmt.Errorf("expected JSON object, found %v", token)
+		}
+		for decoder.More() {
+			token, err = decoder.Token()
+			if err != nil {
+				return err
+			}
+			if key, _ := token.(string); key != "Records" {
+				var skipped json.RawMessage
+				if err := decoder.Decode(&skipped); err != nil {
+					return err
+				}
+				continue
+			}
+
+			token, err = decoder.Token()
+			if err != nil {
+				return err
+			}
+			if token == nil {
+				// "Records": null
+				continue
+			}
+			if delim, ok := token.(json.Delim); !ok || delim != '[' {
+				return fmt.Errorf("expected Records array, found %v", token)
+			}
+			for decoder.More() {
+				var record map[string]interface{}
+				if err := decoder.Decode(&record); err != nil {
+					return err
+				}
+				if skip > 0 {
+					skip--
+					continue
+				}
+				batch = append(batch, record)
+				if len(batch) == batchSize {
+					if err := emit(batch); err != nil {
+						return err
+					}
+					batch = make([]map[string]interface{}, 0, batchSize)
+				}
+			}
+			// closing ]
+			if _, err := decoder.Token(); err != nil {
+				return err
+			}
+		}
+		// closing }
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	}
+	if len(batch) > 0 {
+		return emit(batch)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package cloudtrail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeRecords(t *testing.T) {
+	var document strings.Builder
+	document.WriteString(`{"Records":[`)
+	for i := 0; i < 12345; i++ {
+		if i > 0 {
+			document.WriteString(",")
+		}
+		fmt.Fprintf(&document, `{"eventID":"event-%d","eventName":"GetObject","requestParameters":{"bucketName":"bucket","size":%d}}`, i, i)
+	}
+	// Digest files have other attributes next to Records
+	document.WriteString(`],"digestPublicKeyFingerprint":"abc","logFiles":[{"s3Object":"key"}]}`)
+	document.WriteString("\n" + `{"Records":[{"eventID":"last"}]}` + "\n" + `{"Records":null}`)
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(document.String()))
+	writer.Close()
+	reader, err := gzip.NewReader(&compressed)
+	assert.Nil(t, err)
+
+	batches := 0
+	total := 0
+	lastID := ""
+	err = decodeRecords(reader, 1000, 0, func(records []map[string]interface{}) error {
+		assert.LessOrEqual(t, len(records), 1000)
+		if total == 0 {
+			assert.Equal(t, "event-0", records[0]["eventID"])
+			assert.Equal(t, "bucket", records[0]["requestParameters"].(map[string]interface{})["bucketName"])
+		}
+		batches++
+		total += len(records)
+		lastID = records[len(records)-1]["eventID"].(string)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 12346, total)
+	assert.Equal(t, 13, batches)
+	assert.Equal(t, "last", lastID)
+
+	err = decodeRecords(strings.NewReader(`{"Records":[{"eventID":"1"},`), 10, 0, func(records []map[string]interface{}) error {
+		return nil
+	})
+	assert.NotNil(t, err)
+
+	err = decodeRecords(strings.NewReader(`{"Records":[{"eventID":"1"}]}`), 10, 0, func(records []map[string]interface{}) error {
+		return fmt.Errorf("failed to send events")
+	})
+	assert.NotNil(t, err)
+
+	// Records emitted before a failure are skipped on retry
+	emitted := make([]string, 0)
+	err = decodeRecords(strings.NewReader(`{"Records":[{"eventID":"1"},{"eventID":"2"},{"eventID":"3"}]}`), 2, 1, func(records []map[string]interface{}) error {
+		for _, record := range records {
+			emitted = append(emitted, record["eventID"].(string))
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2", "3"}, emitted)
+}
@@ -12,7 +12,6 @@ package config
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,51 +23,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeDeliveryChannelsColumns returns the list of columns in the table
-func DescribeDeliveryChannelsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("config_snapshot_delivery_properties"),
-		//table.TextColumn("config_snapshot_delivery_properties_delivery_frequency"),
-		table.TextColumn("name"),
-		table.TextColumn("s3_bucket_name"),
-		table.TextColumn("s3_key_prefix"),
-		table.TextColumn("sns_topic_arn"),
-	}
-}
-
 // DescribeDeliveryChannelsGenerate returns the rows in the table for all configured accounts
 func DescribeDeliveryChannelsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_config_delivery_channel", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_config_delivery_channel",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeDeliveryChannels(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_config_delivery_channel", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_config_delivery_channel",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeDeliveryChannels(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_config_delivery_channel", processRegionDescribeDeliveryChannels)
 }
 
 func processRegionDescribeDeliveryChannels(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -124,37 +81,3 @@ func processRegionDescribeDeliveryChannels(osqCtx context.Context, queryContext
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeDeliveryChannels(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_config_delivery_channel"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_config_delivery_channel",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_config_delivery_channel", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeDeliveryChannels(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
@@ -12,7 +12,6 @@ package config
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,51 +23,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeConfigurationRecordersColumns returns the list of columns in the table
-func DescribeConfigurationRecordersColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("name"),
-		table.TextColumn("recording_group"),
-		table.TextColumn("recording_group_all_supported"),
-		table.TextColumn("recording_group_include_global_resource_types"),
-		table.TextColumn("recording_group_resource_types"),
-		table.TextColumn("role_arn"),
-	}
-}
-
 // DescribeConfigurationRecordersGenerate returns the rows in the table for all configured accounts
 func DescribeConfigurationRecordersGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_config_recorder", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_config_recorder",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeConfigurationRecorders(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_config_recorder", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_config_recorder",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeConfigurationRecorders(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_config_recorder", processRegionDescribeConfigurationRecorders)
 }
 
 func processRegionDescribeConfigurationRecorders(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -124,37 +81,3 @@ func processRegionDescribeConfigurationRecorders(osqCtx context.Context, queryCo
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeConfigurationRecorders(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_config_recorder"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_config_recorder",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_config_recorder", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeConfigurationRecorders(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
@@ -12,7 +12,6 @@ package ec2
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,62 +23,19 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeAddressesColumns returns the list of columns in the table
-func DescribeAddressesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("allocation_id"),
-		table.TextColumn("association_id"),
-		table.TextColumn("carrier_ip"),
-		table.TextColumn("customer_owned_ip"),
-		table.TextColumn("customer_owned_ipv4_pool"),
-		table.TextColumn("domain"),
-		table.TextColumn("instance_id"),
-		table.TextColumn("network_border_group"),
-		table.TextColumn("network_interface_id"),
-		table.TextColumn("network_interface_owner_id"),
-		table.TextColumn("private_ip_address"),
-		table.TextColumn("public_ip"),
-		table.TextColumn("public_ipv4_pool"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
-
-	}
+// describeAddressesFilters maps attributes to DescribeAddresses filters used to push down query constraints
+var describeAddressesFilters = map[string]string{
+	"Addresses_AllocationId":            "allocation-id",
+	"Addresses_AssociationId":           "association-id",
+	"Addresses_InstanceId":              "instance-id",
+	"Addresses_NetworkInterfaceId":      "network-interface-id",
+	"Addresses_NetworkInterfaceOwnerId": "network-interface-owner-id",
+	"Addresses_PublicIp":                "public-ip",
 }
 
 // DescribeAddressesGenerate returns the rows in the table for all configured accounts
 func DescribeAddressesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ec2_address", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_address",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeAddresses(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ec2_address", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ec2_address",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeAddresses(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ec2_address", processRegionDescribeAddresses)
 }
 
 func processRegionDescribeAddresses(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -101,7 +57,9 @@ func processRegionDescribeAddresses(osqCtx context.Context, queryContext table.Q
 	}).Debug("processing region")
 
 	svc := ec2.NewFromConfig(*sess)
-	params := &ec2.DescribeAddressesInput{}
+	params := &ec2.DescribeAddressesInput{
+		Filters: extaws.GetEc2Filters(queryContext, tableConfig, describeAddressesFilters),
+	}
 
 	result, err := svc.DescribeAddresses(osqCtx, params)
 	if err != nil {
@@ -135,37 +93,3 @@ func processRegionDescribeAddresses(osqCtx context.Context, queryContext table.Q
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeAddresses(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ec2_address"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_address",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ec2_address", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeAddresses(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
@@ -12,7 +12,6 @@ package ec2
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,53 +23,14 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeEgressOnlyInternetGatewaysColumns returns the list of columns in the table
-func DescribeEgressOnlyInternetGatewaysColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("attachments"),
-		//table.TextColumn("attachments_state"),
-		//table.TextColumn("attachments_vpc_id"),
-		table.TextColumn("egress_only_internet_gateway_id"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
-
-	}
+// describeEgressOnlyInternetGatewaysFilters maps attributes to DescribeEgressOnlyInternetGateways filters used to push down query constraints
+var describeEgressOnlyInternetGatewaysFilters = map[string]string{
+	"EgressOnlyInternetGateways_EgressOnlyInternetGatewayId": "egress-only-internet-gateway-id",
 }
 
 // DescribeEgressOnlyInternetGatewaysGenerate returns the rows in the table for all configured accounts
 func DescribeEgressOnlyInternetGatewaysGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ec2_egress_only_internet_gateway", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_egress_only_internet_gateway",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeEgressOnlyInternetGateways(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ec2_egress_only_internet_gateway", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ec2_egress_only_internet_gateway",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeEgressOnlyInternetGateways(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ec2_egress_only_internet_gateway", processRegionDescribeEgressOnlyInternetGateways)
 }
 
 func processRegionDescribeEgressOnlyInternetGateways(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -92,7 +52,9 @@ func processRegionDescribeEgressOnlyInternetGateways(osqCtx context.Context, que
 	}).Debug("processing region")
 
 	svc := ec2.NewFromConfig(*sess)
-	params := &ec2.DescribeEgressOnlyInternetGatewaysInput{}
+	params := &ec2.DescribeEgressOnlyInternetGatewaysInput{
+		Filters: extaws.GetEc2Filters(queryContext, tableConfig, describeEgressOnlyInternetGatewaysFilters),
+	}
 
 	paginator := ec2.NewDescribeEgressOnlyInternetGatewaysPaginator(svc, params)
 
@@ -133,37 +95,3 @@ func processRegionDescribeEgressOnlyInternetGateways(osqCtx context.Context, que
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeEgressOnlyInternetGateways(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ec2_egress_only_internet_gateway"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_egress_only_internet_gateway",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ec2_egress_only_internet_gateway", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeEgressOnlyInternetGateways(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
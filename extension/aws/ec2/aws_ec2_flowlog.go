@@ -12,7 +12,6 @@ package ec2
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,80 +23,18 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeFlowLogsColumns returns the list of columns in the table
-func DescribeFlowLogsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("creation_time"),
-		//table.BigIntColumn("creation_time_ext"),
-		//table.TextColumn("creation_time_loc"),
-		//table.BigIntColumn("creation_time_loc_cache_end"),
-		//table.BigIntColumn("creation_time_loc_cache_start"),
-		//table.TextColumn("creation_time_loc_cache_zone"),
-		//table.TextColumn("creation_time_loc_cache_zone_is_dst"),
-		//table.TextColumn("creation_time_loc_cache_zone_name"),
-		//table.IntegerColumn("creation_time_loc_cache_zone_offset"),
-		//table.TextColumn("creation_time_loc_name"),
-		//table.TextColumn("creation_time_loc_tx"),
-		//table.IntegerColumn("creation_time_loc_tx_index"),
-		//table.TextColumn("creation_time_loc_tx_isstd"),
-		//table.TextColumn("creation_time_loc_tx_isutc"),
-		//table.BigIntColumn("creation_time_loc_tx_when"),
-		//table.TextColumn("creation_time_loc_zone"),
-		//table.TextColumn("creation_time_loc_zone_is_dst"),
-		//table.TextColumn("creation_time_loc_zone_name"),
-		//table.IntegerColumn("creation_time_loc_zone_offset"),
-		//table.BigIntColumn("creation_time_wall"),
-		table.TextColumn("deliver_logs_error_message"),
-		table.TextColumn("deliver_logs_permission_arn"),
-		table.TextColumn("deliver_logs_status"),
-		table.TextColumn("flow_log_id"),
-		table.TextColumn("flow_log_status"),
-		table.TextColumn("log_destination"),
-		table.TextColumn("log_destination_type"),
-		table.TextColumn("log_format"),
-		table.TextColumn("log_group_name"),
-		table.BigIntColumn("max_aggregation_interval"),
-		table.TextColumn("resource_id"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
-		table.TextColumn("traffic_type"),
-	}
+// describeFlowLogsFilters maps attributes to DescribeFlowLogs filters used to push down query constraints
+var describeFlowLogsFilters = map[string]string{
+	"FlowLogs_FlowLogId":          "flow-log-id",
+	"FlowLogs_LogDestinationType": "log-destination-type",
+	"FlowLogs_LogGroupName":       "log-group-name",
+	"FlowLogs_ResourceId":         "resource-id",
+	"FlowLogs_TrafficType":        "traffic-type",
 }
 
 // DescribeFlowLogsGenerate returns the rows in the table for all configured accounts
 func DescribeFlowLogsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ec2_flowlog", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_flowlog",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeFlowLogs(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ec2_flowlog", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ec2_flowlog",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeFlowLogs(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ec2_flowlog", processRegionDescribeFlowLogs)
 }
 
 func processRegionDescribeFlowLogs(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -119,7 +56,9 @@ func processRegionDescribeFlowLogs(osqCtx context.Context, queryContext table.Qu
 	}).Debug("processing region")
 
 	svc := ec2.NewFromConfig(*sess)
-	params := &ec2.DescribeFlowLogsInput{}
+	params := &ec2.DescribeFlowLogsInput{
+		Filter: extaws.GetEc2Filters(queryContext, tableConfig, describeFlowLogsFilters),
+	}
 
 	paginator := ec2.NewDescribeFlowLogsPaginator(svc, params)
 
@@ -160,37 +99,3 @@ func processRegionDescribeFlowLogs(osqCtx context.Context, queryContext table.Qu
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeFlowLogs(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ec2_flowlog"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_flowlog",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ec2_flowlog", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeFlowLogs(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
@@ -12,7 +12,6 @@ package ec2
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,54 +23,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeInternetGatewaysColumns returns the list of columns in the table
-func DescribeInternetGatewaysColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("attachments"),
-		//table.TextColumn("attachments_state"),
-		//table.TextColumn("attachments_vpc_id"),
-		table.TextColumn("internet_gateway_id"),
-		table.TextColumn("owner_id"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
-
-	}
+// describeInternetGatewaysFilters maps attributes to DescribeInternetGateways filters used to push down query constraints
+var describeInternetGatewaysFilters = map[string]string{
+	"InternetGateways_InternetGatewayId": "internet-gateway-id",
+	"InternetGateways_OwnerId":           "owner-id",
 }
 
 // DescribeInternetGatewaysGenerate returns the rows in the table for all configured accounts
 func DescribeInternetGatewaysGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ec2_internet_gateway", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_internet_gateway",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeInternetGateways(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ec2_internet_gateway", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ec2_internet_gateway",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeInternetGateways(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ec2_internet_gateway", processRegionDescribeInternetGateways)
 }
 
 func processRegionDescribeInternetGateways(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -93,7 +53,9 @@ func processRegionDescribeInternetGateways(osqCtx context.Context, queryContext
 	}).Debug("processing region")
 
 	svc := ec2.NewFromConfig(*sess)
-	params := &ec2.DescribeInternetGatewaysInput{}
+	params := &ec2.DescribeInternetGatewaysInput{
+		Filters: extaws.GetEc2Filters(queryContext, tableConfig, describeInternetGatewaysFilters),
+	}
 
 	paginator := ec2.NewDescribeInternetGatewaysPaginator(svc, params)
 
@@ -134,37 +96,3 @@ func processRegionDescribeInternetGateways(osqCtx context.Context, queryContext
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeInternetGateways(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ec2_internet_gateway"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_internet_gateway",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ec2_internet_gateway", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeInternetGateways(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
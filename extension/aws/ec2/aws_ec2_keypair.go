@@ -12,7 +12,6 @@ package ec2
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,52 +23,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeKeyPairsColumns returns the list of columns in the table
-func DescribeKeyPairsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("key_fingerprint"),
-		table.TextColumn("key_name"),
-		table.TextColumn("key_pair_id"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
-
-	}
+// describeKeyPairsFilters maps attributes to DescribeKeyPairs filters used to push down query constraints
+var describeKeyPairsFilters = map[string]string{
+	"KeyPairs_KeyName":   "key-name",
+	"KeyPairs_KeyPairId": "key-pair-id",
 }
 
 // DescribeKeyPairsGenerate returns the rows in the table for all configured accounts
 func DescribeKeyPairsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ec2_keypair", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_keypair",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeKeyPairs(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ec2_keypair", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ec2_keypair",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeKeyPairs(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ec2_keypair", processRegionDescribeKeyPairs)
 }
 
 func processRegionDescribeKeyPairs(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -91,7 +53,9 @@ func processRegionDescribeKeyPairs(osqCtx context.Context, queryContext table.Qu
 	}).Debug("processing region")
 
 	svc := ec2.NewFromConfig(*sess)
-	params := &ec2.DescribeKeyPairsInput{}
+	params := &ec2.DescribeKeyPairsInput{
+		Filters: extaws.GetEc2Filters(queryContext, tableConfig, describeKeyPairsFilters),
+	}
 
 	result, err := svc.DescribeKeyPairs(osqCtx, params)
 	if err != nil {
@@ -125,37 +89,3 @@ func processRegionDescribeKeyPairs(osqCtx context.Context, queryContext table.Qu
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeKeyPairs(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ec2_keypair"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_keypair",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ec2_keypair", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeKeyPairs(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
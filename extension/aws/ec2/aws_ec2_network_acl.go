@@ -12,7 +12,6 @@ package ec2
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,69 +23,16 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeNetworkAclsColumns returns the list of columns in the table
-func DescribeNetworkAclsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("associations"),
-		//table.TextColumn("associations_network_acl_association_id"),
-		//table.TextColumn("associations_network_acl_id"),
-		//table.TextColumn("associations_subnet_id"),
-		table.TextColumn("entries"),
-		//table.TextColumn("entries_cidr_block"),
-		//table.TextColumn("entries_egress"),
-		//table.TextColumn("entries_icmp_type_code"),
-		//table.BigIntColumn("entries_icmp_type_code_code"),
-		//table.BigIntColumn("entries_icmp_type_code_type"),
-		//table.TextColumn("entries_ipv6_cidr_block"),
-		//table.TextColumn("entries_port_range"),
-		//table.BigIntColumn("entries_port_range_from"),
-		//table.BigIntColumn("entries_port_range_to"),
-		//table.TextColumn("entries_protocol"),
-		//table.TextColumn("entries_rule_action"),
-		//table.BigIntColumn("entries_rule_number"),
-		table.TextColumn("is_default"),
-		table.TextColumn("network_acl_id"),
-		table.TextColumn("owner_id"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
-		table.TextColumn("vpc_id"),
-	}
+// describeNetworkAclsFilters maps attributes to DescribeNetworkAcls filters used to push down query constraints
+var describeNetworkAclsFilters = map[string]string{
+	"NetworkAcls_NetworkAclId": "network-acl-id",
+	"NetworkAcls_OwnerId":      "owner-id",
+	"NetworkAcls_VpcId":        "vpc-id",
 }
 
 // DescribeNetworkAclsGenerate returns the rows in the table for all configured accounts
 func DescribeNetworkAclsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ec2_network_acl", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_network_acl",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeNetworkAcls(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ec2_network_acl", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ec2_network_acl",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeNetworkAcls(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ec2_network_acl", processRegionDescribeNetworkAcls)
 }
 
 func processRegionDescribeNetworkAcls(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -108,7 +54,9 @@ func processRegionDescribeNetworkAcls(osqCtx context.Context, queryContext table
 	}).Debug("processing region")
 
 	svc := ec2.NewFromConfig(*sess)
-	params := &ec2.DescribeNetworkAclsInput{}
+	params := &ec2.DescribeNetworkAclsInput{
+		Filters: extaws.GetEc2Filters(queryContext, tableConfig, describeNetworkAclsFilters),
+	}
 
 	paginator := ec2.NewDescribeNetworkAclsPaginator(svc, params)
 
@@ -149,37 +97,3 @@ func processRegionDescribeNetworkAcls(osqCtx context.Context, queryContext table
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeNetworkAcls(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ec2_network_acl"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_network_acl",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ec2_network_acl", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeNetworkAcls(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
@@ -12,7 +12,6 @@ package ec2
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,78 +23,16 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeRouteTablesColumns returns the list of columns in the table
-func DescribeRouteTablesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("associations"),
-		//table.TextColumn("associations_association_state"),
-		//table.TextColumn("associations_association_state_state"),
-		//table.TextColumn("associations_association_state_status_message"),
-		//table.TextColumn("associations_gateway_id"),
-		//table.TextColumn("associations_main"),
-		//table.TextColumn("associations_route_table_association_id"),
-		//table.TextColumn("associations_route_table_id"),
-		//table.TextColumn("associations_subnet_id"),
-		table.TextColumn("owner_id"),
-		table.TextColumn("propagating_vgws"),
-		//table.TextColumn("propagating_vgws_gateway_id"),
-		table.TextColumn("route_table_id"),
-		table.TextColumn("routes"),
-		//table.TextColumn("routes_carrier_gateway_id"),
-		//table.TextColumn("routes_destination_cidr_block"),
-		//table.TextColumn("routes_destination_ipv6_cidr_block"),
-		//table.TextColumn("routes_destination_prefix_list_id"),
-		//table.TextColumn("routes_egress_only_internet_gateway_id"),
-		//table.TextColumn("routes_gateway_id"),
-		//table.TextColumn("routes_instance_id"),
-		//table.TextColumn("routes_instance_owner_id"),
-		//table.TextColumn("routes_local_gateway_id"),
-		//table.TextColumn("routes_nat_gateway_id"),
-		//table.TextColumn("routes_network_interface_id"),
-		//table.TextColumn("routes_origin"),
-		//table.TextColumn("routes_state"),
-		//table.TextColumn("routes_transit_gateway_id"),
-		//table.TextColumn("routes_vpc_peering_connection_id"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
-		table.TextColumn("vpc_id"),
-	}
+// describeRouteTablesFilters maps attributes to DescribeRouteTables filters used to push down query constraints
+var describeRouteTablesFilters = map[string]string{
+	"RouteTables_RouteTableId": "route-table-id",
+	"RouteTables_OwnerId":      "owner-id",
+	"RouteTables_VpcId":        "vpc-id",
 }
 
 // DescribeRouteTablesGenerate returns the rows in the table for all configured accounts
 func DescribeRouteTablesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ec2_route_table", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_route_table",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeRouteTables(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ec2_route_table", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ec2_route_table",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeRouteTables(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ec2_route_table", processRegionDescribeRouteTables)
 }
 
 func processRegionDescribeRouteTables(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -117,7 +54,9 @@ func processRegionDescribeRouteTables(osqCtx context.Context, queryContext table
 	}).Debug("processing region")
 
 	svc := ec2.NewFromConfig(*sess)
-	params := &ec2.DescribeRouteTablesInput{}
+	params := &ec2.DescribeRouteTablesInput{
+		Filters: extaws.GetEc2Filters(queryContext, tableConfig, describeRouteTablesFilters),
+	}
 
 	paginator := ec2.NewDescribeRouteTablesPaginator(svc, params)
 
@@ -158,37 +97,3 @@ func processRegionDescribeRouteTables(osqCtx context.Context, queryContext table
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeRouteTables(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ec2_route_table"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_route_table",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ec2_route_table", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeRouteTables(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
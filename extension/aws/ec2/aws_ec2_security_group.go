@@ -12,7 +12,6 @@ package ec2
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,95 +23,17 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeSecurityGroupsColumns returns the list of columns in the table
-func DescribeSecurityGroupsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("description"),
-		table.TextColumn("group_id"),
-		table.TextColumn("group_name"),
-		table.TextColumn("ip_permissions"),
-		//table.TextColumn("ip_permissions_egress"),
-		//table.BigIntColumn("ip_permissions_egress_from_port"),
-		//table.TextColumn("ip_permissions_egress_ip_protocol"),
-		//table.TextColumn("ip_permissions_egress_ip_ranges"),
-		//table.TextColumn("ip_permissions_egress_ip_ranges_cidr_ip"),
-		//table.TextColumn("ip_permissions_egress_ip_ranges_description"),
-		//table.TextColumn("ip_permissions_egress_ipv6_ranges"),
-		//table.TextColumn("ip_permissions_egress_ipv6_ranges_cidr_ipv6"),
-		//table.TextColumn("ip_permissions_egress_ipv6_ranges_description"),
-		//table.TextColumn("ip_permissions_egress_prefix_list_ids"),
-		//table.TextColumn("ip_permissions_egress_prefix_list_ids_description"),
-		//table.TextColumn("ip_permissions_egress_prefix_list_ids_prefix_list_id"),
-		//table.BigIntColumn("ip_permissions_egress_to_port"),
-		//table.TextColumn("ip_permissions_egress_user_id_group_pairs"),
-		//table.TextColumn("ip_permissions_egress_user_id_group_pairs_description"),
-		//table.TextColumn("ip_permissions_egress_user_id_group_pairs_group_id"),
-		//table.TextColumn("ip_permissions_egress_user_id_group_pairs_group_name"),
-		//table.TextColumn("ip_permissions_egress_user_id_group_pairs_peering_status"),
-		//table.TextColumn("ip_permissions_egress_user_id_group_pairs_user_id"),
-		//table.TextColumn("ip_permissions_egress_user_id_group_pairs_vpc_id"),
-		//table.TextColumn("ip_permissions_egress_user_id_group_pairs_vpc_peering_connection_id"),
-		//table.BigIntColumn("ip_permissions_from_port"),
-		//table.TextColumn("ip_permissions_ip_protocol"),
-		//table.TextColumn("ip_permissions_ip_ranges"),
-		//table.TextColumn("ip_permissions_ip_ranges_cidr_ip"),
-		//table.TextColumn("ip_permissions_ip_ranges_description"),
-		//table.TextColumn("ip_permissions_ipv6_ranges"),
-		//table.TextColumn("ip_permissions_ipv6_ranges_cidr_ipv6"),
-		//table.TextColumn("ip_permissions_ipv6_ranges_description"),
-		//table.TextColumn("ip_permissions_prefix_list_ids"),
-		//table.TextColumn("ip_permissions_prefix_list_ids_description"),
-		//table.TextColumn("ip_permissions_prefix_list_ids_prefix_list_id"),
-		//table.BigIntColumn("ip_permissions_to_port"),
-		//table.TextColumn("ip_permissions_user_id_group_pairs"),
-		//table.TextColumn("ip_permissions_user_id_group_pairs_description"),
-		//table.TextColumn("ip_permissions_user_id_group_pairs_group_id"),
-		//table.TextColumn("ip_permissions_user_id_group_pairs_group_name"),
-		//table.TextColumn("ip_permissions_user_id_group_pairs_peering_status"),
-		//table.TextColumn("ip_permissions_user_id_group_pairs_user_id"),
-		//table.TextColumn("ip_permissions_user_id_group_pairs_vpc_id"),
-		//table.TextColumn("ip_permissions_user_id_group_pairs_vpc_peering_connection_id"),
-		table.TextColumn("owner_id"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
-		table.TextColumn("vpc_id"),
-	}
+// describeSecurityGroupsFilters maps attributes to DescribeSecurityGroups filters used to push down query constraints
+var describeSecurityGroupsFilters = map[string]string{
+	"SecurityGroups_GroupId":   "group-id",
+	"SecurityGroups_GroupName": "group-name",
+	"SecurityGroups_OwnerId":   "owner-id",
+	"SecurityGroups_VpcId":     "vpc-id",
 }
 
 // DescribeSecurityGroupsGenerate returns the rows in the table for all configured accounts
 func DescribeSecurityGroupsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ec2_security_group", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_security_group",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeSecurityGroups(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ec2_security_group", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ec2_security_group",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeSecurityGroups(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ec2_security_group", processRegionDescribeSecurityGroups)
 }
 
 func processRegionDescribeSecurityGroups(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -134,7 +55,9 @@ func processRegionDescribeSecurityGroups(osqCtx context.Context, queryContext ta
 	}).Debug("processing region")
 
 	svc := ec2.NewFromConfig(*sess)
-	params := &ec2.DescribeSecurityGroupsInput{}
+	params := &ec2.DescribeSecurityGroupsInput{
+		Filters: extaws.GetEc2Filters(queryContext, tableConfig, describeSecurityGroupsFilters),
+	}
 
 	paginator := ec2.NewDescribeSecurityGroupsPaginator(svc, params)
 
@@ -175,37 +98,3 @@ func processRegionDescribeSecurityGroups(osqCtx context.Context, queryContext ta
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeSecurityGroups(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ec2_security_group"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_security_group",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ec2_security_group", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeSecurityGroups(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
@@ -12,7 +12,6 @@ package ec2
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,80 +23,18 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeSnapshotsColumns returns the list of columns in the table
-func DescribeSnapshotsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("data_encryption_key_id"),
-		table.TextColumn("description"),
-		table.TextColumn("encrypted"),
-		table.TextColumn("kms_key_id"),
-		table.TextColumn("owner_alias"),
-		table.TextColumn("owner_id"),
-		table.TextColumn("progress"),
-		table.TextColumn("snapshot_id"),
-		table.TextColumn("start_time"),
-		//table.BigIntColumn("start_time_ext"),
-		//table.TextColumn("start_time_loc"),
-		//table.BigIntColumn("start_time_loc_cache_end"),
-		//table.BigIntColumn("start_time_loc_cache_start"),
-		//table.TextColumn("start_time_loc_cache_zone"),
-		//table.TextColumn("start_time_loc_cache_zone_is_dst"),
-		//table.TextColumn("start_time_loc_cache_zone_name"),
-		//table.IntegerColumn("start_time_loc_cache_zone_offset"),
-		//table.TextColumn("start_time_loc_name"),
-		//table.TextColumn("start_time_loc_tx"),
-		//table.IntegerColumn("start_time_loc_tx_index"),
-		//table.TextColumn("start_time_loc_tx_isstd"),
-		//table.TextColumn("start_time_loc_tx_isutc"),
-		//table.BigIntColumn("start_time_loc_tx_when"),
-		//table.TextColumn("start_time_loc_zone"),
-		//table.TextColumn("start_time_loc_zone_is_dst"),
-		//table.TextColumn("start_time_loc_zone_name"),
-		//table.IntegerColumn("start_time_loc_zone_offset"),
-		//table.BigIntColumn("start_time_wall"),
-		table.TextColumn("state"),
-		table.TextColumn("state_message"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
-		table.TextColumn("volume_id"),
-		table.BigIntColumn("volume_size"),
-	}
+// describeSnapshotsFilters maps attributes to DescribeSnapshots filters used to push down query constraints
+var describeSnapshotsFilters = map[string]string{
+	"Snapshots_SnapshotId": "snapshot-id",
+	"Snapshots_OwnerId":    "owner-id",
+	"Snapshots_State":      "status",
+	"Snapshots_VolumeId":   "volume-id",
+	"Snapshots_KmsKeyId":   "kms-key-id",
 }
 
 // DescribeSnapshotsGenerate returns the rows in the table for all configured accounts
 func DescribeSnapshotsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ec2_snapshot", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_snapshot",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeSnapshots(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ec2_snapshot", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ec2_snapshot",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeSnapshots(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ec2_snapshot", processRegionDescribeSnapshots)
 }
 
 func processRegionDescribeSnapshots(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -119,7 +56,9 @@ func processRegionDescribeSnapshots(osqCtx context.Context, queryContext table.Q
 	}).Debug("processing region")
 
 	svc := ec2.NewFromConfig(*sess)
-	params := &ec2.DescribeSnapshotsInput{}
+	params := &ec2.DescribeSnapshotsInput{
+		Filters: extaws.GetEc2Filters(queryContext, tableConfig, describeSnapshotsFilters),
+	}
 
 	paginator := ec2.NewDescribeSnapshotsPaginator(svc, params)
 
@@ -160,37 +99,3 @@ func processRegionDescribeSnapshots(osqCtx context.Context, queryContext table.Q
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeSnapshots(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ec2_snapshot"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_snapshot",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ec2_snapshot", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeSnapshots(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
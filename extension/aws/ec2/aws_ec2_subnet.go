@@ -12,7 +12,6 @@ package ec2
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,69 +23,18 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeSubnetsColumns returns the list of columns in the table
-func DescribeSubnetsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("assign_ipv6_address_on_creation"),
-		table.TextColumn("availability_zone"),
-		//table.TextColumn("availability_zone_id"),
-		table.BigIntColumn("available_ip_address_count"),
-		table.TextColumn("cidr_block"),
-		table.TextColumn("customer_owned_ipv4_pool"),
-		table.TextColumn("default_for_az"),
-		table.TextColumn("ipv6_cidr_block_association_set"),
-		//table.TextColumn("ipv6_cidr_block_association_set_association_id"),
-		//table.TextColumn("ipv6_cidr_block_association_set_ipv6_cidr_block"),
-		//table.TextColumn("ipv6_cidr_block_association_set_ipv6_cidr_block_state"),
-		//table.TextColumn("ipv6_cidr_block_association_set_ipv6_cidr_block_state_state"),
-		//table.TextColumn("ipv6_cidr_block_association_set_ipv6_cidr_block_state_status_message"),
-		table.TextColumn("map_customer_owned_ip_on_launch"),
-		table.TextColumn("map_public_ip_on_launch"),
-		table.TextColumn("outpost_arn"),
-		table.TextColumn("owner_id"),
-		table.TextColumn("state"),
-		table.TextColumn("subnet_arn"),
-		table.TextColumn("subnet_id"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
-		table.TextColumn("vpc_id"),
-	}
+// describeSubnetsFilters maps attributes to DescribeSubnets filters used to push down query constraints
+var describeSubnetsFilters = map[string]string{
+	"Subnets_SubnetId":         "subnet-id",
+	"Subnets_VpcId":            "vpc-id",
+	"Subnets_State":            "state",
+	"Subnets_OwnerId":          "owner-id",
+	"Subnets_AvailabilityZone": "availability-zone",
 }
 
 // DescribeSubnetsGenerate returns the rows in the table for all configured accounts
 func DescribeSubnetsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ec2_subnet", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_subnet",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeSubnets(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ec2_subnet", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ec2_subnet",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeSubnets(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ec2_subnet", processRegionDescribeSubnets)
 }
 
 func processRegionDescribeSubnets(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -108,7 +56,9 @@ func processRegionDescribeSubnets(osqCtx context.Context, queryContext table.Que
 	}).Debug("processing region")
 
 	svc := ec2.NewFromConfig(*sess)
-	params := &ec2.DescribeSubnetsInput{}
+	params := &ec2.DescribeSubnetsInput{
+		Filters: extaws.GetEc2Filters(queryContext, tableConfig, describeSubnetsFilters),
+	}
 
 	paginator := ec2.NewDescribeSubnetsPaginator(svc, params)
 
@@ -149,37 +99,3 @@ func processRegionDescribeSubnets(osqCtx context.Context, queryContext table.Que
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeSubnets(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ec2_subnet"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_subnet",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ec2_subnet", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeSubnets(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
@@ -12,7 +12,6 @@ package ec2
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,49 +23,17 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// DescribeTagsColumns returns the list of columns in the table
-func DescribeTagsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("key"),
-		table.TextColumn("resource_id"),
-		table.TextColumn("resource_type"),
-		table.TextColumn("value"),
-	}
+// describeTagsFilters maps attributes to DescribeTags filters used to push down query constraints
+var describeTagsFilters = map[string]string{
+	"Tags_ResourceId":   "resource-id",
+	"Tags_ResourceType": "resource-type",
+	"Tags_Key":          "key",
+	"Tags_Value":        "value",
 }
 
 // DescribeTagsGenerate returns the rows in the table for all configured accounts
 func DescribeTagsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ec2_tag", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_tag",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountDescribeTags(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ec2_tag", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ec2_tag",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountDescribeTags(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ec2_tag", processRegionDescribeTags)
 }
 
 func processRegionDescribeTags(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -88,7 +55,9 @@ func processRegionDescribeTags(osqCtx context.Context, queryContext table.QueryC
 	}).Debug("processing region")
 
 	svc := ec2.NewFromConfig(*sess)
-	params := &ec2.DescribeTagsInput{}
+	params := &ec2.DescribeTagsInput{
+		Filters: extaws.GetEc2Filters(queryContext, tableConfig, describeTagsFilters),
+	}
 
 	paginator := ec2.NewDescribeTagsPaginator(svc, params)
 
@@ -129,37 +98,3 @@ func processRegionDescribeTags(osqCtx context.Context, queryContext table.QueryC
 	}
 	return resultMap, nil
 }
-
-func processAccountDescribeTags(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ec2_tag"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ec2_tag",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ec2_tag", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionDescribeTags(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
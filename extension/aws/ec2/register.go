@@ -0,0 +1,40 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package ec2
+
+import (
+	_ "embed"
+
+	"github.com/Uptycs/cloudquery/utilities"
+)
+
+//go:embed table_config.json
+var defaultTableConfig []byte
+
+func init() {
+	utilities.RegisterTableConfigFile("aws/ec2/table_config.json", defaultTableConfig)
+
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_instance", Generate: DescribeInstancesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_vpc", Generate: DescribeVpcsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_subnet", Generate: DescribeSubnetsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_image", Generate: DescribeImagesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_egress_only_internet_gateway", Generate: DescribeEgressOnlyInternetGatewaysGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_internet_gateway", Generate: DescribeInternetGatewaysGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_nat_gateway", Generate: DescribeNatGatewaysGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_network_acl", Generate: DescribeNetworkAclsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_route_table", Generate: DescribeRouteTablesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_security_group", Generate: DescribeSecurityGroupsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_tag", Generate: DescribeTagsGenerate})
+	// utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_address", Generate: DescribeAddressesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_flowlog", Generate: DescribeFlowLogsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_keypair", Generate: DescribeKeyPairsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_snapshot", Generate: DescribeSnapshotsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ec2_volume", Generate: DescribeVolumesGenerate})
+}
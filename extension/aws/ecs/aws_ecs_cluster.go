@@ -12,7 +12,6 @@ package ecs
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -20,50 +19,85 @@ import (
 
 	"github.com/Uptycs/basequery-go/plugin/table"
 	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
 )
 
-// ListClustersColumns returns the list of columns in the table
-func ListClustersColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("cluster_arns"),
+// describeClustersLimit is the maximum number of clusters of a DescribeClusters request
+const describeClustersLimit = 100
+
+// ecsClusterRow is a row of aws_ecs_cluster. Lists are single JSON encoded columns instead of one row per item
+type ecsClusterRow struct {
+	ClusterName                       string
+	ClusterArn                        string
+	Status                            string
+	ActiveServicesCount               int32
+	RunningTasksCount                 int32
+	PendingTasksCount                 int32
+	RegisteredContainerInstancesCount int32
+	CapacityProviders                 []string
+	DefaultCapacityProviderStrategy   []ecstypes.CapacityProviderStrategyItem
+	ContainerInsights                 string
+	Settings                          []ecstypes.ClusterSetting
+	Statistics                        []ecstypes.KeyValuePair
+	Tags                              []ecstypes.Tag
+}
+
+func newEcsClusterRow(cluster ecstypes.Cluster) ecsClusterRow {
+	row := ecsClusterRow{
+		ClusterName:                       aws.ToString(cluster.ClusterName),
+		ClusterArn:                        aws.ToString(cluster.ClusterArn),
+		Status:                            aws.ToString(cluster.Status),
+		ActiveServicesCount:               cluster.ActiveServicesCount,
+		RunningTasksCount:                 cluster.RunningTasksCount,
+		PendingTasksCount:                 cluster.PendingTasksCount,
+		RegisteredContainerInstancesCount: cluster.RegisteredContainerInstancesCount,
+		CapacityProviders:                 append([]string{}, cluster.CapacityProviders...),
+		DefaultCapacityProviderStrategy:   append([]ecstypes.CapacityProviderStrategyItem{}, cluster.DefaultCapacityProviderStrategy...),
+		Settings:                          append([]ecstypes.ClusterSetting{}, cluster.Settings...),
+		Statistics:                        append([]ecstypes.KeyValuePair{}, cluster.Statistics...),
+		Tags:                              append([]ecstypes.Tag{}, cluster.Tags...),
 	}
+	for _, setting := range cluster.Settings {
+		if setting.Name == ecstypes.ClusterSettingNameContainerInsights {
+			row.ContainerInsights = aws.ToString(setting.Value)
+		}
+	}
+	return row
 }
 
-// ListClustersGenerate returns the rows in the table for all configured accounts
-func ListClustersGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_ecs_cluster", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_ecs_cluster",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountListClusters(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
+// getBatches splits items into lists of at most size items, for Describe* APIs accepting a limited number of resources
+func getBatches(items []string, size int) [][]string {
+	batches := make([][]string, 0, (len(items)+size-1)/size)
+	for start := 0; start < len(items); start += size {
+		end := start + size
+		if end > len(items) {
+			end = len(items)
 		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_ecs_cluster", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ecs_cluster",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountListClusters(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
+		batches = append(batches, items[start:end])
+	}
+	return batches
+}
+
+// listClusterArns returns the ARNs of all clusters of the region
+func listClusterArns(osqCtx context.Context, svc *ecs.Client) ([]string, error) {
+	clusterArns := make([]string, 0)
+	paginator := ecs.NewListClustersPaginator(svc, &ecs.ListClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			return nil, err
 		}
+		clusterArns = append(clusterArns, page.ClusterArns...)
 	}
+	return clusterArns, nil
+}
 
-	return resultMap, nil
+// ListClustersGenerate returns the rows in the table for all configured accounts
+func ListClustersGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ecs_cluster", processRegionListClusters)
 }
 
 func processRegionListClusters(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -85,78 +119,58 @@ func processRegionListClusters(osqCtx context.Context, queryContext table.QueryC
 	}).Debug("processing region")
 
 	svc := ecs.NewFromConfig(*sess)
-	params := &ecs.ListClustersInput{}
 
-	paginator := ecs.NewListClustersPaginator(svc, params)
+	clusterArns, err := listClusterArns(osqCtx, svc)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_ecs_cluster",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"task":      "ListClusters",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
 
-	for {
-		page, err := paginator.NextPage(osqCtx)
+	clusters := make([]ecsClusterRow, 0, len(clusterArns))
+	for _, batch := range getBatches(clusterArns, describeClustersLimit) {
+		output, err := svc.DescribeClusters(osqCtx, &ecs.DescribeClustersInput{
+			Clusters: batch,
+			Include:  []ecstypes.ClusterField{ecstypes.ClusterFieldSettings, ecstypes.ClusterFieldStatistics, ecstypes.ClusterFieldTags},
+		})
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": "aws_ecs_cluster",
 				"account":   accountId,
 				"region":    *region.RegionName,
-				"task":      "ListClusters",
+				"task":      "DescribeClusters",
 				"errString": err.Error(),
 			}).Error("failed to process region")
 			return resultMap, err
 		}
-		byteArr, err := json.Marshal(page)
-		if err != nil {
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_ecs_cluster",
-				"account":   accountId,
-				"region":    *region.RegionName,
-				"task":      "ListClusters",
-				"errString": err.Error(),
-			}).Error("failed to marshal response")
-			return nil, err
-		}
-		table := utilities.NewTable(byteArr, tableConfig)
-		for _, row := range table.Rows {
-			if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_ecs_cluster", accountId, *region.RegionName, row) {
-				continue
-			}
-			result := extaws.RowToMap(row, accountId, *region.RegionName, tableConfig)
-			resultMap = append(resultMap, result)
-		}
-		if !paginator.HasMorePages() {
-			break
+		for _, cluster := range output.Clusters {
+			clusters = append(clusters, newEcsClusterRow(cluster))
 		}
 	}
-	return resultMap, nil
-}
 
-func processAccountListClusters(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
+	byteArr, err := json.Marshal(struct{ Clusters []ecsClusterRow }{clusters})
 	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_ecs_cluster"]
-	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "aws_ecs_cluster",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"task":      "DescribeClusters",
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return nil, err
 	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_ecs_cluster", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionListClusters(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_ecs_cluster", accountId, *region.RegionName, row) {
 			continue
 		}
-		resultMap = append(resultMap, result...)
+		result := extaws.RowToMap(row, accountId, *region.RegionName, tableConfig)
+		resultMap = append(resultMap, result)
 	}
 	return resultMap, nil
 }
@@ -0,0 +1,199 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// describeServicesLimit is the maximum number of services of a DescribeServices request
+const describeServicesLimit = 10
+
+// ecsServiceRow is a row of aws_ecs_service, see ecsClusterRow
+type ecsServiceRow struct {
+	ServiceName                   string
+	ServiceArn                    string
+	ClusterArn                    string
+	Status                        string
+	LaunchType                    string
+	PlatformVersion               string
+	TaskDefinition                string
+	DesiredCount                  int32
+	RunningCount                  int32
+	PendingCount                  int32
+	SchedulingStrategy            string
+	DeploymentControllerType      string
+	RoleArn                       string
+	CreatedAt                     *time.Time
+	CreatedBy                     string
+	AssignPublicIp                string
+	Subnets                       []string
+	SecurityGroups                []string
+	LoadBalancers                 []ecstypes.LoadBalancer
+	CapacityProviderStrategy      []ecstypes.CapacityProviderStrategyItem
+	HealthCheckGracePeriodSeconds *int32
+	EnableECSManagedTags          bool
+	PropagateTags                 string
+	Tags                          []ecstypes.Tag
+}
+
+func newEcsServiceRow(service ecstypes.Service) ecsServiceRow {
+	row := ecsServiceRow{
+		ServiceName:                   aws.ToString(service.ServiceName),
+		ServiceArn:                    aws.ToString(service.ServiceArn),
+		ClusterArn:                    aws.ToString(service.ClusterArn),
+		Status:                        aws.ToString(service.Status),
+		LaunchType:                    string(service.LaunchType),
+		PlatformVersion:               aws.ToString(service.PlatformVersion),
+		TaskDefinition:                aws.ToString(service.TaskDefinition),
+		DesiredCount:                  service.DesiredCount,
+		RunningCount:                  service.RunningCount,
+		PendingCount:                  service.PendingCount,
+		SchedulingStrategy:            string(service.SchedulingStrategy),
+		RoleArn:                       aws.ToString(service.RoleArn),
+		CreatedAt:                     service.CreatedAt,
+		CreatedBy:                     aws.ToString(service.CreatedBy),
+		Subnets:                       []string{},
+		SecurityGroups:                []string{},
+		LoadBalancers:                 append([]ecstypes.LoadBalancer{}, service.LoadBalancers...),
+		CapacityProviderStrategy:      append([]ecstypes.CapacityProviderStrategyItem{}, service.CapacityProviderStrategy...),
+		HealthCheckGracePeriodSeconds: service.HealthCheckGracePeriodSeconds,
+		EnableECSManagedTags:          service.EnableECSManagedTags,
+		PropagateTags:                 string(service.PropagateTags),
+		Tags:                          append([]ecstypes.Tag{}, service.Tags...),
+	}
+	if service.DeploymentController != nil {
+		row.DeploymentControllerType = string(service.DeploymentController.Type)
+	}
+	if service.NetworkConfiguration != nil && service.NetworkConfiguration.AwsvpcConfiguration != nil {
+		vpcConfiguration := service.NetworkConfiguration.AwsvpcConfiguration
+		row.AssignPublicIp = string(vpcConfiguration.AssignPublicIp)
+		row.Subnets = append(row.Subnets, vpcConfiguration.Subnets...)
+		row.SecurityGroups = append(row.SecurityGroups, vpcConfiguration.SecurityGroups...)
+	}
+	return row
+}
+
+// listServices returns the services of a cluster
+func listServices(osqCtx context.Context, svc *ecs.Client, clusterArn string) ([]ecsServiceRow, error) {
+	serviceArns := make([]string, 0)
+	paginator := ecs.NewListServicesPaginator(svc, &ecs.ListServicesInput{Cluster: aws.String(clusterArn)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			return nil, err
+		}
+		serviceArns = append(serviceArns, page.ServiceArns...)
+	}
+
+	services := make([]ecsServiceRow, 0, len(serviceArns))
+	for _, batch := range getBatches(serviceArns, describeServicesLimit) {
+		output, err := svc.DescribeServices(osqCtx, &ecs.DescribeServicesInput{
+			Cluster:  aws.String(clusterArn),
+			Services: batch,
+			Include:  []ecstypes.ServiceField{ecstypes.ServiceFieldTags},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range output.Services {
+			services = append(services, newEcsServiceRow(service))
+		}
+	}
+	return services, nil
+}
+
+// ListServicesGenerate returns the rows in the table for all configured accounts
+func ListServicesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ecs_service", processRegionListServices)
+}
+
+func processRegionListServices(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, *region.RegionName)
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_ecs_service",
+		"account":   accountId,
+		"region":    *region.RegionName,
+	}).Debug("processing region")
+
+	svc := ecs.NewFromConfig(*sess)
+
+	clusterArns, err := listClusterArns(osqCtx, svc)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_ecs_service",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"task":      "ListClusters",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
+
+	services := make([]ecsServiceRow, 0)
+	for _, clusterArn := range clusterArns {
+		clusterServices, err := listServices(osqCtx, svc, clusterArn)
+		if err != nil {
+			utilities.GetLogger().WithFields(log.Fields{
+				"tableName": "aws_ecs_service",
+				"account":   accountId,
+				"region":    *region.RegionName,
+				"cluster":   clusterArn,
+				"task":      "DescribeServices",
+				"errString": err.Error(),
+			}).Error("failed to list services")
+			continue
+		}
+		services = append(services, clusterServices...)
+	}
+
+	byteArr, err := json.Marshal(struct{ Services []ecsServiceRow }{services})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_ecs_service",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return nil, err
+	}
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_ecs_service", accountId, *region.RegionName, row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, *region.RegionName, tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
@@ -0,0 +1,169 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// ecsTaskDefinitionRow is a row of aws_ecs_task_definition, see ecsClusterRow.
+// Values of container environment variables, which often hold secrets, are removed.
+type ecsTaskDefinitionRow struct {
+	TaskDefinitionArn       string
+	Family                  string
+	Revision                int32
+	Status                  string
+	TaskRoleArn             string
+	ExecutionRoleArn        string
+	NetworkMode             string
+	Cpu                     string
+	Memory                  string
+	PidMode                 string
+	IpcMode                 string
+	RequiresCompatibilities []ecstypes.Compatibility
+	Compatibilities         []ecstypes.Compatibility
+	ContainerImages         []string
+	PrivilegedContainers    []string
+	ContainerDefinitions    []ecstypes.ContainerDefinition
+	Volumes                 []ecstypes.Volume
+	Tags                    []ecstypes.Tag
+}
+
+func newEcsTaskDefinitionRow(taskDefinition *ecstypes.TaskDefinition, tags []ecstypes.Tag) ecsTaskDefinitionRow {
+	row := ecsTaskDefinitionRow{
+		TaskDefinitionArn:       aws.ToString(taskDefinition.TaskDefinitionArn),
+		Family:                  aws.ToString(taskDefinition.Family),
+		Revision:                taskDefinition.Revision,
+		Status:                  string(taskDefinition.Status),
+		TaskRoleArn:             aws.ToString(taskDefinition.TaskRoleArn),
+		ExecutionRoleArn:        aws.ToString(taskDefinition.ExecutionRoleArn),
+		NetworkMode:             string(taskDefinition.NetworkMode),
+		Cpu:                     aws.ToString(taskDefinition.Cpu),
+		Memory:                  aws.ToString(taskDefinition.Memory),
+		PidMode:                 string(taskDefinition.PidMode),
+		IpcMode:                 string(taskDefinition.IpcMode),
+		RequiresCompatibilities: append([]ecstypes.Compatibility{}, taskDefinition.RequiresCompatibilities...),
+		Compatibilities:         append([]ecstypes.Compatibility{}, taskDefinition.Compatibilities...),
+		ContainerImages:         []string{},
+		PrivilegedContainers:    []string{},
+		ContainerDefinitions:    make([]ecstypes.ContainerDefinition, 0, len(taskDefinition.ContainerDefinitions)),
+		Volumes:                 append([]ecstypes.Volume{}, taskDefinition.Volumes...),
+		Tags:                    append([]ecstypes.Tag{}, tags...),
+	}
+	for _, container := range taskDefinition.ContainerDefinitions {
+		row.ContainerImages = append(row.ContainerImages, aws.ToString(container.Image))
+		if aws.ToBool(container.Privileged) {
+			row.PrivilegedContainers = append(row.PrivilegedContainers, aws.ToString(container.Name))
+		}
+		environment := make([]ecstypes.KeyValuePair, 0, len(container.Environment))
+		for _, variable := range container.Environment {
+			environment = append(environment, ecstypes.KeyValuePair{Name: variable.Name})
+		}
+		container.Environment = environment
+		row.ContainerDefinitions = append(row.ContainerDefinitions, container)
+	}
+	return row
+}
+
+// ListTaskDefinitionsGenerate returns the rows in the table for all configured accounts
+func ListTaskDefinitionsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_ecs_task_definition", processRegionListTaskDefinitions)
+}
+
+func processRegionListTaskDefinitions(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, *region.RegionName)
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_ecs_task_definition",
+		"account":   accountId,
+		"region":    *region.RegionName,
+	}).Debug("processing region")
+
+	svc := ecs.NewFromConfig(*sess)
+	params := &ecs.ListTaskDefinitionsInput{Status: ecstypes.TaskDefinitionStatusActive}
+
+	paginator := ecs.NewListTaskDefinitionsPaginator(svc, params)
+
+	taskDefinitions := make([]ecsTaskDefinitionRow, 0)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			utilities.GetLogger().WithFields(log.Fields{
+				"tableName": "aws_ecs_task_definition",
+				"account":   accountId,
+				"region":    *region.RegionName,
+				"task":      "ListTaskDefinitions",
+				"errString": err.Error(),
+			}).Error("failed to process region")
+			return resultMap, err
+		}
+		for _, taskDefinitionArn := range page.TaskDefinitionArns {
+			output, err := svc.DescribeTaskDefinition(osqCtx, &ecs.DescribeTaskDefinitionInput{
+				TaskDefinition: aws.String(taskDefinitionArn),
+				Include:        []ecstypes.TaskDefinitionField{ecstypes.TaskDefinitionFieldTags},
+			})
+			if err != nil {
+				utilities.GetLogger().WithFields(log.Fields{
+					"tableName":      "aws_ecs_task_definition",
+					"account":        accountId,
+					"region":         *region.RegionName,
+					"taskDefinition": taskDefinitionArn,
+					"task":           "DescribeTaskDefinition",
+					"errString":      err.Error(),
+				}).Error("failed to describe task definition")
+				continue
+			}
+			if output.TaskDefinition != nil {
+				taskDefinitions = append(taskDefinitions, newEcsTaskDefinitionRow(output.TaskDefinition, output.Tags))
+			}
+		}
+	}
+
+	byteArr, err := json.Marshal(struct{ TaskDefinitions []ecsTaskDefinitionRow }{taskDefinitions})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_ecs_task_definition",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return nil, err
+	}
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_ecs_task_definition", accountId, *region.RegionName, row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, *region.RegionName, tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
@@ -0,0 +1,51 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package ecs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEcsTaskDefinitionRow_environmentValues(t *testing.T) {
+	taskDefinition := &ecstypes.TaskDefinition{
+		Family: aws.String("api"),
+		ContainerDefinitions: []ecstypes.ContainerDefinition{
+			{
+				Name:  aws.String("api"),
+				Image: aws.String("api:1"),
+				Environment: []ecstypes.KeyValuePair{
+					{Name: aws.String("DATABASE_PASSWORD"), Value: aws.String("secret-value-1")},
+				},
+			},
+			{
+				Name:        aws.String("sidecar"),
+				Image:       aws.String("sidecar:1"),
+				Privileged:  aws.Bool(true),
+				Environment: []ecstypes.KeyValuePair{{Name: aws.String("TOKEN"), Value: aws.String("secret-value-2")}},
+			},
+		},
+	}
+
+	row := newEcsTaskDefinitionRow(taskDefinition, nil)
+	byteArr, err := json.Marshal(row)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(byteArr), "secret-value")
+	assert.Equal(t, "DATABASE_PASSWORD", aws.ToString(row.ContainerDefinitions[0].Environment[0].Name))
+	assert.Nil(t, row.ContainerDefinitions[0].Environment[0].Value)
+	assert.Equal(t, []string{"api:1", "sidecar:1"}, row.ContainerImages)
+	assert.Equal(t, []string{"sidecar"}, row.PrivilegedContainers)
+	// The task definition itself is left as is
+	assert.Equal(t, "secret-value-1", aws.ToString(taskDefinition.ContainerDefinitions[0].Environment[0].Value))
+}
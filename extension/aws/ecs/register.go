@@ -0,0 +1,27 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package ecs
+
+import (
+	_ "embed"
+
+	"github.com/Uptycs/cloudquery/utilities"
+)
+
+//go:embed table_config.json
+var defaultTableConfig []byte
+
+func init() {
+	utilities.RegisterTableConfigFile("aws/ecs/table_config.json", defaultTableConfig)
+
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ecs_cluster", Generate: ListClustersGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ecs_service", Generate: ListServicesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_ecs_task_definition", Generate: ListTaskDefinitionsGenerate})
+}
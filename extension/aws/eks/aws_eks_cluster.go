@@ -12,7 +12,7 @@ package eks
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -20,50 +20,85 @@ import (
 
 	"github.com/Uptycs/basequery-go/plugin/table"
 	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 )
 
-// ListClustersColumns returns the list of columns in the table
-func ListClustersColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("clusters"),
-	}
+// eksClusterRow is a row of aws_eks_cluster. Nested attributes of the cluster are moved to the top level,
+// so that lists are single JSON encoded columns instead of one row per item
+type eksClusterRow struct {
+	Name                   string
+	Arn                    string
+	Version                string
+	PlatformVersion        string
+	Status                 string
+	Endpoint               string
+	RoleArn                string
+	CreatedAt              *time.Time
+	EndpointPublicAccess   bool
+	EndpointPrivateAccess  bool
+	PublicAccessCidrs      []string
+	VpcId                  string
+	SubnetIds              []string
+	SecurityGroupIds       []string
+	ClusterSecurityGroupId string
+	ServiceIpv4Cidr        string
+	OidcIssuer             string
+	EnabledLogTypes        []string
+	EncryptionConfig       []ekstypes.EncryptionConfig
+	Tags                   map[string]string `json:",omitempty"`
 }
 
-// ListClustersGenerate returns the rows in the table for all configured accounts
-func ListClustersGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_eks_cluster", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_eks_cluster",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountListClusters(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_eks_cluster", account.ID) {
+func newEksClusterRow(cluster *ekstypes.Cluster) eksClusterRow {
+	row := eksClusterRow{
+		Name:              aws.ToString(cluster.Name),
+		Arn:               aws.ToString(cluster.Arn),
+		Version:           aws.ToString(cluster.Version),
+		PlatformVersion:   aws.ToString(cluster.PlatformVersion),
+		Status:            string(cluster.Status),
+		Endpoint:          aws.ToString(cluster.Endpoint),
+		RoleArn:           aws.ToString(cluster.RoleArn),
+		CreatedAt:         cluster.CreatedAt,
+		PublicAccessCidrs: []string{},
+		SubnetIds:         []string{},
+		SecurityGroupIds:  []string{},
+		EnabledLogTypes:   []string{},
+		EncryptionConfig:  append([]ekstypes.EncryptionConfig{}, cluster.EncryptionConfig...),
+		Tags:              cluster.Tags,
+	}
+	if vpcConfig := cluster.ResourcesVpcConfig; vpcConfig != nil {
+		row.EndpointPublicAccess = vpcConfig.EndpointPublicAccess
+		row.EndpointPrivateAccess = vpcConfig.EndpointPrivateAccess
+		row.PublicAccessCidrs = append(row.PublicAccessCidrs, vpcConfig.PublicAccessCidrs...)
+		row.VpcId = aws.ToString(vpcConfig.VpcId)
+		row.SubnetIds = append(row.SubnetIds, vpcConfig.SubnetIds...)
+		row.SecurityGroupIds = append(row.SecurityGroupIds, vpcConfig.SecurityGroupIds...)
+		row.ClusterSecurityGroupId = aws.ToString(vpcConfig.ClusterSecurityGroupId)
+	}
+	if cluster.KubernetesNetworkConfig != nil {
+		row.ServiceIpv4Cidr = aws.ToString(cluster.KubernetesNetworkConfig.ServiceIpv4Cidr)
+	}
+	if cluster.Identity != nil && cluster.Identity.Oidc != nil {
+		row.OidcIssuer = aws.ToString(cluster.Identity.Oidc.Issuer)
+	}
+	if cluster.Logging != nil {
+		for _, logSetup := range cluster.Logging.ClusterLogging {
+			if !aws.ToBool(logSetup.Enabled) {
 				continue
 			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_eks_cluster",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountListClusters(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
+			for _, logType := range logSetup.Types {
+				row.EnabledLogTypes = append(row.EnabledLogTypes, string(logType))
 			}
-			resultMap = append(resultMap, results...)
 		}
 	}
+	return row
+}
 
-	return resultMap, nil
+// ListClustersGenerate returns the rows in the table for all configured accounts
+func ListClustersGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_eks_cluster", processRegionListClusters)
 }
 
 func processRegionListClusters(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -89,7 +124,8 @@ func processRegionListClusters(osqCtx context.Context, queryContext table.QueryC
 
 	paginator := eks.NewListClustersPaginator(svc, params)
 
-	for {
+	clusters := make([]eksClusterRow, 0)
+	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(osqCtx)
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
@@ -101,62 +137,43 @@ func processRegionListClusters(osqCtx context.Context, queryContext table.QueryC
 			}).Error("failed to process region")
 			return resultMap, err
 		}
-		byteArr, err := json.Marshal(page)
-		if err != nil {
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_eks_cluster",
-				"account":   accountId,
-				"region":    *region.RegionName,
-				"task":      "ListClusters",
-				"errString": err.Error(),
-			}).Error("failed to marshal response")
-			return nil, err
-		}
-		table := utilities.NewTable(byteArr, tableConfig)
-		for _, row := range table.Rows {
-			if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_eks_cluster", accountId, *region.RegionName, row) {
+		for _, name := range page.Clusters {
+			output, err := svc.DescribeCluster(osqCtx, &eks.DescribeClusterInput{Name: aws.String(name)})
+			if err != nil {
+				utilities.GetLogger().WithFields(log.Fields{
+					"tableName": "aws_eks_cluster",
+					"account":   accountId,
+					"region":    *region.RegionName,
+					"cluster":   name,
+					"task":      "DescribeCluster",
+					"errString": err.Error(),
+				}).Error("failed to describe cluster")
 				continue
 			}
-			result := extaws.RowToMap(row, accountId, *region.RegionName, tableConfig)
-			resultMap = append(resultMap, result)
-		}
-		if !paginator.HasMorePages() {
-			break
+			if output.Cluster != nil {
+				clusters = append(clusters, newEksClusterRow(output.Cluster))
+			}
 		}
 	}
-	return resultMap, nil
-}
 
-func processAccountListClusters(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
+	byteArr, err := json.Marshal(struct{ Clusters []eksClusterRow }{clusters})
 	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_eks_cluster"]
-	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "aws_eks_cluster",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"task":      "DescribeCluster",
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return nil, err
 	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_eks_cluster", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionListClusters(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_eks_cluster", accountId, *region.RegionName, row) {
 			continue
 		}
-		resultMap = append(resultMap, result...)
+		result := extaws.RowToMap(row, accountId, *region.RegionName, tableConfig)
+		resultMap = append(resultMap, result)
 	}
 	return resultMap, nil
 }
@@ -0,0 +1,202 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package eks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+)
+
+// eksNodegroupRow is a row of aws_eks_nodegroup, see eksClusterRow
+type eksNodegroupRow struct {
+	ClusterName               string
+	NodegroupName             string
+	NodegroupArn              string
+	Version                   string
+	ReleaseVersion            string
+	Status                    string
+	CapacityType              string
+	AmiType                   string
+	InstanceTypes             []string
+	DiskSize                  *int32
+	NodeRole                  string
+	Subnets                   []string
+	MinSize                   *int32
+	MaxSize                   *int32
+	DesiredSize               *int32
+	Ec2SshKey                 string
+	SourceSecurityGroups      []string
+	RemoteAccessSecurityGroup string
+	AutoScalingGroups         []string
+	LaunchTemplate            *ekstypes.LaunchTemplateSpecification `json:",omitempty"`
+	HealthIssues              []ekstypes.Issue
+	CreatedAt                 *time.Time
+	ModifiedAt                *time.Time
+	Labels                    map[string]string `json:",omitempty"`
+	Tags                      map[string]string `json:",omitempty"`
+}
+
+func newEksNodegroupRow(nodegroup *ekstypes.Nodegroup) eksNodegroupRow {
+	row := eksNodegroupRow{
+		ClusterName:          aws.ToString(nodegroup.ClusterName),
+		NodegroupName:        aws.ToString(nodegroup.NodegroupName),
+		NodegroupArn:         aws.ToString(nodegroup.NodegroupArn),
+		Version:              aws.ToString(nodegroup.Version),
+		ReleaseVersion:       aws.ToString(nodegroup.ReleaseVersion),
+		Status:               string(nodegroup.Status),
+		CapacityType:         string(nodegroup.CapacityType),
+		AmiType:              string(nodegroup.AmiType),
+		InstanceTypes:        append([]string{}, nodegroup.InstanceTypes...),
+		DiskSize:             nodegroup.DiskSize,
+		NodeRole:             aws.ToString(nodegroup.NodeRole),
+		Subnets:              append([]string{}, nodegroup.Subnets...),
+		SourceSecurityGroups: []string{},
+		AutoScalingGroups:    []string{},
+		LaunchTemplate:       nodegroup.LaunchTemplate,
+		HealthIssues:         []ekstypes.Issue{},
+		CreatedAt:            nodegroup.CreatedAt,
+		ModifiedAt:           nodegroup.ModifiedAt,
+		Labels:               nodegroup.Labels,
+		Tags:                 nodegroup.Tags,
+	}
+	if nodegroup.ScalingConfig != nil {
+		row.MinSize = nodegroup.ScalingConfig.MinSize
+		row.MaxSize = nodegroup.ScalingConfig.MaxSize
+		row.DesiredSize = nodegroup.ScalingConfig.DesiredSize
+	}
+	if nodegroup.RemoteAccess != nil {
+		row.Ec2SshKey = aws.ToString(nodegroup.RemoteAccess.Ec2SshKey)
+		row.SourceSecurityGroups = append(row.SourceSecurityGroups, nodegroup.RemoteAccess.SourceSecurityGroups...)
+	}
+	if nodegroup.Resources != nil {
+		row.RemoteAccessSecurityGroup = aws.ToString(nodegroup.Resources.RemoteAccessSecurityGroup)
+		for _, group := range nodegroup.Resources.AutoScalingGroups {
+			row.AutoScalingGroups = append(row.AutoScalingGroups, aws.ToString(group.Name))
+		}
+	}
+	if nodegroup.Health != nil {
+		row.HealthIssues = append(row.HealthIssues, nodegroup.Health.Issues...)
+	}
+	return row
+}
+
+// ListNodegroupsGenerate returns the rows in the table for all configured accounts
+func ListNodegroupsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_eks_nodegroup", processRegionListNodegroups)
+}
+
+// listNodegroups returns the node groups of a cluster
+func listNodegroups(osqCtx context.Context, svc *eks.Client, clusterName string) ([]eksNodegroupRow, error) {
+	nodegroups := make([]eksNodegroupRow, 0)
+	paginator := eks.NewListNodegroupsPaginator(svc, &eks.ListNodegroupsInput{ClusterName: aws.String(clusterName)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range page.Nodegroups {
+			output, err := svc.DescribeNodegroup(osqCtx, &eks.DescribeNodegroupInput{
+				ClusterName:   aws.String(clusterName),
+				NodegroupName: aws.String(name),
+			})
+			if err != nil {
+				return nil, err
+			}
+			if output.Nodegroup != nil {
+				nodegroups = append(nodegroups, newEksNodegroupRow(output.Nodegroup))
+			}
+		}
+	}
+	return nodegroups, nil
+}
+
+func processRegionListNodegroups(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, *region.RegionName)
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_eks_nodegroup",
+		"account":   accountId,
+		"region":    *region.RegionName,
+	}).Debug("processing region")
+
+	svc := eks.NewFromConfig(*sess)
+	paginator := eks.NewListClustersPaginator(svc, &eks.ListClustersInput{})
+
+	nodegroups := make([]eksNodegroupRow, 0)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			utilities.GetLogger().WithFields(log.Fields{
+				"tableName": "aws_eks_nodegroup",
+				"account":   accountId,
+				"region":    *region.RegionName,
+				"task":      "ListClusters",
+				"errString": err.Error(),
+			}).Error("failed to process region")
+			return resultMap, err
+		}
+		for _, clusterName := range page.Clusters {
+			clusterNodegroups, err := listNodegroups(osqCtx, svc, clusterName)
+			if err != nil {
+				utilities.GetLogger().WithFields(log.Fields{
+					"tableName": "aws_eks_nodegroup",
+					"account":   accountId,
+					"region":    *region.RegionName,
+					"cluster":   clusterName,
+					"task":      "DescribeNodegroup",
+					"errString": err.Error(),
+				}).Error("failed to list node groups")
+				continue
+			}
+			nodegroups = append(nodegroups, clusterNodegroups...)
+		}
+	}
+
+	byteArr, err := json.Marshal(struct{ Nodegroups []eksNodegroupRow }{nodegroups})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_eks_nodegroup",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return nil, err
+	}
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_eks_nodegroup", accountId, *region.RegionName, row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, *region.RegionName, tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
@@ -11,29 +11,88 @@ package aws
 
 import (
 	"context"
+
 	"github.com/Uptycs/basequery-go/plugin/table"
+	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
 // ShouldProcessAccount returns false if given account is not supposed to be processed for given table
-// Default implementation is no-op (return true always). Add custom logic here if required
-func ShouldProcessAccount(tableName string, accountId string) bool {
-	return true
+// Accounts excluded by an equality constraint on table's account id column are skipped
+func ShouldProcessAccount(osqCtx context.Context, queryContext table.QueryContext, tableName string, accountId string) bool {
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[tableName]
+	if !ok {
+		return true
+	}
+	return utilities.MatchesEqualsConstraints(queryContext, tableConfig.Aws.AccountIDAttribute, accountId)
 }
 
 // ShouldProcessRegion returns false if given region for given account is not supposed to be processed for given table
-// Default implementation is no-op (return true always). Add custom logic here if required
-func ShouldProcessRegion(tableName string, accountId string, region string) bool {
-	return true
+// Regions not selected by includeRegions/excludeRegions of the account (or of the table, which takes precedence)
+// and regions excluded by an equality constraint on table's region code column are skipped
+func ShouldProcessRegion(osqCtx context.Context, queryContext table.QueryContext, tableName string, accountId string, region string) bool {
+	config := utilities.GetConfiguration(osqCtx)
+	includeRegions, excludeRegions := getRegionFilter(config, tableName, accountId)
+	if !utilities.MatchesRegionFilter(includeRegions, excludeRegions, region) {
+		return false
+	}
+	tableConfig, ok := config.Tables[tableName]
+	if !ok {
+		return true
+	}
+	return utilities.MatchesEqualsConstraints(queryContext, tableConfig.Aws.RegionCodeAttribute, region)
+}
+
+// getRegionFilter returns the region patterns of given table if set, otherwise the ones of given account
+func getRegionFilter(config *utilities.Configuration, tableName string, accountId string) ([]string, []string) {
+	if tableConfig, ok := config.Tables[tableName]; ok {
+		if len(tableConfig.Aws.IncludeRegions) > 0 || len(tableConfig.Aws.ExcludeRegions) > 0 {
+			return tableConfig.Aws.IncludeRegions, tableConfig.Aws.ExcludeRegions
+		}
+	}
+	for _, account := range config.Extension.ExtConfAws.Accounts {
+		if account.ID == accountId {
+			return account.IncludeRegions, account.ExcludeRegions
+		}
+	}
+	if account := getDiscoveredAccount(accountId); account != nil {
+		return account.IncludeRegions, account.ExcludeRegions
+	}
+	return nil, nil
 }
 
 // ShouldProcessRow returns false if given row is not supposed to be processed for given table
-// Default implementation is no-op (return true always). Add custom logic here if required
+// Rows excluded by the filter rules of the table in extension_config.json are skipped
 func ShouldProcessRow(osqCtx context.Context, queryContext table.QueryContext, tableName string, accountId string, region string, row map[string]interface{}) bool {
-	return true
+	return utilities.MatchesRowFilter(osqCtx, tableName, row)
 }
 
 // ShouldProcessEvent returns false if given event is not supposed to be processed for given table
-// Default implementation is no-op (return true always). Add custom logic here if required
-func ShouldProcessEvent(tableName string, accountId string, region string, row map[string]string) bool {
-	return true
+// Events excluded by the filter rules of the table in extension_config.json are skipped
+func ShouldProcessEvent(osqCtx context.Context, tableName string, accountId string, region string, row map[string]string) bool {
+	return utilities.MatchesEventFilter(osqCtx, tableName, row)
+}
+
+// GetEc2Filters translates equality constraints into EC2 API filters.
+// filterMap is sourceName => EC2 filter name. Constraints are looked up on the column
+// configured for sourceName, so renamed or disabled attributes are handled correctly.
+// Filters are used instead of id lists (e.g. InstanceIds) because unknown ids in a filter
+// yield an empty result rather than an error for the whole region.
+func GetEc2Filters(queryContext table.QueryContext, tableConfig *utilities.TableConfig, filterMap map[string]string) []types.Filter {
+	filters := make([]types.Filter, 0)
+	for sourceName, filterName := range filterMap {
+		values := utilities.GetEqualsConstraints(queryContext, tableConfig.GetTargetName(sourceName))
+		if len(values) == 0 {
+			continue
+		}
+		name := filterName
+		filters = append(filters, types.Filter{
+			Name:   &name,
+			Values: values,
+		})
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return filters
 }
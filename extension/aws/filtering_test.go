@@ -0,0 +1,136 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package aws
+
+import (
+	"context"
+
+	"testing"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/stretchr/testify/assert"
+)
+
+var filteringTableConfigJSON = `
+{
+	"test_filtering_table": {
+		"aws": {
+			"regionCodeAttribute": "region_code",
+			"accountIdAttribute": "account_id"
+		},
+		"gcp": {},
+		"azure": {},
+		"parsedAttributes": [
+			{
+				"sourceName": "Items_ItemId",
+				"targetName": "item_id",
+				"targetType": "TEXT",
+				"enabled": true
+			},
+			{
+				"sourceName": "Items_OwnerId",
+				"targetName": "owner_id",
+				"targetType": "TEXT",
+				"enabled": false
+			}
+		]
+	}
+}`
+
+func getEqualsQueryContext(columnValues map[string][]string) table.QueryContext {
+	queryContext := table.QueryContext{Constraints: make(map[string]table.ConstraintList)}
+	for column, values := range columnValues {
+		constraints := make([]table.Constraint, 0)
+		for _, value := range values {
+			constraints = append(constraints, table.Constraint{Operator: table.OperatorEquals, Expression: value})
+		}
+		queryContext.Constraints[column] = table.ConstraintList{Affinity: table.ColumnTypeText, Constraints: constraints}
+	}
+	return queryContext
+}
+
+func TestShouldProcessAccountAndRegion(t *testing.T) {
+	err := utilities.ReadTableConfig([]byte(filteringTableConfigJSON))
+	assert.Nil(t, err)
+
+	queryContext := getEqualsQueryContext(map[string][]string{
+		"account_id":  {"111111111111"},
+		"region_code": {"us-east-1", "eu-west-1"},
+	})
+	assert.True(t, ShouldProcessAccount(context.Background(), queryContext, "test_filtering_table", "111111111111"))
+	assert.False(t, ShouldProcessAccount(context.Background(), queryContext, "test_filtering_table", "222222222222"))
+	assert.True(t, ShouldProcessRegion(context.Background(), queryContext, "test_filtering_table", "111111111111", "eu-west-1"))
+	assert.False(t, ShouldProcessRegion(context.Background(), queryContext, "test_filtering_table", "111111111111", "ap-south-1"))
+
+	// Unconstrained query and unknown table process everything
+	assert.True(t, ShouldProcessRegion(context.Background(), table.QueryContext{}, "test_filtering_table", "111111111111", "ap-south-1"))
+	assert.True(t, ShouldProcessAccount(context.Background(), queryContext, "unknown_table", "222222222222"))
+}
+
+func TestShouldProcessRegion_regionPatterns(t *testing.T) {
+	err := utilities.ReadTableConfig([]byte(`{
+		"test_region_table": {"aws": {"regionCodeAttribute": "region_code"}},
+		"test_region_override_table": {"aws": {"regionCodeAttribute": "region_code", "includeRegions": ["ap-*"]}}
+	}`))
+	assert.Nil(t, err)
+	savedConfiguration := utilities.CurrentConfiguration()
+	defer func() {
+		utilities.UpdateConfiguration(func(config *utilities.Configuration) { config.Extension = savedConfiguration.Extension })
+	}()
+	utilities.UpdateConfiguration(func(config *utilities.Configuration) {
+		config.Extension.ExtConfAws.Accounts = []utilities.ExtensionConfigurationAwsAccount{
+			{ID: "111111111111", IncludeRegions: []string{"eu-*", "us-east-1"}, ExcludeRegions: []string{"eu-south-*"}},
+			{ID: "222222222222", ExcludeRegions: []string{"us-*"}},
+		}
+	})
+
+	queryContext := table.QueryContext{}
+	assert.True(t, ShouldProcessRegion(context.Background(), queryContext, "test_region_table", "111111111111", "eu-west-1"))
+	assert.True(t, ShouldProcessRegion(context.Background(), queryContext, "test_region_table", "111111111111", "us-east-1"))
+	assert.False(t, ShouldProcessRegion(context.Background(), queryContext, "test_region_table", "111111111111", "us-east-2"))
+	assert.False(t, ShouldProcessRegion(context.Background(), queryContext, "test_region_table", "111111111111", "eu-south-1"))
+	assert.False(t, ShouldProcessRegion(context.Background(), queryContext, "test_region_table", "222222222222", "us-west-2"))
+	assert.True(t, ShouldProcessRegion(context.Background(), queryContext, "test_region_table", "222222222222", "ap-south-1"))
+	assert.True(t, ShouldProcessRegion(context.Background(), queryContext, "test_region_table", "333333333333", "us-west-2"))
+
+	// Table patterns replace the ones of the account
+	assert.True(t, ShouldProcessRegion(context.Background(), queryContext, "test_region_override_table", "111111111111", "ap-south-1"))
+	assert.False(t, ShouldProcessRegion(context.Background(), queryContext, "test_region_override_table", "111111111111", "eu-west-1"))
+
+	// Query constraints still apply to selected regions
+	constrained := getEqualsQueryContext(map[string][]string{"region_code": {"eu-central-1"}})
+	assert.False(t, ShouldProcessRegion(context.Background(), constrained, "test_region_table", "111111111111", "eu-west-1"))
+
+	err = utilities.ReadTableConfig([]byte(`{"test_bad_region_table": {"aws": {"excludeRegions": ["eu-["]}}}`))
+	assert.NotNil(t, err)
+}
+
+func TestGetEc2Filters(t *testing.T) {
+	err := utilities.ReadTableConfig([]byte(filteringTableConfigJSON))
+	assert.Nil(t, err)
+	tableConfig := utilities.CurrentConfiguration().Tables["test_filtering_table"]
+	filterMap := map[string]string{
+		"Items_ItemId":  "item-id",
+		"Items_OwnerId": "owner-id",
+	}
+
+	assert.Nil(t, GetEc2Filters(table.QueryContext{}, tableConfig, filterMap))
+
+	queryContext := getEqualsQueryContext(map[string][]string{
+		"item_id":  {"i-1", "i-2"},
+		"owner_id": {"123"},
+	})
+	filters := GetEc2Filters(queryContext, tableConfig, filterMap)
+	// owner_id is disabled in table config and must not be pushed down
+	assert.Equal(t, 1, len(filters))
+	assert.Equal(t, "item-id", *filters[0].Name)
+	assert.Equal(t, []string{"i-1", "i-2"}, filters[0].Values)
+}
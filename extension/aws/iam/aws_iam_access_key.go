@@ -0,0 +1,166 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package iam
+
+import (
+	"context"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// accessKeyRow is a row of aws_iam_access_key, made of the key metadata and GetAccessKeyLastUsed
+type accessKeyRow struct {
+	AccessKeyId         string
+	UserName            string
+	UserArn             string
+	Status              string
+	CreateDate          string
+	LastUsedDate        string
+	LastUsedServiceName string
+	LastUsedRegion      string
+}
+
+// getLastUsedValue returns an empty string for the N/A placeholder of keys that were never used
+func getLastUsedValue(value *string) string {
+	if aws.ToString(value) == "N/A" {
+		return ""
+	}
+	return aws.ToString(value)
+}
+
+func newAccessKeyRow(user iamtypes.User, accessKey iamtypes.AccessKeyMetadata, lastUsed *iamtypes.AccessKeyLastUsed) accessKeyRow {
+	row := accessKeyRow{
+		AccessKeyId: aws.ToString(accessKey.AccessKeyId),
+		UserName:    aws.ToString(user.UserName),
+		UserArn:     aws.ToString(user.Arn),
+		Status:      string(accessKey.Status),
+		CreateDate:  formatTime(accessKey.CreateDate),
+	}
+	if lastUsed != nil {
+		row.LastUsedDate = formatTime(lastUsed.LastUsedDate)
+		row.LastUsedServiceName = getLastUsedValue(lastUsed.ServiceName)
+		row.LastUsedRegion = getLastUsedValue(lastUsed.Region)
+	}
+	return row
+}
+
+// listAccessKeys returns the access keys of a user
+func listAccessKeys(osqCtx context.Context, svc *iam.Client, userName string) ([]iamtypes.AccessKeyMetadata, error) {
+	accessKeys := make([]iamtypes.AccessKeyMetadata, 0)
+	paginator := iam.NewListAccessKeysPaginator(svc, &iam.ListAccessKeysInput{UserName: aws.String(userName)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			return nil, err
+		}
+		accessKeys = append(accessKeys, page.AccessKeyMetadata...)
+	}
+	return accessKeys, nil
+}
+
+// ListAccessKeysGenerate returns the rows in the table for all configured accounts
+func ListAccessKeysGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountsGlobal(osqCtx, queryContext, "aws_iam_access_key", processGlobalListAccessKeys)
+}
+
+func processGlobalListAccessKeys(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, "aws-global")
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_iam_access_key",
+		"account":   accountId,
+		"region":    "aws-global",
+	}).Debug("processing region")
+
+	svc := iam.NewFromConfig(*sess)
+
+	users, err := listUsers(osqCtx, svc)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_access_key",
+			"account":   accountId,
+			"region":    "aws-global",
+			"task":      "ListUsers",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
+
+	rows := make([]accessKeyRow, 0)
+	for _, user := range users {
+		accessKeys, err := listAccessKeys(osqCtx, svc, aws.ToString(user.UserName))
+		if err != nil {
+			utilities.GetLogger().WithFields(log.Fields{
+				"tableName": "aws_iam_access_key",
+				"account":   accountId,
+				"region":    "aws-global",
+				"user":      aws.ToString(user.UserName),
+				"task":      "ListAccessKeys",
+				"errString": err.Error(),
+			}).Error("failed to list access keys")
+			continue
+		}
+		for _, accessKey := range accessKeys {
+			output, err := svc.GetAccessKeyLastUsed(osqCtx, &iam.GetAccessKeyLastUsedInput{AccessKeyId: accessKey.AccessKeyId})
+			if err != nil {
+				// The key is still listed, only without its last use
+				utilities.GetLogger().WithFields(log.Fields{
+					"tableName": "aws_iam_access_key",
+					"account":   accountId,
+					"region":    "aws-global",
+					"accessKey": aws.ToString(accessKey.AccessKeyId),
+					"task":      "GetAccessKeyLastUsed",
+					"errString": err.Error(),
+				}).Error("failed to get access key last used")
+				rows = append(rows, newAccessKeyRow(user, accessKey, nil))
+				continue
+			}
+			rows = append(rows, newAccessKeyRow(user, accessKey, output.AccessKeyLastUsed))
+		}
+	}
+
+	byteArr, err := json.Marshal(struct{ AccessKeys []accessKeyRow }{rows})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_access_key",
+			"account":   accountId,
+			"region":    "aws-global",
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return nil, err
+	}
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_iam_access_key", accountId, "aws-global", row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, "aws-global", tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
@@ -0,0 +1,239 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package iam
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+const (
+	// credentialReportPollInterval is the delay between two GenerateCredentialReport calls while the report is generated
+	credentialReportPollInterval = 2 * time.Second
+	// credentialReportMaxAttempts bounds the number of GenerateCredentialReport calls
+	credentialReportMaxAttempts = 30
+)
+
+// credentialReportRow is a row of aws_iam_credential_report, one per user of the CSV report plus the root account.
+// Placeholders of the report (N/A, not_supported, no_information) are empty strings and timestamps are RFC3339
+type credentialReportRow struct {
+	User                      string
+	Arn                       string
+	UserCreationTime          string
+	PasswordEnabled           string
+	PasswordLastUsed          string
+	PasswordLastChanged       string
+	PasswordNextRotation      string
+	MfaActive                 string
+	AccessKey1Active          string
+	AccessKey1LastRotated     string
+	AccessKey1LastUsedDate    string
+	AccessKey1LastUsedRegion  string
+	AccessKey1LastUsedService string
+	AccessKey2Active          string
+	AccessKey2LastRotated     string
+	AccessKey2LastUsedDate    string
+	AccessKey2LastUsedRegion  string
+	AccessKey2LastUsedService string
+	Cert1Active               string
+	Cert1LastRotated          string
+	Cert2Active               string
+	Cert2LastRotated          string
+	GeneratedTime             string
+}
+
+// getReportValue returns an empty string for the placeholders of the credential report
+func getReportValue(value string) string {
+	switch value {
+	case "N/A", "not_supported", "no_information":
+		return ""
+	default:
+		return value
+	}
+}
+
+// getReportTime returns a timestamp of the credential report as RFC3339 in UTC, or an empty string for placeholders
+func getReportTime(value string) string {
+	t, err := time.Parse(time.RFC3339, getReportValue(value))
+	if err != nil {
+		return ""
+	}
+	return formatTime(&t)
+}
+
+// parseCredentialReport returns the rows of a CSV credential report
+func parseCredentialReport(content []byte, generatedTime string) ([]credentialReportRow, error) {
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]credentialReportRow, 0)
+	if len(records) == 0 {
+		return rows, nil
+	}
+	columns := make(map[string]int, len(records[0]))
+	for index, name := range records[0] {
+		columns[name] = index
+	}
+	for _, record := range records[1:] {
+		get := func(name string) string {
+			if index, ok := columns[name]; ok && index < len(record) {
+				return record[index]
+			}
+			return ""
+		}
+		rows = append(rows, credentialReportRow{
+			User:                      get("user"),
+			Arn:                       get("arn"),
+			UserCreationTime:          getReportTime(get("user_creation_time")),
+			PasswordEnabled:           getReportValue(get("password_enabled")),
+			PasswordLastUsed:          getReportTime(get("password_last_used")),
+			PasswordLastChanged:       getReportTime(get("password_last_changed")),
+			PasswordNextRotation:      getReportTime(get("password_next_rotation")),
+			MfaActive:                 getReportValue(get("mfa_active")),
+			AccessKey1Active:          getReportValue(get("access_key_1_active")),
+			AccessKey1LastRotated:     getReportTime(get("access_key_1_last_rotated")),
+			AccessKey1LastUsedDate:    getReportTime(get("access_key_1_last_used_date")),
+			AccessKey1LastUsedRegion:  getReportValue(get("access_key_1_last_used_region")),
+			AccessKey1LastUsedService: getReportValue(get("access_key_1_last_used_service")),
+			AccessKey2Active:          getReportValue(get("access_key_2_active")),
+			AccessKey2LastRotated:     getReportTime(get("access_key_2_last_rotated")),
+			AccessKey2LastUsedDate:    getReportTime(get("access_key_2_last_used_date")),
+			AccessKey2LastUsedRegion:  getReportValue(get("access_key_2_last_used_region")),
+			AccessKey2LastUsedService: getReportValue(get("access_key_2_last_used_service")),
+			Cert1Active:               getReportValue(get("cert_1_active")),
+			Cert1LastRotated:          getReportTime(get("cert_1_last_rotated")),
+			Cert2Active:               getReportValue(get("cert_2_active")),
+			Cert2LastRotated:          getReportTime(get("cert_2_last_rotated")),
+			GeneratedTime:             generatedTime,
+		})
+	}
+	return rows, nil
+}
+
+// getCredentialReportAttributes returns the enabled attributes of a report row, with the source names
+// of a {"Users": [...]} document (e.g. Users_User). Rows are flat, so they don't go through utilities.NewTable,
+// which would keep the JSON encoding of values such as <root_account>
+func getCredentialReportAttributes(tableConfig *utilities.TableConfig, reportRow credentialReportRow) map[string]interface{} {
+	attributes := make(map[string]interface{})
+	value := reflect.ValueOf(reportRow)
+	for index := 0; index < value.NumField(); index++ {
+		sourceName := "Users_" + value.Type().Field(index).Name
+		if tableConfig.GetTargetName(sourceName) != "" {
+			attributes[sourceName] = value.Field(index).String()
+		}
+	}
+	return attributes
+}
+
+// generateCredentialReport requests a credential report and waits until it is complete.
+// IAM reuses a report generated less than four hours ago, in which case the first call returns COMPLETE
+func generateCredentialReport(osqCtx context.Context, svc *iam.Client) error {
+	for attempt := 0; attempt < credentialReportMaxAttempts; attempt++ {
+		output, err := svc.GenerateCredentialReport(osqCtx, &iam.GenerateCredentialReportInput{})
+		if err != nil {
+			return err
+		}
+		if output.State == iamtypes.ReportStateTypeComplete {
+			return nil
+		}
+		select {
+		case <-osqCtx.Done():
+			return osqCtx.Err()
+		case <-time.After(credentialReportPollInterval):
+		}
+	}
+	return fmt.Errorf("credential report not complete after %d attempts", credentialReportMaxAttempts)
+}
+
+// GetCredentialReportGenerate returns the rows in the table for all configured accounts
+func GetCredentialReportGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountsGlobal(osqCtx, queryContext, "aws_iam_credential_report", processGlobalGetCredentialReport)
+}
+
+func processGlobalGetCredentialReport(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, "aws-global")
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_iam_credential_report",
+		"account":   accountId,
+		"region":    "aws-global",
+	}).Debug("processing region")
+
+	svc := iam.NewFromConfig(*sess)
+
+	err = generateCredentialReport(osqCtx, svc)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_credential_report",
+			"account":   accountId,
+			"region":    "aws-global",
+			"task":      "GenerateCredentialReport",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
+
+	output, err := svc.GetCredentialReport(osqCtx, &iam.GetCredentialReportInput{})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_credential_report",
+			"account":   accountId,
+			"region":    "aws-global",
+			"task":      "GetCredentialReport",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
+
+	rows, err := parseCredentialReport(output.Content, formatTime(output.GeneratedTime))
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_credential_report",
+			"account":   accountId,
+			"region":    "aws-global",
+			"task":      "GetCredentialReport",
+			"errString": err.Error(),
+		}).Error("failed to parse credential report")
+		return resultMap, err
+	}
+
+	for _, reportRow := range rows {
+		row := getCredentialReportAttributes(tableConfig, reportRow)
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_iam_credential_report", accountId, "aws-global", row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, "aws-global", tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
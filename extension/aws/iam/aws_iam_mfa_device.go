@@ -0,0 +1,201 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package iam
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// mfaDeviceRow is a row of aws_iam_mfa_device.
+// Devices come from ListMFADevices of each user, plus virtual devices that are unassigned or assigned to the root user
+type mfaDeviceRow struct {
+	SerialNumber     string
+	DeviceType       string
+	UserName         string
+	UserArn          string
+	EnableDate       string
+	AssignmentStatus string
+}
+
+// getMfaDeviceType returns virtual, u2f or hardware, based on the serial number of the device.
+// Serial numbers of virtual and U2F devices are ARNs, hardware devices have a vendor serial number
+func getMfaDeviceType(serialNumber string) string {
+	switch {
+	case strings.HasPrefix(serialNumber, "arn:") && strings.Contains(serialNumber, ":mfa/"):
+		return "virtual"
+	case strings.HasPrefix(serialNumber, "arn:") && strings.Contains(serialNumber, ":u2f/"):
+		return "u2f"
+	default:
+		return "hardware"
+	}
+}
+
+func newMfaDeviceRow(user iamtypes.User, device iamtypes.MFADevice) mfaDeviceRow {
+	serialNumber := aws.ToString(device.SerialNumber)
+	return mfaDeviceRow{
+		SerialNumber:     serialNumber,
+		DeviceType:       getMfaDeviceType(serialNumber),
+		UserName:         aws.ToString(user.UserName),
+		UserArn:          aws.ToString(user.Arn),
+		EnableDate:       formatTime(device.EnableDate),
+		AssignmentStatus: string(iamtypes.AssignmentStatusTypeAssigned),
+	}
+}
+
+func newVirtualMfaDeviceRow(device iamtypes.VirtualMFADevice) mfaDeviceRow {
+	row := mfaDeviceRow{
+		SerialNumber:     aws.ToString(device.SerialNumber),
+		DeviceType:       "virtual",
+		EnableDate:       formatTime(device.EnableDate),
+		AssignmentStatus: string(iamtypes.AssignmentStatusTypeUnassigned),
+	}
+	if device.User != nil {
+		row.UserName = aws.ToString(device.User.UserName)
+		row.UserArn = aws.ToString(device.User.Arn)
+		row.AssignmentStatus = string(iamtypes.AssignmentStatusTypeAssigned)
+	}
+	return row
+}
+
+// listMfaDevices returns the MFA devices of a user
+func listMfaDevices(osqCtx context.Context, svc *iam.Client, userName string) ([]iamtypes.MFADevice, error) {
+	devices := make([]iamtypes.MFADevice, 0)
+	paginator := iam.NewListMFADevicesPaginator(svc, &iam.ListMFADevicesInput{UserName: aws.String(userName)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, page.MFADevices...)
+	}
+	return devices, nil
+}
+
+// listVirtualMfaDevices returns all virtual MFA devices of the account, assigned or not
+func listVirtualMfaDevices(osqCtx context.Context, svc *iam.Client) ([]iamtypes.VirtualMFADevice, error) {
+	devices := make([]iamtypes.VirtualMFADevice, 0)
+	paginator := iam.NewListVirtualMFADevicesPaginator(svc, &iam.ListVirtualMFADevicesInput{AssignmentStatus: iamtypes.AssignmentStatusTypeAny})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, page.VirtualMFADevices...)
+	}
+	return devices, nil
+}
+
+// ListMfaDevicesGenerate returns the rows in the table for all configured accounts
+func ListMfaDevicesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountsGlobal(osqCtx, queryContext, "aws_iam_mfa_device", processGlobalListMfaDevices)
+}
+
+func processGlobalListMfaDevices(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, "aws-global")
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_iam_mfa_device",
+		"account":   accountId,
+		"region":    "aws-global",
+	}).Debug("processing region")
+
+	svc := iam.NewFromConfig(*sess)
+
+	users, err := listUsers(osqCtx, svc)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_mfa_device",
+			"account":   accountId,
+			"region":    "aws-global",
+			"task":      "ListUsers",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
+
+	rows := make([]mfaDeviceRow, 0)
+	serialNumbers := make(map[string]bool)
+	for _, user := range users {
+		devices, err := listMfaDevices(osqCtx, svc, aws.ToString(user.UserName))
+		if err != nil {
+			utilities.GetLogger().WithFields(log.Fields{
+				"tableName": "aws_iam_mfa_device",
+				"account":   accountId,
+				"region":    "aws-global",
+				"user":      aws.ToString(user.UserName),
+				"task":      "ListMFADevices",
+				"errString": err.Error(),
+			}).Error("failed to list mfa devices")
+			continue
+		}
+		for _, device := range devices {
+			serialNumbers[aws.ToString(device.SerialNumber)] = true
+			rows = append(rows, newMfaDeviceRow(user, device))
+		}
+	}
+
+	virtualDevices, err := listVirtualMfaDevices(osqCtx, svc)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_mfa_device",
+			"account":   accountId,
+			"region":    "aws-global",
+			"task":      "ListVirtualMFADevices",
+			"errString": err.Error(),
+		}).Error("failed to list virtual mfa devices")
+	}
+	for _, device := range virtualDevices {
+		if serialNumbers[aws.ToString(device.SerialNumber)] {
+			continue
+		}
+		rows = append(rows, newVirtualMfaDeviceRow(device))
+	}
+
+	byteArr, err := json.Marshal(struct{ MfaDevices []mfaDeviceRow }{rows})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_mfa_device",
+			"account":   accountId,
+			"region":    "aws-global",
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return nil, err
+	}
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_iam_mfa_device", accountId, "aws-global", row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, "aws-global", tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
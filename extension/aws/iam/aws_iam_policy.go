@@ -12,7 +12,6 @@ package iam
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -23,94 +22,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/iam"
 )
 
-// ListPoliciesColumns returns the list of columns in the table
-func ListPoliciesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("arn"),
-		table.BigIntColumn("attachment_count"),
-		table.TextColumn("create_date"),
-		//table.BigIntColumn("create_date_ext"),
-		//table.TextColumn("create_date_loc"),
-		//table.BigIntColumn("create_date_loc_cache_end"),
-		//table.BigIntColumn("create_date_loc_cache_start"),
-		//table.TextColumn("create_date_loc_cache_zone"),
-		//table.TextColumn("create_date_loc_cache_zone_is_dst"),
-		//table.TextColumn("create_date_loc_cache_zone_name"),
-		//table.IntegerColumn("create_date_loc_cache_zone_offset"),
-		//table.TextColumn("create_date_loc_name"),
-		//table.TextColumn("create_date_loc_tx"),
-		//table.IntegerColumn("create_date_loc_tx_index"),
-		//table.TextColumn("create_date_loc_tx_isstd"),
-		//table.TextColumn("create_date_loc_tx_isutc"),
-		//table.BigIntColumn("create_date_loc_tx_when"),
-		//table.TextColumn("create_date_loc_zone"),
-		//table.TextColumn("create_date_loc_zone_is_dst"),
-		//table.TextColumn("create_date_loc_zone_name"),
-		//table.IntegerColumn("create_date_loc_zone_offset"),
-		//table.BigIntColumn("create_date_wall"),
-		table.TextColumn("default_version_id"),
-		table.TextColumn("description"),
-		table.TextColumn("is_attachable"),
-		table.TextColumn("path"),
-		table.BigIntColumn("permissions_boundary_usage_count"),
-		table.TextColumn("policy_id"),
-		table.TextColumn("policy_name"),
-		table.TextColumn("update_date"),
-		//table.BigIntColumn("update_date_ext"),
-		//table.TextColumn("update_date_loc"),
-		//table.BigIntColumn("update_date_loc_cache_end"),
-		//table.BigIntColumn("update_date_loc_cache_start"),
-		//table.TextColumn("update_date_loc_cache_zone"),
-		//table.TextColumn("update_date_loc_cache_zone_is_dst"),
-		//table.TextColumn("update_date_loc_cache_zone_name"),
-		//table.IntegerColumn("update_date_loc_cache_zone_offset"),
-		//table.TextColumn("update_date_loc_name"),
-		//table.TextColumn("update_date_loc_tx"),
-		//table.IntegerColumn("update_date_loc_tx_index"),
-		//table.TextColumn("update_date_loc_tx_isstd"),
-		//table.TextColumn("update_date_loc_tx_isutc"),
-		//table.BigIntColumn("update_date_loc_tx_when"),
-		//table.TextColumn("update_date_loc_zone"),
-		//table.TextColumn("update_date_loc_zone_is_dst"),
-		//table.TextColumn("update_date_loc_zone_name"),
-		//table.IntegerColumn("update_date_loc_zone_offset"),
-		//table.BigIntColumn("update_date_wall"),
-
-	}
-}
-
 // ListPoliciesGenerate returns the rows in the table for all configured accounts
 func ListPoliciesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_iam_policy", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_iam_policy",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountListPolicies(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_iam_policy", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_iam_policy",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountListPolicies(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountsGlobal(osqCtx, queryContext, "aws_iam_policy", processGlobalListPolicies)
 }
 
 func processGlobalListPolicies(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
@@ -173,20 +87,3 @@ func processGlobalListPolicies(osqCtx context.Context, queryContext table.QueryC
 	}
 	return resultMap, nil
 }
-
-func processAccountListPolicies(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	tableConfig, ok := utilities.TableConfigurationMap["aws_iam_policy"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_iam_policy",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	result, err := processGlobalListPolicies(osqCtx, queryContext, tableConfig, account)
-	if err != nil {
-		return resultMap, err
-	}
-	resultMap = append(resultMap, result...)
-	return resultMap, nil
-}
@@ -0,0 +1,231 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package iam
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+const (
+	policyTypeManaged = "managed"
+	policyTypeInline  = "inline"
+)
+
+// policyStatementRow is a row of aws_iam_policy_statement: one action and resource of a statement of a managed policy
+// (default version) or of an inline policy of a role, user or group. Entity columns are only set for inline policies
+type policyStatementRow struct {
+	PolicyType   string
+	PolicyName   string
+	PolicyArn    string
+	VersionId    string
+	IsAwsManaged bool
+	EntityType   string
+	EntityName   string
+	EntityArn    string
+	extaws.PolicyStatement
+}
+
+// authorizationDetails holds all pages of GetAccountAuthorizationDetails
+type authorizationDetails struct {
+	Groups   []iamtypes.GroupDetail
+	Policies []iamtypes.ManagedPolicyDetail
+	Roles    []iamtypes.RoleDetail
+	Users    []iamtypes.UserDetail
+}
+
+// getAuthorizationDetails returns the entities of given types with their policy documents
+func getAuthorizationDetails(osqCtx context.Context, svc *iam.Client, filter ...iamtypes.EntityType) (*authorizationDetails, error) {
+	details := &authorizationDetails{}
+	paginator := iam.NewGetAccountAuthorizationDetailsPaginator(svc, &iam.GetAccountAuthorizationDetailsInput{Filter: filter})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			return nil, err
+		}
+		details.Groups = append(details.Groups, page.GroupDetailList...)
+		details.Policies = append(details.Policies, page.Policies...)
+		details.Roles = append(details.Roles, page.RoleDetailList...)
+		details.Users = append(details.Users, page.UserDetailList...)
+	}
+	return details, nil
+}
+
+// isAwsManagedPolicy returns true for policies managed by AWS, e.g. arn:aws:iam::aws:policy/ReadOnlyAccess
+func isAwsManagedPolicy(policyArn string) bool {
+	return strings.Contains(policyArn, ":iam::aws:policy/")
+}
+
+// getPolicyStatementRows parses document and returns its statements with the columns of the policy set from row
+func getPolicyStatementRows(row policyStatementRow, document *string) ([]policyStatementRow, error) {
+	statements, err := extaws.ParsePolicyDocument(aws.ToString(document))
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]policyStatementRow, 0, len(statements))
+	for _, statement := range statements {
+		row.PolicyStatement = statement
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// getManagedPolicyStatementRows returns the statements of the default version of a managed policy
+func getManagedPolicyStatementRows(policy iamtypes.ManagedPolicyDetail) ([]policyStatementRow, error) {
+	for _, version := range policy.PolicyVersionList {
+		if !version.IsDefaultVersion {
+			continue
+		}
+		return getPolicyStatementRows(policyStatementRow{
+			PolicyType:   policyTypeManaged,
+			PolicyName:   aws.ToString(policy.PolicyName),
+			PolicyArn:    aws.ToString(policy.Arn),
+			VersionId:    aws.ToString(version.VersionId),
+			IsAwsManaged: isAwsManagedPolicy(aws.ToString(policy.Arn)),
+		}, version.Document)
+	}
+	return nil, nil
+}
+
+// getInlinePolicyStatementRows returns the statements of the inline policies of a role, user or group
+func getInlinePolicyStatementRows(entityType iamtypes.EntityType, entityName, entityArn *string, policies []iamtypes.PolicyDetail) ([]policyStatementRow, error) {
+	rows := make([]policyStatementRow, 0)
+	for _, policy := range policies {
+		policyRows, err := getPolicyStatementRows(policyStatementRow{
+			PolicyType: policyTypeInline,
+			PolicyName: aws.ToString(policy.PolicyName),
+			EntityType: string(entityType),
+			EntityName: aws.ToString(entityName),
+			EntityArn:  aws.ToString(entityArn),
+		}, policy.PolicyDocument)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, policyRows...)
+	}
+	return rows, nil
+}
+
+// ListPolicyStatementsGenerate returns the rows in the table for all configured accounts
+func ListPolicyStatementsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountsGlobal(osqCtx, queryContext, "aws_iam_policy_statement", processGlobalListPolicyStatements)
+}
+
+func processGlobalListPolicyStatements(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, "aws-global")
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_iam_policy_statement",
+		"account":   accountId,
+		"region":    "aws-global",
+	}).Debug("processing region")
+
+	svc := iam.NewFromConfig(*sess)
+
+	details, err := getAuthorizationDetails(osqCtx, svc, iamtypes.EntityTypeLocalManagedPolicy, iamtypes.EntityTypeAWSManagedPolicy,
+		iamtypes.EntityTypeRole, iamtypes.EntityTypeUser, iamtypes.EntityTypeGroup)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_policy_statement",
+			"account":   accountId,
+			"region":    "aws-global",
+			"task":      "GetAccountAuthorizationDetails",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
+
+	statements := make([]policyStatementRow, 0)
+	logParseError := func(policyName string, err error) {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_policy_statement",
+			"account":   accountId,
+			"region":    "aws-global",
+			"policy":    policyName,
+			"errString": err.Error(),
+		}).Error("failed to parse policy")
+	}
+	for _, policy := range details.Policies {
+		// AWS managed policies are only listed if they are in use, there are more than a thousand of them
+		if isAwsManagedPolicy(aws.ToString(policy.Arn)) && aws.ToInt32(policy.AttachmentCount) == 0 && aws.ToInt32(policy.PermissionsBoundaryUsageCount) == 0 {
+			continue
+		}
+		rows, err := getManagedPolicyStatementRows(policy)
+		if err != nil {
+			logParseError(aws.ToString(policy.Arn), err)
+			continue
+		}
+		statements = append(statements, rows...)
+	}
+	for _, role := range details.Roles {
+		rows, err := getInlinePolicyStatementRows(iamtypes.EntityTypeRole, role.RoleName, role.Arn, role.RolePolicyList)
+		if err != nil {
+			logParseError(aws.ToString(role.Arn), err)
+			continue
+		}
+		statements = append(statements, rows...)
+	}
+	for _, user := range details.Users {
+		rows, err := getInlinePolicyStatementRows(iamtypes.EntityTypeUser, user.UserName, user.Arn, user.UserPolicyList)
+		if err != nil {
+			logParseError(aws.ToString(user.Arn), err)
+			continue
+		}
+		statements = append(statements, rows...)
+	}
+	for _, group := range details.Groups {
+		rows, err := getInlinePolicyStatementRows(iamtypes.EntityTypeGroup, group.GroupName, group.Arn, group.GroupPolicyList)
+		if err != nil {
+			logParseError(aws.ToString(group.Arn), err)
+			continue
+		}
+		statements = append(statements, rows...)
+	}
+
+	byteArr, err := json.Marshal(struct{ Statements []policyStatementRow }{statements})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_policy_statement",
+			"account":   accountId,
+			"region":    "aws-global",
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return resultMap, err
+	}
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_iam_policy_statement", accountId, "aws-global", row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, "aws-global", tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
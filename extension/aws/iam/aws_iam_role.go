@@ -12,7 +12,6 @@ package iam
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
@@ -20,103 +19,99 @@ import (
 
 	"github.com/Uptycs/basequery-go/plugin/table"
 	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 )
 
-// ListRolesColumns returns the list of columns in the table
-func ListRolesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("arn"),
-		table.TextColumn("assume_role_policy_document"),
-		table.TextColumn("create_date"),
-		//table.BigIntColumn("create_date_ext"),
-		//table.TextColumn("create_date_loc"),
-		//table.BigIntColumn("create_date_loc_cache_end"),
-		//table.BigIntColumn("create_date_loc_cache_start"),
-		//table.TextColumn("create_date_loc_cache_zone"),
-		//table.TextColumn("create_date_loc_cache_zone_is_dst"),
-		//table.TextColumn("create_date_loc_cache_zone_name"),
-		//table.IntegerColumn("create_date_loc_cache_zone_offset"),
-		//table.TextColumn("create_date_loc_name"),
-		//table.TextColumn("create_date_loc_tx"),
-		//table.IntegerColumn("create_date_loc_tx_index"),
-		//table.TextColumn("create_date_loc_tx_isstd"),
-		//table.TextColumn("create_date_loc_tx_isutc"),
-		//table.BigIntColumn("create_date_loc_tx_when"),
-		//table.TextColumn("create_date_loc_zone"),
-		//table.TextColumn("create_date_loc_zone_is_dst"),
-		//table.TextColumn("create_date_loc_zone_name"),
-		//table.IntegerColumn("create_date_loc_zone_offset"),
-		//table.BigIntColumn("create_date_wall"),
-		table.TextColumn("description"),
-		table.BigIntColumn("max_session_duration"),
-		table.TextColumn("path"),
-		table.TextColumn("permissions_boundary"),
-		//table.TextColumn("permissions_boundary_permissions_boundary_arn"),
-		//table.TextColumn("permissions_boundary_permissions_boundary_type"),
-		table.TextColumn("role_id"),
-		table.TextColumn("role_last_used"),
-		//table.TextColumn("role_last_used_last_used_date"),
-		//table.BigIntColumn("role_last_used_last_used_date_ext"),
-		//table.TextColumn("role_last_used_last_used_date_loc"),
-		//table.BigIntColumn("role_last_used_last_used_date_loc_cache_end"),
-		//table.BigIntColumn("role_last_used_last_used_date_loc_cache_start"),
-		//table.TextColumn("role_last_used_last_used_date_loc_cache_zone"),
-		//table.TextColumn("role_last_used_last_used_date_loc_cache_zone_is_dst"),
-		//table.TextColumn("role_last_used_last_used_date_loc_cache_zone_name"),
-		//table.IntegerColumn("role_last_used_last_used_date_loc_cache_zone_offset"),
-		//table.TextColumn("role_last_used_last_used_date_loc_name"),
-		//table.TextColumn("role_last_used_last_used_date_loc_tx"),
-		//table.IntegerColumn("role_last_used_last_used_date_loc_tx_index"),
-		//table.TextColumn("role_last_used_last_used_date_loc_tx_isstd"),
-		//table.TextColumn("role_last_used_last_used_date_loc_tx_isutc"),
-		//table.BigIntColumn("role_last_used_last_used_date_loc_tx_when"),
-		//table.TextColumn("role_last_used_last_used_date_loc_zone"),
-		//table.TextColumn("role_last_used_last_used_date_loc_zone_is_dst"),
-		//table.TextColumn("role_last_used_last_used_date_loc_zone_name"),
-		//table.IntegerColumn("role_last_used_last_used_date_loc_zone_offset"),
-		//table.BigIntColumn("role_last_used_last_used_date_wall"),
-		//table.TextColumn("role_last_used_region"),
-		table.TextColumn("role_name"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
+// maxRoleWorkers bounds the number of GetRole requests of an account in flight
+const maxRoleWorkers = 8
+
+// roleRow is a row of aws_iam_role, see userRow. The trust policy is the URL decoded JSON document.
+// Permissions boundary and last use are both kept in the format of GetRole and flattened.
+type roleRow struct {
+	RoleName                 string
+	RoleId                   string
+	Arn                      string
+	Path                     string
+	Description              string
+	CreateDate               string
+	MaxSessionDuration       *int32
+	AssumeRolePolicyDocument json.RawMessage                       `json:",omitempty"`
+	PermissionsBoundary      *iamtypes.AttachedPermissionsBoundary `json:",omitempty"`
+	RoleLastUsed             *iamtypes.RoleLastUsed                `json:",omitempty"`
+	PermissionsBoundaryArn   string
+	PermissionsBoundaryType  string
+	RoleLastUsedDate         string
+	RoleLastUsedRegion       string
+	Tags                     map[string]string `json:",omitempty"`
+}
 
+func newRoleRow(role iamtypes.Role) roleRow {
+	row := roleRow{
+		RoleName:            aws.ToString(role.RoleName),
+		RoleId:              aws.ToString(role.RoleId),
+		Arn:                 aws.ToString(role.Arn),
+		Path:                aws.ToString(role.Path),
+		Description:         aws.ToString(role.Description),
+		CreateDate:          formatTime(role.CreateDate),
+		MaxSessionDuration:  role.MaxSessionDuration,
+		PermissionsBoundary: role.PermissionsBoundary,
+		RoleLastUsed:        role.RoleLastUsed,
+	}
+	if document := extaws.DecodePolicyDocument(aws.ToString(role.AssumeRolePolicyDocument)); json.Valid([]byte(document)) {
+		row.AssumeRolePolicyDocument = json.RawMessage(document)
+	}
+	if role.PermissionsBoundary != nil {
+		row.PermissionsBoundaryArn = aws.ToString(role.PermissionsBoundary.PermissionsBoundaryArn)
+		row.PermissionsBoundaryType = string(role.PermissionsBoundary.PermissionsBoundaryType)
+	}
+	if role.RoleLastUsed != nil {
+		row.RoleLastUsedDate = formatTime(role.RoleLastUsed.LastUsedDate)
+		row.RoleLastUsedRegion = aws.ToString(role.RoleLastUsed.Region)
+	}
+	if len(role.Tags) > 0 {
+		row.Tags = make(map[string]string, len(role.Tags))
+		for _, tag := range role.Tags {
+			row.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
 	}
+	return row
 }
 
-// ListRolesGenerate returns the rows in the table for all configured accounts
-func ListRolesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_iam_role", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_iam_role",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountListRoles(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_iam_role", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_iam_role",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountListRoles(osqCtx, queryContext, &account)
+// getRoleRows returns the rows of roles. ListRoles leaves out permissions boundary, last use and tags,
+// so each role is read again with GetRole. A role GetRole fails for keeps what ListRoles returned.
+func getRoleRows(osqCtx context.Context, svc *iam.Client, accountId string, roles []iamtypes.Role) []roleRow {
+	rows := make([]roleRow, len(roles))
+	collector := utilities.NewRowCollector(osqCtx, maxRoleWorkers)
+	for index, role := range roles {
+		index, role := index, role
+		rows[index] = newRoleRow(role)
+		collector.Go(func() {
+			output, err := svc.GetRole(collector.Context(), &iam.GetRoleInput{RoleName: role.RoleName})
 			if err != nil {
-				continue
+				utilities.GetLogger().WithFields(log.Fields{
+					"tableName": "aws_iam_role",
+					"account":   accountId,
+					"region":    "aws-global",
+					"task":      "GetRole",
+					"role":      aws.ToString(role.RoleName),
+					"errString": err.Error(),
+				}).Error("failed to get role")
+				return
 			}
-			resultMap = append(resultMap, results...)
-		}
+			if output.Role != nil {
+				rows[index] = newRoleRow(*output.Role)
+			}
+		})
 	}
+	collector.Wait()
+	return rows
+}
 
-	return resultMap, nil
+// ListRolesGenerate returns the rows in the table for all configured accounts
+func ListRolesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountsGlobal(osqCtx, queryContext, "aws_iam_role", processGlobalListRoles)
 }
 
 func processGlobalListRoles(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
@@ -142,7 +137,8 @@ func processGlobalListRoles(osqCtx context.Context, queryContext table.QueryCont
 
 	paginator := iam.NewListRolesPaginator(svc, params)
 
-	for {
+	listedRoles := make([]iamtypes.Role, 0)
+	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(osqCtx)
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
@@ -154,45 +150,28 @@ func processGlobalListRoles(osqCtx context.Context, queryContext table.QueryCont
 			}).Error("failed to process region")
 			return resultMap, err
 		}
-		byteArr, err := json.Marshal(page)
-		if err != nil {
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_iam_role",
-				"account":   accountId,
-				"region":    "aws-global",
-				"task":      "ListRoles",
-				"errString": err.Error(),
-			}).Error("failed to marshal response")
-			return nil, err
-		}
-		table := utilities.NewTable(byteArr, tableConfig)
-		for _, row := range table.Rows {
-			if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_iam_role", accountId, "aws-global", row) {
-				continue
-			}
-			result := extaws.RowToMap(row, accountId, "aws-global", tableConfig)
-			resultMap = append(resultMap, result)
-		}
-		if !paginator.HasMorePages() {
-			break
-		}
+		listedRoles = append(listedRoles, page.Roles...)
 	}
-	return resultMap, nil
-}
+	roles := getRoleRows(osqCtx, svc, accountId, listedRoles)
 
-func processAccountListRoles(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	tableConfig, ok := utilities.TableConfigurationMap["aws_iam_role"]
-	if !ok {
+	byteArr, err := json.Marshal(struct{ Roles []roleRow }{roles})
+	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "aws_iam_role",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
+			"account":   accountId,
+			"region":    "aws-global",
+			"task":      "ListRoles",
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return nil, err
 	}
-	result, err := processGlobalListRoles(osqCtx, queryContext, tableConfig, account)
-	if err != nil {
-		return resultMap, err
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_iam_role", accountId, "aws-global", row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, "aws-global", tableConfig)
+		resultMap = append(resultMap, result)
 	}
-	resultMap = append(resultMap, result...)
 	return resultMap, nil
 }
@@ -0,0 +1,115 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package iam
+
+import (
+	"context"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// roleTrustStatementRow is a row of aws_iam_role_trust_statement: one principal and action of a statement
+// of the trust policy (AssumeRolePolicyDocument) of a role
+type roleTrustStatementRow struct {
+	RoleName string
+	RoleId   string
+	RoleArn  string
+	extaws.PolicyStatement
+}
+
+// ListRoleTrustStatementsGenerate returns the rows in the table for all configured accounts
+func ListRoleTrustStatementsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountsGlobal(osqCtx, queryContext, "aws_iam_role_trust_statement", processGlobalListRoleTrustStatements)
+}
+
+func processGlobalListRoleTrustStatements(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, "aws-global")
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_iam_role_trust_statement",
+		"account":   accountId,
+		"region":    "aws-global",
+	}).Debug("processing region")
+
+	svc := iam.NewFromConfig(*sess)
+
+	details, err := getAuthorizationDetails(osqCtx, svc, iamtypes.EntityTypeRole)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_role_trust_statement",
+			"account":   accountId,
+			"region":    "aws-global",
+			"task":      "GetAccountAuthorizationDetails",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
+
+	statements := make([]roleTrustStatementRow, 0)
+	for _, role := range details.Roles {
+		policyStatements, err := extaws.ParsePolicyDocument(aws.ToString(role.AssumeRolePolicyDocument))
+		if err != nil {
+			utilities.GetLogger().WithFields(log.Fields{
+				"tableName": "aws_iam_role_trust_statement",
+				"account":   accountId,
+				"region":    "aws-global",
+				"role":      aws.ToString(role.Arn),
+				"errString": err.Error(),
+			}).Error("failed to parse policy")
+			continue
+		}
+		for _, policyStatement := range policyStatements {
+			statements = append(statements, roleTrustStatementRow{
+				RoleName:        aws.ToString(role.RoleName),
+				RoleId:          aws.ToString(role.RoleId),
+				RoleArn:         aws.ToString(role.Arn),
+				PolicyStatement: policyStatement,
+			})
+		}
+	}
+
+	byteArr, err := json.Marshal(struct{ Statements []roleTrustStatementRow }{statements})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_role_trust_statement",
+			"account":   accountId,
+			"region":    "aws-global",
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return resultMap, err
+	}
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_iam_role_trust_statement", accountId, "aws-global", row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, "aws-global", tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
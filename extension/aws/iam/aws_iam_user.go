@@ -12,7 +12,7 @@ package iam
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -20,97 +20,62 @@ import (
 
 	"github.com/Uptycs/basequery-go/plugin/table"
 	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 )
 
-// ListUsersColumns returns the list of columns in the table
-func ListUsersColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("arn"),
-		table.TextColumn("create_date"),
-		//table.BigIntColumn("create_date_ext"),
-		//table.TextColumn("create_date_loc"),
-		//table.BigIntColumn("create_date_loc_cache_end"),
-		//table.BigIntColumn("create_date_loc_cache_start"),
-		//table.TextColumn("create_date_loc_cache_zone"),
-		//table.TextColumn("create_date_loc_cache_zone_is_dst"),
-		//table.TextColumn("create_date_loc_cache_zone_name"),
-		//table.IntegerColumn("create_date_loc_cache_zone_offset"),
-		//table.TextColumn("create_date_loc_name"),
-		//table.TextColumn("create_date_loc_tx"),
-		//table.IntegerColumn("create_date_loc_tx_index"),
-		//table.TextColumn("create_date_loc_tx_isstd"),
-		//table.TextColumn("create_date_loc_tx_isutc"),
-		//table.BigIntColumn("create_date_loc_tx_when"),
-		//table.TextColumn("create_date_loc_zone"),
-		//table.TextColumn("create_date_loc_zone_is_dst"),
-		//table.TextColumn("create_date_loc_zone_name"),
-		//table.IntegerColumn("create_date_loc_zone_offset"),
-		//table.BigIntColumn("create_date_wall"),
-		table.TextColumn("password_last_used"),
-		//table.BigIntColumn("password_last_used_ext"),
-		//table.TextColumn("password_last_used_loc"),
-		//table.BigIntColumn("password_last_used_loc_cache_end"),
-		//table.BigIntColumn("password_last_used_loc_cache_start"),
-		//table.TextColumn("password_last_used_loc_cache_zone"),
-		//table.TextColumn("password_last_used_loc_cache_zone_is_dst"),
-		//table.TextColumn("password_last_used_loc_cache_zone_name"),
-		//table.IntegerColumn("password_last_used_loc_cache_zone_offset"),
-		//table.TextColumn("password_last_used_loc_name"),
-		//table.TextColumn("password_last_used_loc_tx"),
-		//table.IntegerColumn("password_last_used_loc_tx_index"),
-		//table.TextColumn("password_last_used_loc_tx_isstd"),
-		//table.TextColumn("password_last_used_loc_tx_isutc"),
-		//table.BigIntColumn("password_last_used_loc_tx_when"),
-		//table.TextColumn("password_last_used_loc_zone"),
-		//table.TextColumn("password_last_used_loc_zone_is_dst"),
-		//table.TextColumn("password_last_used_loc_zone_name"),
-		//table.IntegerColumn("password_last_used_loc_zone_offset"),
-		//table.BigIntColumn("password_last_used_wall"),
-		table.TextColumn("path"),
-		table.TextColumn("permissions_boundary"),
-		//table.TextColumn("permissions_boundary_permissions_boundary_arn"),
-		//table.TextColumn("permissions_boundary_permissions_boundary_type"),
-		table.TextColumn("tags"),
-		//table.TextColumn("tags_key"),
-		//table.TextColumn("tags_value"),
-		table.TextColumn("user_id"),
-		table.TextColumn("user_name"),
+// userRow is a row of aws_iam_user. Timestamps are RFC3339 strings instead of the fields of time.Time,
+// permissions boundary and tags keep the format of ListUsers
+type userRow struct {
+	UserName            string
+	UserId              string
+	Arn                 string
+	Path                string
+	CreateDate          string
+	PasswordLastUsed    string
+	PermissionsBoundary *iamtypes.AttachedPermissionsBoundary `json:",omitempty"`
+	Tags                []iamtypes.Tag                        `json:",omitempty"`
+}
+
+// formatTime returns t as an RFC3339 string in UTC, or an empty string if t is not set
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
 	}
+	return t.UTC().Format(time.RFC3339)
 }
 
-// ListUsersGenerate returns the rows in the table for all configured accounts
-func ListUsersGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_iam_user", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_iam_user",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountListUsers(osqCtx, queryContext, nil)
+func newUserRow(user iamtypes.User) userRow {
+	return userRow{
+		UserName:            aws.ToString(user.UserName),
+		UserId:              aws.ToString(user.UserId),
+		Arn:                 aws.ToString(user.Arn),
+		Path:                aws.ToString(user.Path),
+		CreateDate:          formatTime(user.CreateDate),
+		PasswordLastUsed:    formatTime(user.PasswordLastUsed),
+		PermissionsBoundary: user.PermissionsBoundary,
+		Tags:                user.Tags,
+	}
+}
+
+// listUsers returns all users of the account
+func listUsers(osqCtx context.Context, svc *iam.Client) ([]iamtypes.User, error) {
+	users := make([]iamtypes.User, 0)
+	paginator := iam.NewListUsersPaginator(svc, &iam.ListUsersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
 		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_iam_user", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_iam_user",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountListUsers(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
+			return nil, err
 		}
+		users = append(users, page.Users...)
 	}
+	return users, nil
+}
 
-	return resultMap, nil
+// ListUsersGenerate returns the rows in the table for all configured accounts
+func ListUsersGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountsGlobal(osqCtx, queryContext, "aws_iam_user", processGlobalListUsers)
 }
 
 func processGlobalListUsers(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
@@ -132,61 +97,42 @@ func processGlobalListUsers(osqCtx context.Context, queryContext table.QueryCont
 	}).Debug("processing region")
 
 	svc := iam.NewFromConfig(*sess)
-	params := &iam.ListUsersInput{}
 
-	paginator := iam.NewListUsersPaginator(svc, params)
+	users, err := listUsers(osqCtx, svc)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_iam_user",
+			"account":   accountId,
+			"region":    "aws-global",
+			"task":      "ListUsers",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
 
-	for {
-		page, err := paginator.NextPage(osqCtx)
-		if err != nil {
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_iam_user",
-				"account":   accountId,
-				"region":    "aws-global",
-				"task":      "ListUsers",
-				"errString": err.Error(),
-			}).Error("failed to process region")
-			return resultMap, err
-		}
-		byteArr, err := json.Marshal(page)
-		if err != nil {
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_iam_user",
-				"account":   accountId,
-				"region":    "aws-global",
-				"task":      "ListUsers",
-				"errString": err.Error(),
-			}).Error("failed to marshal response")
-			return nil, err
-		}
-		table := utilities.NewTable(byteArr, tableConfig)
-		for _, row := range table.Rows {
-			if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_iam_user", accountId, "aws-global", row) {
-				continue
-			}
-			result := extaws.RowToMap(row, accountId, "aws-global", tableConfig)
-			resultMap = append(resultMap, result)
-		}
-		if !paginator.HasMorePages() {
-			break
-		}
+	rows := make([]userRow, 0, len(users))
+	for _, user := range users {
+		rows = append(rows, newUserRow(user))
 	}
-	return resultMap, nil
-}
 
-func processAccountListUsers(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	tableConfig, ok := utilities.TableConfigurationMap["aws_iam_user"]
-	if !ok {
+	byteArr, err := json.Marshal(struct{ Users []userRow }{rows})
+	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "aws_iam_user",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
+			"account":   accountId,
+			"region":    "aws-global",
+			"task":      "ListUsers",
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return nil, err
 	}
-	result, err := processGlobalListUsers(osqCtx, queryContext, tableConfig, account)
-	if err != nil {
-		return resultMap, err
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_iam_user", accountId, "aws-global", row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, "aws-global", tableConfig)
+		resultMap = append(resultMap, result)
 	}
-	resultMap = append(resultMap, result...)
 	return resultMap, nil
 }
@@ -0,0 +1,38 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package iam
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/Uptycs/cloudquery/utilities/vcr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	utilities.CreateLogger(true, 20, 1, 30)
+	os.Exit(m.Run())
+}
+
+func TestGenerate(t *testing.T) {
+	assert.Nil(t, utilities.ReadTableConfig(defaultTableConfig))
+	utilities.AwsAccountID = "123456789012"
+	vcr.RunGoldenTests(t, map[string]table.GenerateFunc{
+		"aws_iam_access_key":           ListAccessKeysGenerate,
+		"aws_iam_credential_report":    GetCredentialReportGenerate,
+		"aws_iam_mfa_device":           ListMfaDevicesGenerate,
+		"aws_iam_policy_statement":     ListPolicyStatementsGenerate,
+		"aws_iam_role":                 ListRolesGenerate,
+		"aws_iam_role_trust_statement": ListRoleTrustStatementsGenerate,
+	})
+}
@@ -0,0 +1,34 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package iam
+
+import (
+	_ "embed"
+
+	"github.com/Uptycs/cloudquery/utilities"
+)
+
+//go:embed table_config.json
+var defaultTableConfig []byte
+
+func init() {
+	utilities.RegisterTableConfigFile("aws/iam/table_config.json", defaultTableConfig)
+
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_iam_user", Generate: ListUsersGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_iam_role", Generate: ListRolesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_iam_group", Generate: ListGroupsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_iam_policy", Generate: ListPoliciesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_iam_account_password_policy", Generate: GetAccountPasswordPolicyGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_iam_access_key", Generate: ListAccessKeysGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_iam_mfa_device", Generate: ListMfaDevicesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_iam_credential_report", Generate: GetCredentialReportGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_iam_policy_statement", Generate: ListPolicyStatementsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_iam_role_trust_statement", Generate: ListRoleTrustStatementsGenerate})
+}
@@ -0,0 +1,141 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// aliasRow is a row of aws_lambda_alias, an AliasConfiguration of the Lambda API with the name of its function
+type aliasRow struct {
+	FunctionName    string
+	AliasArn        string
+	Name            string
+	FunctionVersion string
+	Description     string
+	RevisionId      string
+	RoutingConfig   *lambdatypes.AliasRoutingConfiguration
+}
+
+func newAliasRow(functionName string, alias lambdatypes.AliasConfiguration) aliasRow {
+	return aliasRow{
+		FunctionName:    functionName,
+		AliasArn:        aws.ToString(alias.AliasArn),
+		Name:            aws.ToString(alias.Name),
+		FunctionVersion: aws.ToString(alias.FunctionVersion),
+		Description:     aws.ToString(alias.Description),
+		RevisionId:      aws.ToString(alias.RevisionId),
+		RoutingConfig:   alias.RoutingConfig,
+	}
+}
+
+// listAliases returns the aliases of a function
+func listAliases(osqCtx context.Context, svc *lambda.Client, functionName string) ([]aliasRow, error) {
+	aliases := make([]aliasRow, 0)
+	paginator := lambda.NewListAliasesPaginator(svc, &lambda.ListAliasesInput{FunctionName: aws.String(functionName)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			return nil, err
+		}
+		for _, alias := range page.Aliases {
+			aliases = append(aliases, newAliasRow(functionName, alias))
+		}
+	}
+	return aliases, nil
+}
+
+// ListAliasesGenerate returns the rows in the table for all configured accounts
+func ListAliasesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_lambda_alias", processRegionListAliases)
+}
+
+func processRegionListAliases(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, *region.RegionName)
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_lambda_alias",
+		"account":   accountId,
+		"region":    *region.RegionName,
+	}).Debug("processing region")
+
+	svc := lambda.NewFromConfig(*sess)
+
+	functions, err := listFunctions(osqCtx, svc, false)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_lambda_alias",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"task":      "ListFunctions",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
+
+	aliases := make([]aliasRow, 0)
+	for _, function := range functions {
+		functionName := aws.ToString(function.FunctionName)
+		functionAliases, err := listAliases(osqCtx, svc, functionName)
+		if err != nil {
+			utilities.GetLogger().WithFields(log.Fields{
+				"tableName": "aws_lambda_alias",
+				"account":   accountId,
+				"region":    *region.RegionName,
+				"function":  functionName,
+				"task":      "ListAliases",
+				"errString": err.Error(),
+			}).Error("failed to list aliases")
+			continue
+		}
+		aliases = append(aliases, functionAliases...)
+	}
+
+	byteArr, err := json.Marshal(struct{ Aliases []aliasRow }{aliases})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_lambda_alias",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return resultMap, err
+	}
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_lambda_alias", accountId, *region.RegionName, row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, *region.RegionName, tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
@@ -0,0 +1,208 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+type functionLayer struct {
+	Arn      string
+	CodeSize int64
+}
+
+type environmentError struct {
+	ErrorCode string
+	Message   string
+}
+
+// functionRow is a row of aws_lambda_function. Nested attributes are moved to the top level,
+// so that lists are single JSON encoded columns instead of one row per item.
+// Only the names of environment variables are kept, their values often hold secrets.
+type functionRow struct {
+	FunctionName             string
+	FunctionArn              string
+	Version                  string
+	Description              string
+	Runtime                  string
+	Handler                  string
+	Role                     string
+	PackageType              string
+	CodeSize                 int64
+	CodeSha256               string
+	MemorySize               *int32
+	Timeout                  *int32
+	LastModified             string
+	State                    string
+	StateReason              string
+	LastUpdateStatus         string
+	KMSKeyArn                string
+	MasterArn                string
+	RevisionId               string
+	Architectures            []lambdatypes.Architecture
+	TracingMode              string
+	VpcId                    string
+	SubnetIds                []string
+	SecurityGroupIds         []string
+	EnvironmentVariableNames []string
+	EnvironmentError         *environmentError `json:",omitempty"`
+	DeadLetterTargetArn      string
+	Layers                   []functionLayer
+}
+
+func newFunctionRow(function lambdatypes.FunctionConfiguration) functionRow {
+	row := functionRow{
+		FunctionName:             aws.ToString(function.FunctionName),
+		FunctionArn:              aws.ToString(function.FunctionArn),
+		Version:                  aws.ToString(function.Version),
+		Description:              aws.ToString(function.Description),
+		Runtime:                  string(function.Runtime),
+		Handler:                  aws.ToString(function.Handler),
+		Role:                     aws.ToString(function.Role),
+		PackageType:              string(function.PackageType),
+		CodeSize:                 function.CodeSize,
+		CodeSha256:               aws.ToString(function.CodeSha256),
+		MemorySize:               function.MemorySize,
+		Timeout:                  function.Timeout,
+		LastModified:             aws.ToString(function.LastModified),
+		State:                    string(function.State),
+		StateReason:              aws.ToString(function.StateReason),
+		LastUpdateStatus:         string(function.LastUpdateStatus),
+		KMSKeyArn:                aws.ToString(function.KMSKeyArn),
+		MasterArn:                aws.ToString(function.MasterArn),
+		RevisionId:               aws.ToString(function.RevisionId),
+		Architectures:            append([]lambdatypes.Architecture{}, function.Architectures...),
+		SubnetIds:                []string{},
+		SecurityGroupIds:         []string{},
+		EnvironmentVariableNames: []string{},
+		Layers:                   make([]functionLayer, 0, len(function.Layers)),
+	}
+	if function.TracingConfig != nil {
+		row.TracingMode = string(function.TracingConfig.Mode)
+	}
+	if function.VpcConfig != nil {
+		row.VpcId = aws.ToString(function.VpcConfig.VpcId)
+		row.SubnetIds = append(row.SubnetIds, function.VpcConfig.SubnetIds...)
+		row.SecurityGroupIds = append(row.SecurityGroupIds, function.VpcConfig.SecurityGroupIds...)
+	}
+	if function.Environment != nil {
+		for name := range function.Environment.Variables {
+			row.EnvironmentVariableNames = append(row.EnvironmentVariableNames, name)
+		}
+		sort.Strings(row.EnvironmentVariableNames)
+		if function.Environment.Error != nil {
+			row.EnvironmentError = &environmentError{
+				ErrorCode: aws.ToString(function.Environment.Error.ErrorCode),
+				Message:   aws.ToString(function.Environment.Error.Message),
+			}
+		}
+	}
+	if function.DeadLetterConfig != nil {
+		row.DeadLetterTargetArn = aws.ToString(function.DeadLetterConfig.TargetArn)
+	}
+	for _, layer := range function.Layers {
+		row.Layers = append(row.Layers, functionLayer{Arn: aws.ToString(layer.Arn), CodeSize: layer.CodeSize})
+	}
+	return row
+}
+
+// listFunctions returns the functions of the region. If allVersions is true, published versions are
+// returned along with $LATEST
+func listFunctions(osqCtx context.Context, svc *lambda.Client, allVersions bool) ([]lambdatypes.FunctionConfiguration, error) {
+	params := &lambda.ListFunctionsInput{}
+	if allVersions {
+		params.FunctionVersion = lambdatypes.FunctionVersionAll
+	}
+	functions := make([]lambdatypes.FunctionConfiguration, 0)
+	paginator := lambda.NewListFunctionsPaginator(svc, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			return nil, err
+		}
+		functions = append(functions, page.Functions...)
+	}
+	return functions, nil
+}
+
+// ListFunctionsGenerate returns the rows in the table for all configured accounts
+func ListFunctionsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_lambda_function", processRegionListFunctions)
+}
+
+func processRegionListFunctions(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, *region.RegionName)
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_lambda_function",
+		"account":   accountId,
+		"region":    *region.RegionName,
+	}).Debug("processing region")
+
+	svc := lambda.NewFromConfig(*sess)
+
+	functions, err := listFunctions(osqCtx, svc, true)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_lambda_function",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"task":      "ListFunctions",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
+
+	rows := make([]functionRow, 0, len(functions))
+	for _, function := range functions {
+		rows = append(rows, newFunctionRow(function))
+	}
+	byteArr, err := json.Marshal(struct{ Functions []functionRow }{rows})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_lambda_function",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return resultMap, err
+	}
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_lambda_function", accountId, *region.RegionName, row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, *region.RegionName, tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
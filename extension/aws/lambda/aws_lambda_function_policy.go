@@ -0,0 +1,145 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// getPolicy returns the resource-based policy of a function. It returns nil if the function has no policy
+func getPolicy(osqCtx context.Context, svc *lambda.Client, functionName string) (*lambda.GetPolicyOutput, error) {
+	output, err := svc.GetPolicy(osqCtx, &lambda.GetPolicyInput{FunctionName: aws.String(functionName)})
+	if err != nil {
+		var notFound *lambdatypes.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return output, nil
+}
+
+// policyStatementRow is a row of aws_lambda_function_policy: one principal and one action (and resource) of a statement
+type policyStatementRow struct {
+	FunctionName string
+	FunctionArn  string
+	RevisionId   string
+	extaws.PolicyStatement
+}
+
+// GetFunctionPoliciesGenerate returns the rows in the table for all configured accounts
+func GetFunctionPoliciesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_lambda_function_policy", processRegionGetFunctionPolicies)
+}
+
+func processRegionGetFunctionPolicies(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, *region.RegionName)
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_lambda_function_policy",
+		"account":   accountId,
+		"region":    *region.RegionName,
+	}).Debug("processing region")
+
+	svc := lambda.NewFromConfig(*sess)
+
+	functions, err := listFunctions(osqCtx, svc, false)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_lambda_function_policy",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"task":      "ListFunctions",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
+
+	statements := make([]policyStatementRow, 0)
+	for _, function := range functions {
+		functionName := aws.ToString(function.FunctionName)
+		output, err := getPolicy(osqCtx, svc, functionName)
+		if err != nil {
+			utilities.GetLogger().WithFields(log.Fields{
+				"tableName": "aws_lambda_function_policy",
+				"account":   accountId,
+				"region":    *region.RegionName,
+				"function":  functionName,
+				"task":      "GetPolicy",
+				"errString": err.Error(),
+			}).Error("failed to get policy")
+			continue
+		}
+		if output == nil {
+			continue
+		}
+		policyStatements, err := extaws.ParsePolicyDocument(aws.ToString(output.Policy))
+		if err != nil {
+			utilities.GetLogger().WithFields(log.Fields{
+				"tableName": "aws_lambda_function_policy",
+				"account":   accountId,
+				"region":    *region.RegionName,
+				"function":  functionName,
+				"errString": err.Error(),
+			}).Error("failed to parse policy")
+			continue
+		}
+		for _, policyStatement := range policyStatements {
+			statements = append(statements, policyStatementRow{
+				FunctionName:    functionName,
+				FunctionArn:     aws.ToString(function.FunctionArn),
+				RevisionId:      aws.ToString(output.RevisionId),
+				PolicyStatement: policyStatement,
+			})
+		}
+	}
+
+	byteArr, err := json.Marshal(struct{ Statements []policyStatementRow }{statements})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_lambda_function_policy",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return resultMap, err
+	}
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_lambda_function_policy", accountId, *region.RegionName, row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, *region.RegionName, tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
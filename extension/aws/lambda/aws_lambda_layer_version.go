@@ -0,0 +1,158 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Uptycs/cloudquery/utilities"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// layerVersionRow is a row of aws_lambda_layer_version, a LayerVersionsListItem of the Lambda API with the name and ARN of its layer
+type layerVersionRow struct {
+	LayerName               string
+	LayerArn                string
+	LayerVersionArn         string
+	Version                 int64
+	Description             string
+	CreatedDate             string
+	LicenseInfo             string
+	CompatibleRuntimes      []lambdatypes.Runtime
+	CompatibleArchitectures []lambdatypes.Architecture
+}
+
+func newLayerVersionRow(layer lambdatypes.LayersListItem, version lambdatypes.LayerVersionsListItem) layerVersionRow {
+	return layerVersionRow{
+		LayerName:               aws.ToString(layer.LayerName),
+		LayerArn:                aws.ToString(layer.LayerArn),
+		LayerVersionArn:         aws.ToString(version.LayerVersionArn),
+		Version:                 version.Version,
+		Description:             aws.ToString(version.Description),
+		CreatedDate:             aws.ToString(version.CreatedDate),
+		LicenseInfo:             aws.ToString(version.LicenseInfo),
+		CompatibleRuntimes:      append([]lambdatypes.Runtime{}, version.CompatibleRuntimes...),
+		CompatibleArchitectures: append([]lambdatypes.Architecture{}, version.CompatibleArchitectures...),
+	}
+}
+
+// listLayers returns the layers of the region
+func listLayers(osqCtx context.Context, svc *lambda.Client) ([]lambdatypes.LayersListItem, error) {
+	layers := make([]lambdatypes.LayersListItem, 0)
+	paginator := lambda.NewListLayersPaginator(svc, &lambda.ListLayersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, page.Layers...)
+	}
+	return layers, nil
+}
+
+// listLayerVersions returns all versions of a layer
+func listLayerVersions(osqCtx context.Context, svc *lambda.Client, layer lambdatypes.LayersListItem) ([]layerVersionRow, error) {
+	versions := make([]layerVersionRow, 0)
+	paginator := lambda.NewListLayerVersionsPaginator(svc, &lambda.ListLayerVersionsInput{LayerName: layer.LayerName})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range page.LayerVersions {
+			versions = append(versions, newLayerVersionRow(layer, version))
+		}
+	}
+	return versions, nil
+}
+
+// ListLayerVersionsGenerate returns the rows in the table for all configured accounts
+func ListLayerVersionsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_lambda_layer_version", processRegionListLayerVersions)
+}
+
+func processRegionListLayerVersions(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
+	resultMap := make([]map[string]string, 0)
+	sess, err := extaws.GetAwsConfig(account, *region.RegionName)
+	if err != nil {
+		return resultMap, err
+	}
+
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": "aws_lambda_layer_version",
+		"account":   accountId,
+		"region":    *region.RegionName,
+	}).Debug("processing region")
+
+	svc := lambda.NewFromConfig(*sess)
+
+	layers, err := listLayers(osqCtx, svc)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_lambda_layer_version",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"task":      "ListLayers",
+			"errString": err.Error(),
+		}).Error("failed to process region")
+		return resultMap, err
+	}
+
+	layerVersions := make([]layerVersionRow, 0)
+	for _, layer := range layers {
+		versions, err := listLayerVersions(osqCtx, svc, layer)
+		if err != nil {
+			utilities.GetLogger().WithFields(log.Fields{
+				"tableName": "aws_lambda_layer_version",
+				"account":   accountId,
+				"region":    *region.RegionName,
+				"layer":     aws.ToString(layer.LayerName),
+				"task":      "ListLayerVersions",
+				"errString": err.Error(),
+			}).Error("failed to list layer versions")
+			continue
+		}
+		layerVersions = append(layerVersions, versions...)
+	}
+
+	byteArr, err := json.Marshal(struct{ LayerVersions []layerVersionRow }{layerVersions})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_lambda_layer_version",
+			"account":   accountId,
+			"region":    *region.RegionName,
+			"errString": err.Error(),
+		}).Error("failed to marshal response")
+		return resultMap, err
+	}
+	table := utilities.NewTable(byteArr, tableConfig)
+	for _, row := range table.Rows {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_lambda_layer_version", accountId, *region.RegionName, row) {
+			continue
+		}
+		result := extaws.RowToMap(row, accountId, *region.RegionName, tableConfig)
+		resultMap = append(resultMap, result)
+	}
+	return resultMap, nil
+}
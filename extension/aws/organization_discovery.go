@@ -0,0 +1,298 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMemberRoleName    = "OrganizationAccountAccessRole"
+	defaultDiscoveryInterval = 3600 // seconds
+	discoveryTimeout         = 10 * time.Minute
+)
+
+// organizationMembers holds the member accounts discovered for a management account
+type organizationMembers struct {
+	accounts  []utilities.ExtensionConfigurationAwsAccount
+	refreshed time.Time
+}
+
+var (
+	organizationMutex sync.Mutex
+	// organizationCache is management account id => discovered members
+	organizationCache = make(map[string]*organizationMembers)
+	// organizationGeneration is incremented whenever organizationCache is invalidated
+	organizationGeneration uint64
+	// organizationDiscoveryReset wakes up the discovery started with StartOrganizationDiscovery after organizationCache is invalidated
+	organizationDiscoveryReset = make(chan struct{}, 1)
+	timeNow                    = time.Now
+)
+
+// GetAccounts returns the configured AWS accounts followed by the members discovered for the
+// management accounts with discoverOrganization set. Members are listed again when the discovery
+// interval has passed. If listing fails, previously discovered members are returned.
+// Accounts which are configured explicitly are not duplicated
+func GetAccounts(ctx context.Context) []utilities.ExtensionConfigurationAwsAccount {
+	configured := utilities.GetConfiguration(ctx).Extension.ExtConfAws.Accounts
+	accounts := append([]utilities.ExtensionConfigurationAwsAccount{}, configured...)
+	accountIds := make(map[string]bool)
+	for _, account := range configured {
+		accountIds[account.ID] = true
+	}
+	for idx := range configured {
+		if !configured[idx].DiscoverOrganization {
+			continue
+		}
+		for _, member := range getOrganizationMembers(ctx, &configured[idx]) {
+			if accountIds[member.ID] {
+				continue
+			}
+			accountIds[member.ID] = true
+			accounts = append(accounts, member)
+		}
+	}
+	return accounts
+}
+
+// InvalidateDiscoveredAccounts forgets the discovered member accounts, e.g. after extension configuration has changed.
+// Members are listed again when they are needed, or right away by the discovery started with StartOrganizationDiscovery
+func InvalidateDiscoveredAccounts() {
+	organizationMutex.Lock()
+	defer organizationMutex.Unlock()
+	organizationCache = make(map[string]*organizationMembers)
+	organizationGeneration++
+	select {
+	case organizationDiscoveryReset <- struct{}{}:
+	default:
+	}
+}
+
+func getDiscoveryInterval(management *utilities.ExtensionConfigurationAwsAccount) time.Duration {
+	if management.DiscoveryInterval <= 0 {
+		return defaultDiscoveryInterval * time.Second
+	}
+	return time.Duration(management.DiscoveryInterval) * time.Second
+}
+
+// getRefreshInterval returns the shortest discovery interval of the management accounts in the current
+// configuration, the default interval if there are none
+func getRefreshInterval() time.Duration {
+	var interval time.Duration
+	for _, account := range utilities.CurrentConfiguration().Extension.ExtConfAws.Accounts {
+		if !account.DiscoverOrganization {
+			continue
+		}
+		accountInterval := getDiscoveryInterval(&account)
+		if interval == 0 || accountInterval < interval {
+			interval = accountInterval
+		}
+	}
+	if interval == 0 {
+		return defaultDiscoveryInterval * time.Second
+	}
+	return interval
+}
+
+// StartOrganizationDiscovery lists the members of the management accounts in the configuration,
+// then keeps refreshing them in the background until ctx is cancelled.
+// The configuration is read again on every refresh, and a reload triggers a refresh right away
+func StartOrganizationDiscovery(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			GetAccounts(ctx)
+			timer := time.NewTimer(getRefreshInterval())
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-organizationDiscoveryReset:
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// getDiscoveredAccount returns the member account with given id discovered so far (without listing members)
+func getDiscoveredAccount(accountId string) *utilities.ExtensionConfigurationAwsAccount {
+	organizationMutex.Lock()
+	defer organizationMutex.Unlock()
+	for _, members := range organizationCache {
+		for idx := range members.accounts {
+			if members.accounts[idx].ID == accountId {
+				return &members.accounts[idx]
+			}
+		}
+	}
+	return nil
+}
+
+// getOrganizationMembers returns the cached members of management, listing them again if the discovery interval has passed.
+// The members are listed without holding organizationMutex, so queries of other accounts are not blocked meanwhile.
+// They are listed with a context of their own, as the result is shared by all queries
+func getOrganizationMembers(ctx context.Context, management *utilities.ExtensionConfigurationAwsAccount) []utilities.ExtensionConfigurationAwsAccount {
+	organizationMutex.Lock()
+	members, found := organizationCache[management.ID]
+	generation := organizationGeneration
+	organizationMutex.Unlock()
+	if found && timeNow().Sub(members.refreshed) < getDiscoveryInterval(management) {
+		return members.accounts
+	}
+
+	discoveryCtx, cancel := context.WithTimeout(utilities.ContextWithConfiguration(context.Background(), utilities.GetConfiguration(ctx)), discoveryTimeout)
+	defer cancel()
+	accounts, err := discoverOrganizationMembers(discoveryCtx, management)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"account":   management.ID,
+			"task":      "ListAccounts",
+			"errString": err.Error(),
+		}).Error("failed to discover organization accounts")
+		var previous []utilities.ExtensionConfigurationAwsAccount
+		if found {
+			previous = members.accounts
+		}
+		// Retry after the interval rather than on every query
+		storeOrganizationMembers(management.ID, generation, &organizationMembers{accounts: previous, refreshed: timeNow()})
+		return previous
+	}
+	utilities.GetLogger().WithFields(log.Fields{
+		"account": management.ID,
+		"members": len(accounts),
+	}).Info("discovered organization accounts")
+	storeOrganizationMembers(management.ID, generation, &organizationMembers{accounts: accounts, refreshed: timeNow()})
+	return accounts
+}
+
+// storeOrganizationMembers caches members of given management account unless the cache was invalidated since
+// generation was read, in which case they may have been listed with an outdated configuration
+func storeOrganizationMembers(managementId string, generation uint64, members *organizationMembers) {
+	organizationMutex.Lock()
+	defer organizationMutex.Unlock()
+	if generation == organizationGeneration {
+		organizationCache[managementId] = members
+	}
+}
+
+func discoverOrganizationMembers(ctx context.Context, management *utilities.ExtensionConfigurationAwsAccount) ([]utilities.ExtensionConfigurationAwsAccount, error) {
+	sess, err := GetAwsConfig(management, "us-east-1")
+	if err != nil {
+		return nil, err
+	}
+	svc := organizations.NewFromConfig(*sess)
+	filterByParents := len(management.IncludeOrganizationalUnits) > 0 || len(management.ExcludeOrganizationalUnits) > 0
+	// parents is child id => parent id, shared by the members to list each OU once
+	parents := make(map[string]string)
+
+	accounts := make([]utilities.ExtensionConfigurationAwsAccount, 0)
+	paginator := organizations.NewListAccountsPaginator(svc, &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range page.Accounts {
+			if member.Status != types.AccountStatusActive || aws.ToString(member.Id) == management.ID {
+				continue
+			}
+			if filterByParents {
+				ancestors, err := getAncestors(ctx, svc, parents, aws.ToString(member.Id))
+				if err != nil {
+					return nil, err
+				}
+				if !matchesOrganizationalUnits(management, ancestors) {
+					continue
+				}
+			}
+			accounts = append(accounts, getMemberAccount(management, member))
+		}
+	}
+	return accounts, nil
+}
+
+// getAncestors returns the ids of the parent OUs of given account up to (and including) the root
+func getAncestors(ctx context.Context, svc *organizations.Client, parents map[string]string, childId string) ([]string, error) {
+	ancestors := make([]string, 0)
+	for !strings.HasPrefix(childId, "r-") {
+		parentId, found := parents[childId]
+		if !found {
+			output, err := svc.ListParents(ctx, &organizations.ListParentsInput{ChildId: aws.String(childId)})
+			if err != nil {
+				return nil, err
+			}
+			if len(output.Parents) == 0 {
+				return nil, fmt.Errorf("no parent found for %s", childId)
+			}
+			parentId = aws.ToString(output.Parents[0].Id)
+			parents[childId] = parentId
+		}
+		ancestors = append(ancestors, parentId)
+		childId = parentId
+	}
+	return ancestors, nil
+}
+
+func matchesOrganizationalUnits(management *utilities.ExtensionConfigurationAwsAccount, ancestors []string) bool {
+	contains := func(ids []string) bool {
+		for _, id := range ids {
+			for _, ancestor := range ancestors {
+				if id == ancestor {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if contains(management.ExcludeOrganizationalUnits) {
+		return false
+	}
+	return len(management.IncludeOrganizationalUnits) == 0 || contains(management.IncludeOrganizationalUnits)
+}
+
+// getMemberAccount returns the configuration assuming the member role with the credentials of the management account
+func getMemberAccount(management *utilities.ExtensionConfigurationAwsAccount, member types.Account) utilities.ExtensionConfigurationAwsAccount {
+	roleName := management.MemberRoleName
+	if roleName == "" {
+		roleName = defaultMemberRoleName
+	}
+	roleName = strings.NewReplacer("{accountId}", aws.ToString(member.Id), "{accountName}", aws.ToString(member.Name)).Replace(roleName)
+	partition := "aws"
+	if memberArn, err := arn.Parse(aws.ToString(member.Arn)); err == nil {
+		partition = memberArn.Partition
+	}
+	return utilities.ExtensionConfigurationAwsAccount{
+		ID:                     aws.ToString(member.Id),
+		CredentialFile:         management.CredentialFile,
+		ProfileName:            management.ProfileName,
+		SourceRoleArn:          management.RoleArn,
+		SourceExternalID:       management.ExternalID,
+		RoleArn:                fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, aws.ToString(member.Id), roleName),
+		ExternalID:             management.MemberExternalID,
+		EndpointURL:            management.EndpointURL,
+		Regions:                management.Regions,
+		DisableRegionDiscovery: management.DisableRegionDiscovery,
+		IncludeRegions:         management.IncludeRegions,
+		ExcludeRegions:         management.ExcludeRegions,
+	}
+}
@@ -0,0 +1,183 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package aws
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// PolicyStatement is one principal, action and resource of a statement of a policy document.
+// NotPrincipal, NotAction and NotResource are true if the value comes from the negated element of the statement
+type PolicyStatement struct {
+	PolicyId      string
+	Sid           string
+	Effect        string
+	PrincipalType string
+	Principal     string
+	NotPrincipal  bool
+	Action        string
+	NotAction     bool
+	Resource      string
+	NotResource   bool
+	Condition     json.RawMessage `json:",omitempty"`
+}
+
+type policyStatement struct {
+	Sid          string
+	Effect       string
+	Principal    json.RawMessage
+	NotPrincipal json.RawMessage
+	Action       json.RawMessage
+	NotAction    json.RawMessage
+	Resource     json.RawMessage
+	NotResource  json.RawMessage
+	Condition    json.RawMessage
+}
+
+// policyStatementList accepts both a single statement and a list of statements
+type policyStatementList []policyStatement
+
+func (list *policyStatementList) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var statement policyStatement
+		if err := json.Unmarshal(data, &statement); err != nil {
+			return err
+		}
+		*list = policyStatementList{statement}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]policyStatement)(list))
+}
+
+type policyDocument struct {
+	Id        string
+	Statement policyStatementList
+}
+
+type policyPrincipal struct {
+	Type  string
+	Value string
+}
+
+// decodeStringList decodes an element which is either a string or a list of strings
+func decodeStringList(data json.RawMessage) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		return []string{value}
+	}
+	var values []string
+	json.Unmarshal(data, &values)
+	return values
+}
+
+// decodePrincipals decodes the Principal element of a statement. "*" is the same as {"AWS": "*"}
+func decodePrincipals(data json.RawMessage) []policyPrincipal {
+	principals := make([]policyPrincipal, 0)
+	if len(data) == 0 {
+		return principals
+	}
+	var wildcard string
+	if err := json.Unmarshal(data, &wildcard); err == nil {
+		return append(principals, policyPrincipal{Type: "AWS", Value: wildcard})
+	}
+	var principalMap map[string]json.RawMessage
+	json.Unmarshal(data, &principalMap)
+	principalTypes := make([]string, 0, len(principalMap))
+	for principalType := range principalMap {
+		principalTypes = append(principalTypes, principalType)
+	}
+	sort.Strings(principalTypes)
+	for _, principalType := range principalTypes {
+		for _, value := range decodeStringList(principalMap[principalType]) {
+			principals = append(principals, policyPrincipal{Type: principalType, Value: value})
+		}
+	}
+	return principals
+}
+
+// decodeElement returns the values of element, or of its negated form notElement if element is not set.
+// The list has at least one (possibly empty) value, so that statements without the element still have rows
+func decodeElement(element, notElement json.RawMessage) ([]string, bool) {
+	if values := decodeStringList(element); len(values) > 0 {
+		return values, false
+	}
+	if values := decodeStringList(notElement); len(values) > 0 {
+		return values, true
+	}
+	return []string{""}, false
+}
+
+// DecodePolicyDocument returns the JSON of a policy document. IAM returns documents URL encoded (RFC 3986),
+// other services return them as is
+func DecodePolicyDocument(document string) string {
+	document = strings.TrimSpace(document)
+	if strings.HasPrefix(document, "{") {
+		return document
+	}
+	// PathUnescape keeps "+" as is, unlike QueryUnescape
+	decoded, err := url.PathUnescape(document)
+	if err != nil {
+		return document
+	}
+	return strings.TrimSpace(decoded)
+}
+
+// ParsePolicyDocument returns one PolicyStatement per statement, principal, action and resource of the policy document.
+// The document may be URL encoded, see DecodePolicyDocument
+func ParsePolicyDocument(document string) ([]PolicyStatement, error) {
+	var policy policyDocument
+	if err := json.Unmarshal([]byte(DecodePolicyDocument(document)), &policy); err != nil {
+		return nil, err
+	}
+	rows := make([]PolicyStatement, 0)
+	for _, statement := range policy.Statement {
+		var condition json.RawMessage
+		if len(statement.Condition) > 0 && string(statement.Condition) != "null" {
+			condition = statement.Condition
+		}
+		principals := decodePrincipals(statement.Principal)
+		notPrincipal := false
+		if len(principals) == 0 {
+			principals = decodePrincipals(statement.NotPrincipal)
+			notPrincipal = len(principals) > 0
+		}
+		if len(principals) == 0 {
+			principals = append(principals, policyPrincipal{})
+		}
+		actions, notAction := decodeElement(statement.Action, statement.NotAction)
+		resources, notResource := decodeElement(statement.Resource, statement.NotResource)
+		for _, principal := range principals {
+			for _, action := range actions {
+				for _, resource := range resources {
+					rows = append(rows, PolicyStatement{
+						PolicyId:      policy.Id,
+						Sid:           statement.Sid,
+						Effect:        statement.Effect,
+						PrincipalType: principal.Type,
+						Principal:     principal.Value,
+						NotPrincipal:  notPrincipal,
+						Action:        action,
+						NotAction:     notAction,
+						Resource:      resource,
+						NotResource:   notResource,
+						Condition:     condition,
+					})
+				}
+			}
+		}
+	}
+	return rows, nil
+}
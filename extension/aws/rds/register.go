@@ -0,0 +1,27 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package rds
+
+import (
+	_ "embed"
+
+	"github.com/Uptycs/cloudquery/utilities"
+)
+
+//go:embed table_config.json
+var defaultTableConfig []byte
+
+func init() {
+	utilities.RegisterTableConfigFile("aws/rds/table_config.json", defaultTableConfig)
+
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_rds_snapshot", Generate: DescribeSnapshotsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_rds_instance", Generate: DescribeDBInstances})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "aws_rds_cluster", Generate: DescribeClustersGenerate})
+}
@@ -10,9 +10,13 @@
 package s3
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
 
 	"github.com/Uptycs/cloudquery/utilities"
 
@@ -20,138 +24,114 @@ import (
 
 	"github.com/Uptycs/basequery-go/plugin/table"
 	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+const (
+	// maxBucketWorkers bounds the number of buckets of an account processed in parallel
+	maxBucketWorkers = 8
+	// globalRegion is the region of ListBuckets and GetBucketLocation requests
+	globalRegion = "us-east-1"
 )
 
 type s3BucketInfo struct {
 	Name                              string
 	CreationTime                      string
-	ServerSideEncryptionConfiguration *types.ServerSideEncryptionConfiguration
+	ServerSideEncryptionConfiguration *types.ServerSideEncryptionConfiguration `json:",omitempty"`
 	MfaDelete                         string
 	VersioningStatus                  string
-	AclOwner                          *types.Owner
-	AclGrants                         []types.Grant
+	AclOwner                          *types.Owner  `json:",omitempty"`
+	AclGrants                         []types.Grant `json:",omitempty"`
 	WebsiteEnabled                    bool
-	WebsiteRedirection                *types.RedirectAllRequestsTo
-	PublicAccessBlockConfig           *types.PublicAccessBlockConfiguration
-	PolicyStatus                      *types.PolicyStatus
+	WebsiteRedirection                *types.RedirectAllRequestsTo          `json:",omitempty"`
+	PublicAccessBlockConfig           *types.PublicAccessBlockConfiguration `json:",omitempty"`
+	PolicyStatus                      *types.PolicyStatus                   `json:",omitempty"`
 	AccelerateConfigurationStatus     string
 	ObjectLockConfigurationEnabled    bool
 	LifecycleConfigurationEnabled     bool
 	NotificationEnabled               bool
 	CorsEnabled                       bool
-	Policy                            *string
-	Tags                              []types.Tag
-}
-
-type s3BucketInfoList struct {
-	buckets []s3BucketInfo
+	Policy                            json.RawMessage `json:",omitempty"`
+	Tags                              []types.Tag     `json:",omitempty"`
+	ObjectOwnership                   string
+	LoggingEnabled                    bool
+	LoggingTargetBucket               string
+	LoggingTargetPrefix               string
+	ReplicationRole                   string
+	ReplicationRules                  []types.ReplicationRule
+	IntelligentTieringConfigurations  []types.IntelligentTieringConfiguration
 }
 
-var (
-	// Map of region to buckets
-	regionBuckets map[string]s3BucketInfoList
-)
-
-// ListBucketsColumns returns the list of columns in the table
-func ListBucketsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("region"),
-		table.TextColumn("name"),
-		table.TextColumn("creation_time"),
-		table.TextColumn("server_side_encryption_configuration"),
-		table.TextColumn("mfa_delete"),
-		table.TextColumn("versioning_status"),
-		table.TextColumn("acl_owner"),
-		table.TextColumn("acl_grants"),
-		table.TextColumn("website_enabled"),
-		table.TextColumn("website_redirection"),
-		table.TextColumn("public_access_block_config"),
-		table.TextColumn("policy_status"),
-		table.TextColumn("accelerate_configuration_status"),
-		table.TextColumn("object_lock_configuration_enabled"),
-		table.TextColumn("lifecycle_configuration_enabled"),
-		table.TextColumn("notification_enabled"),
-		table.TextColumn("cors_enabled"),
-		table.TextColumn("policy"),
-		table.TextColumn("tags"),
-	}
+// bucketQuery is the state of a query of aws_s3_bucket for one account.
+// Buckets are processed concurrently, each with the client of its region
+type bucketQuery struct {
+	osqCtx       context.Context
+	queryContext table.QueryContext
+	tableConfig  *utilities.TableConfig
+	account      *utilities.ExtensionConfigurationAwsAccount
+	accountId    string
+	collector    *utilities.RowCollector
+	mutex        sync.Mutex
+	clients      map[string]*s3.Client
 }
 
 // ListBucketsGenerate returns the rows in the table for all configured accounts
 func ListBucketsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_s3_bucket", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_s3_bucket",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountListBuckets(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_s3_bucket", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_s3_bucket",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountListBuckets(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
-		}
-	}
-
-	return resultMap, nil
+	return extaws.ProcessAccountsGlobal(osqCtx, queryContext, "aws_s3_bucket", processListBuckets)
 }
 
-func getBucketLocation(osqCtx context.Context, queryContext table.QueryContext, svc *s3.Client, bucketName *string) (string, error) {
-	bucketLocationInput := s3.GetBucketLocationInput{Bucket: bucketName}
-	getBucketLocationOutput, err := svc.GetBucketLocation(osqCtx, &bucketLocationInput)
-	if err != nil {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_s3_bucket",
-			"bucket":    *bucketName,
-			"errString": err.Error(),
-		}).Error("failed to get bucket location")
-		return "", err
-	}
-	if len(getBucketLocationOutput.LocationConstraint) == 0 {
-		// Default is us-east-1
-		return "us-east-1", nil
-	} else if getBucketLocationOutput.LocationConstraint == types.BucketLocationConstraintEu {
-		return "us-west-1", nil
-	} else {
-		return string(getBucketLocationOutput.LocationConstraint), nil
+// getBucketRegion returns the region of a location constraint. Buckets of us-east-1 have no location constraint
+// and EU is the legacy name of eu-west-1. Other constraints, including opt-in regions, are region codes
+func getBucketRegion(locationConstraint types.BucketLocationConstraint) string {
+	switch locationConstraint {
+	case "":
+		return "us-east-1"
+	case types.BucketLocationConstraintEu:
+		return "eu-west-1"
+	default:
+		return string(locationConstraint)
 	}
 }
 
-func addBucketToRegionBucketList(osqCtx context.Context, queryContext table.QueryContext, svc *s3.Client, bucket types.Bucket) error {
-	bucketRegion, err := getBucketLocation(osqCtx, queryContext, svc, bucket.Name)
-	if err != nil {
-		return err
+// getClient returns the client of region, creating it on first use
+func (query *bucketQuery) getClient(region string) (*s3.Client, error) {
+	query.mutex.Lock()
+	defer query.mutex.Unlock()
+	if svc, ok := query.clients[region]; ok {
+		return svc, nil
 	}
-	bucketInfo := s3BucketInfo{
-		Name:         *bucket.Name,
-		CreationTime: bucket.CreationDate.String(),
+	sess, err := extaws.GetAwsConfig(query.account, region)
+	if err != nil {
+		return nil, err
 	}
-	bucketList, ok := regionBuckets[bucketRegion]
-	if !ok {
-		bucketList = s3BucketInfoList{buckets: make([]s3BucketInfo, 0)}
-		regionBuckets[bucketRegion] = bucketList
+	svc := s3.NewFromConfig(*sess)
+	query.clients[region] = svc
+	return svc, nil
+}
+
+// getBucketLocation returns the region of a bucket. If GetBucketLocation is not allowed, the region is read
+// from the x-amz-bucket-region header that S3 returns for HeadBucket, even when it fails
+func (query *bucketQuery) getBucketLocation(svc *s3.Client, bucketName string) (string, error) {
+	output, err := svc.GetBucketLocation(query.osqCtx, &s3.GetBucketLocationInput{Bucket: aws.String(bucketName)})
+	if err == nil {
+		return getBucketRegion(output.LocationConstraint), nil
+	}
+	_, headErr := svc.HeadBucket(query.osqCtx, &s3.HeadBucketInput{Bucket: aws.String(bucketName)})
+	if headErr == nil {
+		return globalRegion, nil
+	}
+	var responseError *awshttp.ResponseError
+	if errors.As(headErr, &responseError) && responseError.Response != nil {
+		if region := responseError.Response.Header.Get("X-Amz-Bucket-Region"); region != "" {
+			return region, nil
+		}
 	}
-	bucketList.buckets = append(bucketList.buckets, bucketInfo)
-	regionBuckets[bucketRegion] = bucketList
-	return nil
+	return "", err
 }
 
 func (bucket *s3BucketInfo) getBucketEncryption(osqCtx context.Context, queryContext table.QueryContext, svc *s3.Client) {
@@ -280,127 +260,211 @@ func (bucket *s3BucketInfo) getBucketCorsConfiguration(osqCtx context.Context, q
 	}
 }
 
+// withRawResponseBody copies the body of successful responses into body before the operation deserializer reads it
+func withRawResponseBody(body *[]byte) func(*s3.Options) {
+	return func(options *s3.Options) {
+		options.APIOptions = append(options.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("rawResponseBody", func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+				out, metadata, err := next.HandleDeserialize(ctx, in)
+				response, ok := out.RawResponse.(*smithyhttp.Response)
+				if err != nil || !ok || response.StatusCode < 200 || response.StatusCode >= 300 {
+					return out, metadata, err
+				}
+				*body, err = ioutil.ReadAll(response.Body)
+				response.Body.Close()
+				response.Body = ioutil.NopCloser(bytes.NewReader(*body))
+				return out, metadata, err
+			}), middleware.After)
+		})
+	}
+}
+
 func (bucket *s3BucketInfo) getBucketPolicy(osqCtx context.Context, queryContext table.QueryContext, svc *s3.Client) {
 	input := s3.GetBucketPolicyInput{Bucket: &bucket.Name}
-	output, err := svc.GetBucketPolicy(osqCtx, &input)
+	// The response is the policy document itself, which this version of the SDK expects in a Policy XML element
+	var body []byte
+	output, err := svc.GetBucketPolicy(osqCtx, &input, withRawResponseBody(&body))
 	if err != nil {
 		return
 	}
-	bucket.Policy = output.Policy
+	policy := aws.ToString(output.Policy)
+	if policy == "" {
+		policy = string(body)
+	}
+	if json.Valid([]byte(policy)) {
+		bucket.Policy = json.RawMessage(policy)
+	}
 }
 
-func processBucket(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region string, bucket *s3BucketInfo) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	sess, err := extaws.GetAwsConfig(account, region)
+func (bucket *s3BucketInfo) getBucketOwnershipControls(osqCtx context.Context, queryContext table.QueryContext, svc *s3.Client) {
+	input := s3.GetBucketOwnershipControlsInput{Bucket: &bucket.Name}
+	output, err := svc.GetBucketOwnershipControls(osqCtx, &input)
 	if err != nil {
-		return resultMap, err
+		return
 	}
-	accountId := utilities.AwsAccountID
-	if account != nil {
-		accountId = account.ID
+	if output.OwnershipControls != nil && len(output.OwnershipControls.Rules) > 0 {
+		bucket.ObjectOwnership = string(output.OwnershipControls.Rules[0].ObjectOwnership)
 	}
-	svc := s3.NewFromConfig(*sess)
+}
+
+func (bucket *s3BucketInfo) getBucketLogging(osqCtx context.Context, queryContext table.QueryContext, svc *s3.Client) {
+	bucket.LoggingEnabled = false
+	input := s3.GetBucketLoggingInput{Bucket: &bucket.Name}
+	output, err := svc.GetBucketLogging(osqCtx, &input)
+	if err != nil {
+		return
+	}
+	if output.LoggingEnabled != nil {
+		bucket.LoggingEnabled = true
+		bucket.LoggingTargetBucket = aws.ToString(output.LoggingEnabled.TargetBucket)
+		bucket.LoggingTargetPrefix = aws.ToString(output.LoggingEnabled.TargetPrefix)
+	}
+}
+
+func (bucket *s3BucketInfo) getBucketReplication(osqCtx context.Context, queryContext table.QueryContext, svc *s3.Client) {
+	input := s3.GetBucketReplicationInput{Bucket: &bucket.Name}
+	output, err := svc.GetBucketReplication(osqCtx, &input)
+	if err != nil {
+		return
+	}
+	if output.ReplicationConfiguration != nil {
+		bucket.ReplicationRole = aws.ToString(output.ReplicationConfiguration.Role)
+		bucket.ReplicationRules = append(bucket.ReplicationRules, output.ReplicationConfiguration.Rules...)
+	}
+}
+
+func (bucket *s3BucketInfo) getBucketIntelligentTieringConfigurations(osqCtx context.Context, queryContext table.QueryContext, svc *s3.Client) {
+	input := s3.ListBucketIntelligentTieringConfigurationsInput{Bucket: &bucket.Name}
+	for {
+		output, err := svc.ListBucketIntelligentTieringConfigurations(osqCtx, &input)
+		if err != nil {
+			return
+		}
+		bucket.IntelligentTieringConfigurations = append(bucket.IntelligentTieringConfigurations, output.IntelligentTieringConfigurationList...)
+		if !output.IsTruncated || output.NextContinuationToken == nil {
+			return
+		}
+		input.ContinuationToken = output.NextContinuationToken
+	}
+}
+
+// processBucket gets the attributes of a bucket in its region and adds its rows to the collector
+func (query *bucketQuery) processBucket(bucket types.Bucket, globalSvc *s3.Client) {
+	bucketName := aws.ToString(bucket.Name)
+	region, err := query.getBucketLocation(globalSvc, bucketName)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_s3_bucket",
+			"account":   query.accountId,
+			"bucket":    bucketName,
+			"task":      "GetBucketLocation",
+			"errString": err.Error(),
+		}).Error("failed to get bucket location")
+		return
+	}
+	if !extaws.IsRegionConfigured(query.account, region) || !extaws.ShouldProcessRegion(query.osqCtx, query.queryContext, "aws_s3_bucket", query.accountId, region) {
+		return
+	}
+	svc, err := query.getClient(region)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": "aws_s3_bucket",
+			"account":   query.accountId,
+			"region":    region,
+			"bucket":    bucketName,
+			"errString": err.Error(),
+		}).Error("failed to create client")
+		return
+	}
+
 	utilities.GetLogger().WithFields(log.Fields{
 		"tableName": "aws_s3_bucket",
-		"bucket":    bucket.Name,
+		"bucket":    bucketName,
 	}).Debug("processing bucket")
-	bucket.getBucketAccelerateConfiguration(osqCtx, queryContext, svc)
-	bucket.getBucketAcl(osqCtx, queryContext, svc)
-	bucket.getBucketCorsConfiguration(osqCtx, queryContext, svc)
-	bucket.getBucketEncryption(osqCtx, queryContext, svc)
-	bucket.getBucketLifecycleConfiguration(osqCtx, queryContext, svc)
-	bucket.getBucketNotificationConfiguration(osqCtx, queryContext, svc)
-	bucket.getBucketPolicy(osqCtx, queryContext, svc)
-	bucket.getBucketPolicyStatus(osqCtx, queryContext, svc)
-	bucket.getBucketPublicAccessBlock(osqCtx, queryContext, svc)
-	bucket.getBucketTags(osqCtx, queryContext, svc)
-	bucket.getBucketVersioning(osqCtx, queryContext, svc)
-	bucket.getBucketWebsite(osqCtx, queryContext, svc)
-	bucket.getObjectLockConfiguration(osqCtx, queryContext, svc)
-	byteArr, err := json.Marshal(bucket)
+
+	osqCtx, queryContext := query.osqCtx, query.queryContext
+	info := &s3BucketInfo{
+		Name:                             bucketName,
+		ReplicationRules:                 []types.ReplicationRule{},
+		IntelligentTieringConfigurations: []types.IntelligentTieringConfiguration{},
+	}
+	if bucket.CreationDate != nil {
+		info.CreationTime = bucket.CreationDate.UTC().Format(time.RFC3339)
+	}
+	info.getBucketAccelerateConfiguration(osqCtx, queryContext, svc)
+	info.getBucketAcl(osqCtx, queryContext, svc)
+	info.getBucketCorsConfiguration(osqCtx, queryContext, svc)
+	info.getBucketEncryption(osqCtx, queryContext, svc)
+	info.getBucketIntelligentTieringConfigurations(osqCtx, queryContext, svc)
+	info.getBucketLifecycleConfiguration(osqCtx, queryContext, svc)
+	info.getBucketLogging(osqCtx, queryContext, svc)
+	info.getBucketNotificationConfiguration(osqCtx, queryContext, svc)
+	info.getBucketOwnershipControls(osqCtx, queryContext, svc)
+	info.getBucketPolicy(osqCtx, queryContext, svc)
+	info.getBucketPolicyStatus(osqCtx, queryContext, svc)
+	info.getBucketPublicAccessBlock(osqCtx, queryContext, svc)
+	info.getBucketReplication(osqCtx, queryContext, svc)
+	info.getBucketTags(osqCtx, queryContext, svc)
+	info.getBucketVersioning(osqCtx, queryContext, svc)
+	info.getBucketWebsite(osqCtx, queryContext, svc)
+	info.getObjectLockConfiguration(osqCtx, queryContext, svc)
+
+	byteArr, err := json.Marshal(struct{ Buckets []*s3BucketInfo }{[]*s3BucketInfo{info}})
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "aws_s3_bucket",
-			"account":   accountId,
+			"account":   query.accountId,
 			"region":    region,
-			"bucket":    bucket.Name,
+			"bucket":    bucketName,
 			"errString": err.Error(),
 		}).Error("failed to marshal response")
-		return resultMap, err
+		return
 	}
-	table := utilities.NewTable(byteArr, tableConfig)
+	table := utilities.NewTable(byteArr, query.tableConfig)
 	for _, row := range table.Rows {
-		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_s3_bucket", accountId, region, row) {
+		if !extaws.ShouldProcessRow(osqCtx, queryContext, "aws_s3_bucket", query.accountId, region, row) {
 			continue
 		}
-		result := extaws.RowToMap(row, accountId, region, tableConfig)
-		resultMap = append(resultMap, result)
+		query.collector.Add(extaws.RowToMap(row, query.accountId, region, query.tableConfig))
 	}
-	return resultMap, nil
 }
 
 func processListBuckets(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	sess, err := extaws.GetAwsConfig(account, "us-west-1")
-	if err != nil {
-		return resultMap, err
-	}
-
-	svc := s3.NewFromConfig(*sess)
-	params := &s3.ListBucketsInput{}
-
 	accountId := utilities.AwsAccountID
 	if account != nil {
 		accountId = account.ID
 	}
+	query := &bucketQuery{
+		osqCtx:       osqCtx,
+		queryContext: queryContext,
+		tableConfig:  tableConfig,
+		account:      account,
+		accountId:    accountId,
+		collector:    utilities.NewRowCollector(osqCtx, maxBucketWorkers),
+		clients:      make(map[string]*s3.Client),
+	}
+
+	svc, err := query.getClient(globalRegion)
+	if err != nil {
+		return make([]map[string]string, 0), err
+	}
 
 	// Get list of buckets
-	output, err := svc.ListBuckets(osqCtx, params)
+	output, err := svc.ListBuckets(osqCtx, &s3.ListBucketsInput{})
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "aws_s3_bucket",
 			"account":   accountId,
+			"task":      "ListBuckets",
 			"errString": err.Error(),
 		}).Error("failed to get bucket list")
-		return resultMap, err
+		return make([]map[string]string, 0), err
 	}
-	regionBuckets = make(map[string]s3BucketInfoList)
-	// Get bucket region and put that bucket in that bucketList
 	for _, bucket := range output.Buckets {
-		addBucketToRegionBucketList(osqCtx, queryContext, svc, bucket)
-	}
-	// Process all buckets
-	for region, regionBucketList := range regionBuckets {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_s3_bucket", accountId, region) {
-			continue
-		}
-		for _, regionBucket := range regionBucketList.buckets {
-			result, err := processBucket(osqCtx, queryContext, tableConfig, account, region, &regionBucket)
-			if err == nil {
-				resultMap = append(resultMap, result...)
-			}
-		}
-	}
-	return resultMap, nil
-}
-
-func processAccountListBuckets(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	tableConfig, ok := utilities.TableConfigurationMap["aws_s3_bucket"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_s3_bucket",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	result, err := processListBuckets(osqCtx, queryContext, tableConfig, account)
-	if err != nil {
-		return resultMap, err
+		bucket := bucket
+		query.collector.Go(func() {
+			query.processBucket(bucket, svc)
+		})
 	}
-	resultMap = append(resultMap, result...)
-	return resultMap, nil
+	return query.collector.Wait(), nil
 }
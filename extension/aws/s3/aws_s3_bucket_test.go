@@ -0,0 +1,71 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package s3
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/Uptycs/cloudquery/utilities/vcr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBucketRegion(t *testing.T) {
+	assert.Equal(t, "us-east-1", getBucketRegion(""))
+	assert.Equal(t, "eu-west-1", getBucketRegion("EU"))
+	assert.Equal(t, "ap-east-1", getBucketRegion("ap-east-1"))
+}
+
+func TestListBucketsGenerateConcurrent(t *testing.T) {
+	assert.Nil(t, utilities.ReadTableConfig(defaultTableConfig))
+	vcr.Start(t, "testdata/aws_s3_bucket.cassette.json")
+	savedConfiguration := utilities.CurrentConfiguration()
+	defer utilities.UpdateConfiguration(func(config *utilities.Configuration) { config.Extension = savedConfiguration.Extension })
+	utilities.UpdateConfiguration(func(config *utilities.Configuration) {
+		config.Extension.ExtConfAws.Accounts = []utilities.ExtensionConfigurationAwsAccount{{ID: "111111111111"}, {ID: "222222222222"}}
+	})
+
+	queries := []struct {
+		accountId string
+		region    string
+		bucket    string
+	}{
+		{"111111111111", "eu-west-1", "legacy-eu-assets"},
+		{"222222222222", "us-east-1", "app-logs"},
+	}
+	results := make([][]map[string]string, len(queries))
+	var wg sync.WaitGroup
+	for idx, query := range queries {
+		wg.Add(1)
+		go func(idx int, accountId string, region string) {
+			defer wg.Done()
+			queryContext := table.QueryContext{Constraints: map[string]table.ConstraintList{
+				"account_id":  {Affinity: table.ColumnTypeText, Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: accountId}}},
+				"region_code": {Affinity: table.ColumnTypeText, Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: region}}},
+			}}
+			rows, err := ListBucketsGenerate(context.Background(), queryContext)
+			assert.Nil(t, err)
+			results[idx] = rows
+		}(idx, query.accountId, query.region)
+	}
+	wg.Wait()
+
+	// Each query returns only the bucket of its own account and region
+	for idx, query := range queries {
+		if assert.Equal(t, 1, len(results[idx])) {
+			assert.Equal(t, query.bucket, results[idx][0]["name"])
+			assert.Equal(t, query.accountId, results[idx][0]["account_id"])
+			assert.Equal(t, query.region, results[idx][0]["region_code"])
+		}
+	}
+}
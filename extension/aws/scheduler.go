@@ -0,0 +1,213 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxWorkers           = 16
+	defaultMaxWorkersPerAccount = 4
+)
+
+var (
+	workerPoolMutex sync.Mutex
+	// workerPool bounds the number of regions processed in parallel across all queries.
+	// It is replaced when the configured size changes
+	workerPool chan struct{}
+)
+
+// RegionProcessor returns the rows of a table for given region of given account
+type RegionProcessor func(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig,
+	account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error)
+
+// GlobalProcessor returns the rows of a table backed by a global (non regional) service for given account
+type GlobalProcessor func(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig,
+	account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error)
+
+// getWorkerPool returns the worker pool sized as configured in the configuration of ctx. If the size has changed
+// (e.g. by a reload), a new pool replaces the current one, so workers busy in the old pool are not counted in the new one
+func getWorkerPool(ctx context.Context) chan struct{} {
+	maxWorkers := utilities.GetConfiguration(ctx).Extension.ExtConfAws.Concurrency.MaxWorkers
+	if maxWorkers <= 0 {
+		maxWorkers = defaultMaxWorkers
+	}
+	workerPoolMutex.Lock()
+	defer workerPoolMutex.Unlock()
+	if workerPool == nil || cap(workerPool) != maxWorkers {
+		workerPool = make(chan struct{}, maxWorkers)
+	}
+	return workerPool
+}
+
+func getMaxWorkersPerAccount(ctx context.Context) int {
+	maxWorkers := utilities.GetConfiguration(ctx).Extension.ExtConfAws.Concurrency.MaxWorkersPerAccount
+	if maxWorkers <= 0 {
+		return defaultMaxWorkersPerAccount
+	}
+	return maxWorkers
+}
+
+// acquireWorker blocks until a worker is available and returns its pool, to be given to releaseWorker.
+// It returns false if ctx is cancelled first
+func acquireWorker(ctx context.Context) (chan struct{}, bool) {
+	pool := getWorkerPool(ctx)
+	select {
+	case pool <- struct{}{}:
+		return pool, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+func releaseWorker(pool chan struct{}) {
+	<-pool
+}
+
+func getTableConfig(osqCtx context.Context, tableName string) (*utilities.TableConfig, error) {
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[tableName]
+	if !ok {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": tableName,
+		}).Error("failed to get table configuration")
+		return nil, fmt.Errorf("table configuration not found")
+	}
+	return tableConfig, nil
+}
+
+// forEachAccount calls processAccount for all configured (and discovered, see GetAccounts) accounts in parallel.
+// If no account is configured, it is called once with nil (default) account and its error is returned.
+// Errors for configured accounts are logged and skipped so that one bad account doesn't fail the query.
+func forEachAccount(osqCtx context.Context, queryContext table.QueryContext, tableName string, processAccount func(*utilities.ExtensionConfigurationAwsAccount) error) error {
+	accounts := GetAccounts(osqCtx)
+	if len(accounts) == 0 {
+		if !ShouldProcessAccount(osqCtx, queryContext, tableName, utilities.AwsAccountID) {
+			return nil
+		}
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": tableName,
+			"account":   "default",
+		}).Info("processing account")
+		return processAccount(nil)
+	}
+
+	var wg sync.WaitGroup
+	for _, account := range accounts {
+		if !ShouldProcessAccount(osqCtx, queryContext, tableName, account.ID) {
+			continue
+		}
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": tableName,
+			"account":   account.ID,
+		}).Info("processing account")
+		wg.Add(1)
+		go func(account utilities.ExtensionConfigurationAwsAccount) {
+			defer wg.Done()
+			if err := processAccount(&account); err != nil {
+				utilities.GetLogger().WithFields(log.Fields{
+					"tableName": tableName,
+					"account":   account.ID,
+					"task":      "processAccount",
+					"errString": err.Error(),
+				}).Error("failed to process account")
+			}
+		}(account)
+	}
+	wg.Wait()
+	return nil
+}
+
+// ProcessAccountRegions returns the rows of given table for all accounts and all of their regions.
+// Regions are processed by a bounded pool of workers (see ExtensionConfigurationAwsConcurrency).
+// processor is expected to log its own errors; a failed region doesn't fail the account.
+func ProcessAccountRegions(osqCtx context.Context, queryContext table.QueryContext, tableName string, processor RegionProcessor) ([]map[string]string, error) {
+	collector := utilities.NewRowCollector(osqCtx, 0)
+	tableConfig, err := getTableConfig(osqCtx, tableName)
+	if err != nil {
+		return collector.Rows(), err
+	}
+	err = forEachAccount(osqCtx, queryContext, tableName, func(account *utilities.ExtensionConfigurationAwsAccount) error {
+		return processRegions(osqCtx, queryContext, tableName, tableConfig, account, processor, collector)
+	})
+	return collector.Rows(), err
+}
+
+func processRegions(osqCtx context.Context, queryContext table.QueryContext, tableName string, tableConfig *utilities.TableConfig,
+	account *utilities.ExtensionConfigurationAwsAccount, processor RegionProcessor, collector *utilities.RowCollector) error {
+	awsSession, err := GetAwsConfig(account, "us-east-1")
+	if err != nil {
+		return err
+	}
+	regions, err := GetRegions(osqCtx, account, awsSession)
+	if err != nil {
+		return err
+	}
+	accountId := utilities.AwsAccountID
+	if account != nil {
+		accountId = account.ID
+	}
+
+	accountWorkers := make(chan struct{}, getMaxWorkersPerAccount(osqCtx))
+	var wg sync.WaitGroup
+	for _, region := range regions {
+		if !ShouldProcessRegion(osqCtx, queryContext, tableName, accountId, *region.RegionName) {
+			continue
+		}
+		accountWorkers <- struct{}{}
+		wg.Add(1)
+		go func(region types.Region) {
+			defer wg.Done()
+			defer func() { <-accountWorkers }()
+			pool, ok := acquireWorker(osqCtx)
+			if !ok {
+				return
+			}
+			defer releaseWorker(pool)
+			result, err := processor(osqCtx, queryContext, tableConfig, account, region)
+			if err != nil {
+				return
+			}
+			collector.AddRows(result)
+		}(region)
+	}
+	wg.Wait()
+	return nil
+}
+
+// ProcessAccountsGlobal returns the rows of given table for all accounts, for services which are not regional (e.g. IAM)
+func ProcessAccountsGlobal(osqCtx context.Context, queryContext table.QueryContext, tableName string, processor GlobalProcessor) ([]map[string]string, error) {
+	collector := utilities.NewRowCollector(osqCtx, 0)
+	tableConfig, err := getTableConfig(osqCtx, tableName)
+	if err != nil {
+		return collector.Rows(), err
+	}
+	err = forEachAccount(osqCtx, queryContext, tableName, func(account *utilities.ExtensionConfigurationAwsAccount) error {
+		pool, ok := acquireWorker(osqCtx)
+		if !ok {
+			return osqCtx.Err()
+		}
+		defer releaseWorker(pool)
+		result, err := processor(osqCtx, queryContext, tableConfig, account)
+		if err != nil {
+			return err
+		}
+		collector.AddRows(result)
+		return nil
+	})
+	return collector.Rows(), err
+}
@@ -0,0 +1,82 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	"github.com/Uptycs/cloudquery/utilities"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireWorker(t *testing.T) {
+	pool := getWorkerPool(context.Background())
+	assert.Equal(t, defaultMaxWorkers, cap(pool))
+
+	for i := 0; i < cap(pool); i++ {
+		acquired, ok := acquireWorker(context.Background())
+		assert.True(t, ok)
+		assert.Equal(t, pool, acquired)
+	}
+	// Pool is exhausted, cancelled context must not block
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ok := acquireWorker(ctx)
+	assert.False(t, ok)
+
+	// The pool is resized when the configuration changes, e.g. by a reload
+	config := &utilities.Configuration{}
+	config.Extension.ExtConfAws.Concurrency.MaxWorkers = 2
+	resized, ok := acquireWorker(utilities.ContextWithConfiguration(context.Background(), config))
+	assert.True(t, ok)
+	assert.Equal(t, 2, cap(resized))
+	releaseWorker(resized)
+
+	for i := 0; i < cap(pool); i++ {
+		releaseWorker(pool)
+	}
+	assert.Equal(t, 0, len(pool))
+	assert.Equal(t, defaultMaxWorkers, cap(getWorkerPool(context.Background())))
+}
+
+func TestForEachAccountLogsErrors(t *testing.T) {
+	savedConfiguration := utilities.CurrentConfiguration()
+	defer utilities.UpdateConfiguration(func(config *utilities.Configuration) { config.Extension = savedConfiguration.Extension })
+	utilities.UpdateConfiguration(func(config *utilities.Configuration) {
+		config.Extension.ExtConfAws.Accounts = []utilities.ExtensionConfigurationAwsAccount{{ID: "111111111111"}, {ID: "222222222222"}}
+	})
+	hook := test.NewLocal(utilities.GetLogger())
+	defer hook.Reset()
+
+	err := forEachAccount(context.Background(), table.QueryContext{}, "aws_test_table", func(account *utilities.ExtensionConfigurationAwsAccount) error {
+		if account.ID == "222222222222" {
+			return fmt.Errorf("access denied")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+
+	errors := make([]*log.Entry, 0)
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == log.ErrorLevel {
+			errors = append(errors, entry)
+		}
+	}
+	if assert.Equal(t, 1, len(errors)) {
+		assert.Equal(t, "222222222222", errors[0].Data["account"])
+		assert.Equal(t, "aws_test_table", errors[0].Data["tableName"])
+		assert.Equal(t, "access denied", errors[0].Data["errString"])
+	}
+}
@@ -12,7 +12,8 @@ package sqs
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -20,51 +21,100 @@ import (
 
 	"github.com/Uptycs/basequery-go/plugin/table"
 	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
-// ListQueuesColumns returns the list of columns in the table
-func ListQueuesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("account_id"),
-		table.TextColumn("region_code"),
-		table.TextColumn("region"),
-		table.TextColumn("queue_urls"),
+// sqsQueueRow is a row of aws_sqs_queue, made of the attributes returned by GetQueueAttributes.
+// Policy and RedrivePolicy are kept as JSON documents, the dead-letter queue is also split into columns.
+type sqsQueueRow struct {
+	QueueUrl                              string
+	QueueName                             string
+	QueueArn                              string
+	CreatedTimestamp                      *int64
+	LastModifiedTimestamp                 *int64
+	VisibilityTimeout                     *int64
+	MaximumMessageSize                    *int64
+	MessageRetentionPeriod                *int64
+	DelaySeconds                          *int64
+	ReceiveMessageWaitTimeSeconds         *int64
+	ApproximateNumberOfMessages           *int64
+	ApproximateNumberOfMessagesNotVisible *int64
+	ApproximateNumberOfMessagesDelayed    *int64
+	FifoQueue                             bool
+	ContentBasedDeduplication             bool
+	DeduplicationScope                    string
+	FifoThroughputLimit                   string
+	KmsMasterKeyId                        string
+	KmsDataKeyReusePeriodSeconds          *int64
+	SqsManagedSseEnabled                  bool
+	Policy                                json.RawMessage `json:",omitempty"`
+	RedrivePolicy                         json.RawMessage `json:",omitempty"`
+	DeadLetterTargetArn                   string
+	MaxReceiveCount                       *int64
+}
+
+// getIntAttribute returns the numeric attribute name, or nil if it is not set
+func getIntAttribute(attributes map[string]string, name string) *int64 {
+	value, err := strconv.ParseInt(strings.Trim(attributes[name], `"`), 10, 64)
+	if err != nil {
+		return nil
 	}
+	return &value
 }
 
-// ListQueuesGenerate returns the rows in the table for all configured accounts
-func ListQueuesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAws.Accounts) == 0 && extaws.ShouldProcessAccount("aws_sqs_queue", utilities.AwsAccountID) {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_sqs_queue",
-			"account":   "default",
-		}).Info("processing account")
-		results, err := processAccountListQueues(osqCtx, queryContext, nil)
-		if err != nil {
-			return resultMap, err
-		}
-		resultMap = append(resultMap, results...)
-	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAws.Accounts {
-			if !extaws.ShouldProcessAccount("aws_sqs_queue", account.ID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": "aws_sqs_queue",
-				"account":   account.ID,
-			}).Info("processing account")
-			results, err := processAccountListQueues(osqCtx, queryContext, &account)
-			if err != nil {
-				continue
-			}
-			resultMap = append(resultMap, results...)
+// getJSONAttribute returns the attribute name holding a JSON document, or nil if it is not set or not valid
+func getJSONAttribute(attributes map[string]string, name string) json.RawMessage {
+	value := attributes[name]
+	if value == "" || !json.Valid([]byte(value)) {
+		return nil
+	}
+	return json.RawMessage(value)
+}
+
+func newSqsQueueRow(queueUrl string, attributes map[string]string) sqsQueueRow {
+	row := sqsQueueRow{
+		QueueUrl:                              queueUrl,
+		QueueName:                             queueUrl[strings.LastIndex(queueUrl, "/")+1:],
+		QueueArn:                              attributes[string(sqstypes.QueueAttributeNameQueueArn)],
+		CreatedTimestamp:                      getIntAttribute(attributes, string(sqstypes.QueueAttributeNameCreatedTimestamp)),
+		LastModifiedTimestamp:                 getIntAttribute(attributes, string(sqstypes.QueueAttributeNameLastModifiedTimestamp)),
+		VisibilityTimeout:                     getIntAttribute(attributes, string(sqstypes.QueueAttributeNameVisibilityTimeout)),
+		MaximumMessageSize:                    getIntAttribute(attributes, string(sqstypes.QueueAttributeNameMaximumMessageSize)),
+		MessageRetentionPeriod:                getIntAttribute(attributes, string(sqstypes.QueueAttributeNameMessageRetentionPeriod)),
+		DelaySeconds:                          getIntAttribute(attributes, string(sqstypes.QueueAttributeNameDelaySeconds)),
+		ReceiveMessageWaitTimeSeconds:         getIntAttribute(attributes, string(sqstypes.QueueAttributeNameReceiveMessageWaitTimeSeconds)),
+		ApproximateNumberOfMessages:           getIntAttribute(attributes, string(sqstypes.QueueAttributeNameApproximateNumberOfMessages)),
+		ApproximateNumberOfMessagesNotVisible: getIntAttribute(attributes, string(sqstypes.QueueAttributeNameApproximateNumberOfMessagesNotVisible)),
+		ApproximateNumberOfMessagesDelayed:    getIntAttribute(attributes, string(sqstypes.QueueAttributeNameApproximateNumberOfMessagesDelayed)),
+		FifoQueue:                             attributes[string(sqstypes.QueueAttributeNameFifoQueue)] == "true",
+		ContentBasedDeduplication:             attributes[string(sqstypes.QueueAttributeNameContentBasedDeduplication)] == "true",
+		DeduplicationScope:                    attributes[string(sqstypes.QueueAttributeNameDeduplicationScope)],
+		FifoThroughputLimit:                   attributes[string(sqstypes.QueueAttributeNameFifoThroughputLimit)],
+		KmsMasterKeyId:                        attributes[string(sqstypes.QueueAttributeNameKmsMasterKeyId)],
+		KmsDataKeyReusePeriodSeconds:          getIntAttribute(attributes, string(sqstypes.QueueAttributeNameKmsDataKeyReusePeriodSeconds)),
+		SqsManagedSseEnabled:                  attributes["SqsManagedSseEnabled"] == "true",
+		Policy:                                getJSONAttribute(attributes, string(sqstypes.QueueAttributeNamePolicy)),
+		RedrivePolicy:                         getJSONAttribute(attributes, string(sqstypes.QueueAttributeNameRedrivePolicy)),
+	}
+	if row.RedrivePolicy != nil {
+		var redrivePolicy map[string]interface{}
+		json.Unmarshal(row.RedrivePolicy, &redrivePolicy)
+		redriveAttributes := make(map[string]string, len(redrivePolicy))
+		for key, value := range redrivePolicy {
+			redriveAttributes[key] = utilities.GetStringValue(value)
 		}
+		row.DeadLetterTargetArn = redriveAttributes["deadLetterTargetArn"]
+		row.MaxReceiveCount = getIntAttribute(redriveAttributes, "maxReceiveCount")
 	}
+	return row
+}
 
-	return resultMap, nil
+// ListQueuesGenerate returns the rows in the table for all configured accounts
+func ListQueuesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return extaws.ProcessAccountRegions(osqCtx, queryContext, "aws_sqs_queue", processRegionListQueues)
 }
 
 func processRegionListQueues(osqCtx context.Context, queryContext table.QueryContext, tableConfig *utilities.TableConfig, account *utilities.ExtensionConfigurationAwsAccount, region types.Region) ([]map[string]string, error) {
@@ -86,21 +136,45 @@ func processRegionListQueues(osqCtx context.Context, queryContext table.QueryCon
 	}).Debug("processing region")
 
 	svc := sqs.NewFromConfig(*sess)
-	params := &sqs.ListQueuesInput{}
+	// Without MaxResults, ListQueues returns at most 1000 queues and no NextToken
+	params := &sqs.ListQueuesInput{MaxResults: aws.Int32(1000)}
 
-	result, err := svc.ListQueues(osqCtx, params)
-	if err != nil {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_sqs_queue",
-			"account":   accountId,
-			"region":    *region.RegionName,
-			"task":      "ListQueues",
-			"errString": err.Error(),
-		}).Error("failed to process region")
-		return resultMap, err
+	paginator := sqs.NewListQueuesPaginator(svc, params)
+
+	queues := make([]sqsQueueRow, 0)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(osqCtx)
+		if err != nil {
+			utilities.GetLogger().WithFields(log.Fields{
+				"tableName": "aws_sqs_queue",
+				"account":   accountId,
+				"region":    *region.RegionName,
+				"task":      "ListQueues",
+				"errString": err.Error(),
+			}).Error("failed to process region")
+			return resultMap, err
+		}
+		for _, queueUrl := range page.QueueUrls {
+			output, err := svc.GetQueueAttributes(osqCtx, &sqs.GetQueueAttributesInput{
+				QueueUrl:       aws.String(queueUrl),
+				AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameAll},
+			})
+			if err != nil {
+				utilities.GetLogger().WithFields(log.Fields{
+					"tableName": "aws_sqs_queue",
+					"account":   accountId,
+					"region":    *region.RegionName,
+					"queue":     queueUrl,
+					"task":      "GetQueueAttributes",
+					"errString": err.Error(),
+				}).Error("failed to get queue attributes")
+				continue
+			}
+			queues = append(queues, newSqsQueueRow(queueUrl, output.Attributes))
+		}
 	}
 
-	byteArr, err := json.Marshal(result)
+	byteArr, err := json.Marshal(struct{ Queues []sqsQueueRow }{queues})
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "aws_sqs_queue",
@@ -120,37 +194,3 @@ func processRegionListQueues(osqCtx context.Context, queryContext table.QueryCon
 	}
 	return resultMap, nil
 }
-
-func processAccountListQueues(osqCtx context.Context, queryContext table.QueryContext, account *utilities.ExtensionConfigurationAwsAccount) ([]map[string]string, error) {
-	resultMap := make([]map[string]string, 0)
-	awsSession, err := extaws.GetAwsConfig(account, "us-east-1")
-	if err != nil {
-		return resultMap, err
-	}
-	regions, err := extaws.FetchRegions(osqCtx, awsSession)
-	if err != nil {
-		return resultMap, err
-	}
-	tableConfig, ok := utilities.TableConfigurationMap["aws_sqs_queue"]
-	if !ok {
-		utilities.GetLogger().WithFields(log.Fields{
-			"tableName": "aws_sqs_queue",
-		}).Error("failed to get table configuration")
-		return resultMap, fmt.Errorf("table configuration not found")
-	}
-	for _, region := range regions {
-		accountId := utilities.AwsAccountID
-		if account != nil {
-			accountId = account.ID
-		}
-		if !extaws.ShouldProcessRegion("aws_sqs_queue", accountId, *region.RegionName) {
-			continue
-		}
-		result, err := processRegionListQueues(osqCtx, queryContext, tableConfig, account, region)
-		if err != nil {
-			continue
-		}
-		resultMap = append(resultMap, result...)
-	}
-	return resultMap, nil
-}
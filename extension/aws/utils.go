@@ -11,6 +11,8 @@ package aws
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/Uptycs/cloudquery/utilities"
@@ -23,12 +25,36 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// GetAwsConfig creates an AWS Config for given account.
-// If account is nil, it creates a default config
+// GetAwsConfig returns an AWS Config for given account and region.
+// If account is nil, it returns a default config.
+// Configs are cached per account (see utilities.GetCachedSession) and copied for each region, so all regions
+// share the credentials of the account and roles are assumed once until their credentials expire.
+// The cached config is created with the first region asked for, which is also the region of its STS client.
 func GetAwsConfig(account *utilities.ExtensionConfigurationAwsAccount, regionCode string) (*aws.Config, error) {
+	cfg, err := utilities.GetCachedSession(utilities.ProviderAws, getSessionKey(account), func() (interface{}, error) {
+		return newAwsConfig(account, regionCode)
+	})
+	if err != nil {
+		return nil, err
+	}
+	regionCfg := cfg.(*aws.Config).Copy()
+	regionCfg.Region = regionCode
+	return &regionCfg, nil
+}
+
+// getSessionKey identifies the credentials and endpoint of the configs of an account
+func getSessionKey(account *utilities.ExtensionConfigurationAwsAccount) string {
+	if account == nil {
+		return utilities.GetSessionCacheKey(utilities.ProviderAws, "default")
+	}
+	return utilities.GetSessionCacheKey(utilities.ProviderAws, account.ID, account.CredentialFile, account.ProfileName,
+		account.SourceRoleArn, account.SourceExternalID, account.RoleArn, account.ExternalID, account.EndpointURL)
+}
+
+func newAwsConfig(account *utilities.ExtensionConfigurationAwsAccount, regionCode string) (*aws.Config, error) {
 	if account == nil {
 		utilities.GetLogger().Debug("creating default session")
-		return getDefaultAwsConfig(regionCode)
+		return getDefaultAwsConfig(nil, regionCode)
 	}
 
 	if len(account.ProfileName) != 0 && len(account.RoleArn) == 0 {
@@ -39,7 +65,7 @@ func GetAwsConfig(account *utilities.ExtensionConfigurationAwsAccount, regionCod
 		return getAwsConfigForRole(account, regionCode)
 	} else {
 		utilities.GetLogger().Debug("creating default session")
-		return getDefaultAwsConfig(regionCode)
+		return getDefaultAwsConfig(account, regionCode)
 	}
 }
 
@@ -51,11 +77,11 @@ func getAwsConfigForProfile(account *utilities.ExtensionConfigurationAwsAccount,
 	}).Debug("creating config")
 	credentialFiles := make([]string, 0)
 	credentialFiles = append(credentialFiles, account.CredentialFile)
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
+	cfg, err := config.LoadDefaultConfig(context.TODO(), getLoadOptions(account,
 		config.WithRegion(regionCode),
 		config.WithSharedCredentialsFiles(credentialFiles),
 		config.WithSharedConfigProfile(account.ProfileName),
-	)
+	)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"account":   account.ID,
@@ -76,11 +102,11 @@ func getAwsConfigForRole(account *utilities.ExtensionConfigurationAwsAccount, re
 	}).Debug("creating config")
 	credentialFiles := make([]string, 0)
 	credentialFiles = append(credentialFiles, account.CredentialFile)
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
+	cfg, err := config.LoadDefaultConfig(context.TODO(), getLoadOptions(account,
 		config.WithRegion(regionCode),
 		config.WithSharedCredentialsFiles(credentialFiles),
 		config.WithSharedConfigProfile(account.ProfileName),
-	)
+	)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"account":   account.ID,
@@ -92,18 +118,31 @@ func getAwsConfigForRole(account *utilities.ExtensionConfigurationAwsAccount, re
 	// Create the credentials from AssumeRoleProvider to assume the role
 	// referenced by the role ARN.
 	stsSvc := sts.NewFromConfig(cfg)
+	if len(account.SourceRoleArn) != 0 {
+		// Role chaining: RoleArn is assumed with the credentials of the source role
+		sourceCreds := stscreds.NewAssumeRoleProvider(stsSvc, account.SourceRoleArn, func(options *stscreds.AssumeRoleOptions) {
+			if len(account.SourceExternalID) != 0 {
+				options.ExternalID = &account.SourceExternalID
+			}
+		})
+		sourceCfg := cfg.Copy()
+		sourceCfg.Credentials = aws.NewCredentialsCache(sourceCreds)
+		stsSvc = sts.NewFromConfig(sourceCfg)
+	}
 	creds := stscreds.NewAssumeRoleProvider(stsSvc, account.RoleArn, func(options *stscreds.AssumeRoleOptions) {
 		options.Duration = time.Duration(60) * time.Minute
-		options.ExternalID = &account.ExternalID
+		if len(account.ExternalID) != 0 {
+			options.ExternalID = &account.ExternalID
+		}
 	})
 	cfg.Credentials = aws.NewCredentialsCache(creds)
 	return &cfg, nil
 }
 
-func getDefaultAwsConfig(regionCode string) (*aws.Config, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
+func getDefaultAwsConfig(account *utilities.ExtensionConfigurationAwsAccount, regionCode string) (*aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), getLoadOptions(account,
 		config.WithRegion(regionCode),
-	)
+	)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"account":   "default",
@@ -115,6 +154,72 @@ func getDefaultAwsConfig(regionCode string) (*aws.Config, error) {
 	return &cfg, nil
 }
 
+// getLoadOptions adds the endpoint of the account (if set) and the HTTP client using the transport
+// set with utilities.SetHTTPTransport (if any)
+func getLoadOptions(account *utilities.ExtensionConfigurationAwsAccount, optFns ...func(*config.LoadOptions) error) []func(*config.LoadOptions) error {
+	if account != nil && account.EndpointURL != "" {
+		optFns = append(optFns, config.WithEndpointResolver(getEndpointResolver(account.EndpointURL)))
+	}
+	if transport := utilities.GetHTTPTransport(); transport != nil {
+		optFns = append(optFns, config.WithHTTPClient(&http.Client{Transport: transport}))
+	}
+	return optFns
+}
+
+// getEndpointResolver returns a resolver sending requests of all services and regions to endpointURL,
+// e.g. a LocalStack or moto server. Host name is kept as is, so S3 uses path style addressing.
+func getEndpointResolver(endpointURL string) aws.EndpointResolver {
+	return aws.EndpointResolverFunc(func(service, region string) (aws.Endpoint, error) {
+		return aws.Endpoint{
+			URL:               endpointURL,
+			SigningRegion:     region,
+			HostnameImmutable: true,
+			Source:            aws.EndpointSourceCustom,
+		}, nil
+	})
+}
+
+// GetRegions returns the regions to process for given account.
+// Regions listed in account configuration are used as is if region discovery is disabled,
+// otherwise discovered regions are limited to them.
+func GetRegions(ctx context.Context, account *utilities.ExtensionConfigurationAwsAccount, awsConfig *aws.Config) ([]types.Region, error) {
+	if account != nil && account.DisableRegionDiscovery {
+		if len(account.Regions) == 0 {
+			return nil, fmt.Errorf("regions must be set for account %s when region discovery is disabled", account.ID)
+		}
+		regions := make([]types.Region, 0, len(account.Regions))
+		for _, regionName := range account.Regions {
+			regions = append(regions, types.Region{RegionName: aws.String(regionName)})
+		}
+		return regions, nil
+	}
+
+	awsRegions, err := FetchRegions(ctx, awsConfig)
+	if err != nil || account == nil || len(account.Regions) == 0 {
+		return awsRegions, err
+	}
+	regions := make([]types.Region, 0, len(account.Regions))
+	for _, region := range awsRegions {
+		if IsRegionConfigured(account, aws.ToString(region.RegionName)) {
+			regions = append(regions, region)
+		}
+	}
+	return regions, nil
+}
+
+// IsRegionConfigured returns false if regions of the account are limited in configuration and region is not one of them
+func IsRegionConfigured(account *utilities.ExtensionConfigurationAwsAccount, region string) bool {
+	if account == nil || len(account.Regions) == 0 {
+		return true
+	}
+	for _, regionName := range account.Regions {
+		if regionName == region {
+			return true
+		}
+	}
+	return false
+}
+
 // FetchRegions returns the list of regions for given AWS config
 func FetchRegions(ctx context.Context, awsConfig *aws.Config) ([]types.Region, error) {
 	svc := ec2.NewFromConfig(*awsConfig)
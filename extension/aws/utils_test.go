@@ -10,10 +10,22 @@
 package aws
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/Uptycs/basequery-go/plugin/table"
 	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,9 +53,224 @@ func TestRowToMap(t *testing.T) {
 
 	acntID, region := "test-account", "us-east4"
 	inRow := make(map[string]interface{})
-	tabConfig := utilities.TableConfigurationMap["test_table_1"]
+	tabConfig := utilities.CurrentConfiguration().Tables["test_table_1"]
 	outRow := RowToMap(inRow, acntID, region, tabConfig)
 
 	assert.Equal(t, acntID, outRow["account_id"])
 	assert.Equal(t, region, outRow["region_code"])
 }
+
+func TestGetAwsConfig_endpointURL(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "testing")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "testing")
+	t.Setenv("AWS_CONFIG_FILE", os.DevNull)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", os.DevNull)
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<DescribeRegionsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><regionInfo>` +
+			`<item><regionName>us-east-1</regionName></item><item><regionName>eu-west-1</regionName></item>` +
+			`<item><regionName>ap-south-1</regionName></item></regionInfo></DescribeRegionsResponse>`))
+	}))
+	defer server.Close()
+
+	account := utilities.ExtensionConfigurationAwsAccount{ID: "123456789012", EndpointURL: server.URL}
+	cfg, err := GetAwsConfig(&account, "eu-west-1")
+	assert.Nil(t, err)
+	regions, err := GetRegions(context.Background(), &account, cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(regions))
+	assert.True(t, strings.Contains(authorization, "/eu-west-1/ec2/"))
+
+	// Discovered regions are limited to the configured ones
+	account.Regions = []string{"eu-west-1", "us-west-2"}
+	regions, err = GetRegions(context.Background(), &account, cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(regions))
+	assert.Equal(t, "eu-west-1", aws.ToString(regions[0].RegionName))
+}
+
+func TestGetAwsConfig_sharedCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "testing")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "testing")
+	t.Setenv("AWS_CONFIG_FILE", os.DevNull)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", os.DevNull)
+	utilities.InvalidateSessionCache(utilities.ProviderAws)
+	defer utilities.InvalidateSessionCache(utilities.ProviderAws)
+
+	var assumeRoleCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&assumeRoleCalls, 1)
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult><Credentials>` +
+			`<AccessKeyId>ASIAASSUMED</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken>` +
+			`<Expiration>` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `</Expiration>` +
+			`</Credentials></AssumeRoleResult></AssumeRoleResponse>`))
+	}))
+	defer server.Close()
+
+	account := utilities.ExtensionConfigurationAwsAccount{ID: "123456789012", RoleArn: "arn:aws:iam::123456789012:role/audit", EndpointURL: server.URL}
+	for _, regionCode := range []string{"us-east-1", "eu-west-1", "ap-south-1"} {
+		cfg, err := GetAwsConfig(&account, regionCode)
+		assert.Nil(t, err)
+		assert.Equal(t, regionCode, cfg.Region)
+		creds, err := cfg.Credentials.Retrieve(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, "ASIAASSUMED", creds.AccessKeyID)
+	}
+	// The role is assumed once for all regions of the account
+	assert.Equal(t, int32(1), atomic.LoadInt32(&assumeRoleCalls))
+}
+
+func TestGetAwsConfig_memberExternalID(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "testing")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "testing")
+	t.Setenv("AWS_CONFIG_FILE", os.DevNull)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", os.DevNull)
+	utilities.InvalidateSessionCache(utilities.ProviderAws)
+	defer utilities.InvalidateSessionCache(utilities.ProviderAws)
+
+	var externalIds [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		externalIds = append(externalIds, r.Form["ExternalId"])
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult><Credentials>` +
+			`<AccessKeyId>ASIAASSUMED</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken>` +
+			`<Expiration>` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `</Expiration>` +
+			`</Credentials></AssumeRoleResult></AssumeRoleResponse>`))
+	}))
+	defer server.Close()
+
+	management := utilities.ExtensionConfigurationAwsAccount{ID: "111111111111", EndpointURL: server.URL, DiscoverOrganization: true}
+	member := types.Account{Id: aws.String("222222222222"), Arn: aws.String("arn:aws:organizations::111111111111:account/o-test/222222222222")}
+	for _, memberExternalID := range []string{"", "secret-id"} {
+		management.MemberExternalID = memberExternalID
+		account := getMemberAccount(&management, member)
+		cfg, err := GetAwsConfig(&account, "us-east-1")
+		assert.Nil(t, err)
+		_, err = cfg.Credentials.Retrieve(context.Background())
+		assert.Nil(t, err)
+	}
+	// No ExternalId is sent for members without one, as STS rejects empty ones
+	assert.Equal(t, [][]string{nil, {"secret-id"}}, externalIds)
+}
+
+func TestGetRegions_discoveryDisabled(t *testing.T) {
+	account := utilities.ExtensionConfigurationAwsAccount{ID: "123456789012", DisableRegionDiscovery: true}
+	_, err := GetRegions(context.Background(), &account, nil)
+	assert.NotNil(t, err)
+
+	account.Regions = []string{"us-east-1", "us-west-2"}
+	regions, err := GetRegions(context.Background(), &account, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(regions))
+	assert.Equal(t, "us-west-2", aws.ToString(regions[1].RegionName))
+	assert.True(t, IsRegionConfigured(&account, "us-east-1"))
+	assert.False(t, IsRegionConfigured(&account, "eu-west-1"))
+	assert.True(t, IsRegionConfigured(nil, "eu-west-1"))
+}
+
+func TestGetAccounts_discoverOrganization(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "testing")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "testing")
+	t.Setenv("AWS_CONFIG_FILE", os.DevNull)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", os.DevNull)
+
+	var listAccountsCalls int32
+	parents := map[string]string{
+		"222222222222":     "ou-root-prod",
+		"333333333333":     "ou-root-sandbox1",
+		"ou-root-sandbox1": "ou-root-sandbox",
+		"ou-root-sandbox":  "r-root",
+		"ou-root-prod":     "r-root",
+		"444444444444":     "r-root",
+		"555555555555":     "r-root",
+		"999999999999":     "r-root",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		var input map[string]string
+		json.NewDecoder(r.Body).Decode(&input)
+		switch r.Header.Get("X-Amz-Target") {
+		case "AWSOrganizationsV20161128.ListAccounts":
+			atomic.AddInt32(&listAccountsCalls, 1)
+			// Looking up discovered accounts must not wait for the discovery in progress
+			getDiscoveredAccount("222222222222")
+			w.Write([]byte(`{"Accounts": [
+				{"Id": "111111111111", "Arn": "arn:aws:organizations::111111111111:account/o-test/111111111111", "Status": "ACTIVE"},
+				{"Id": "222222222222", "Arn": "arn:aws:organizations::111111111111:account/o-test/222222222222", "Name": "prod", "Status": "ACTIVE"},
+				{"Id": "333333333333", "Arn": "arn:aws:organizations::111111111111:account/o-test/333333333333", "Status": "ACTIVE"},
+				{"Id": "444444444444", "Arn": "arn:aws:organizations::111111111111:account/o-test/444444444444", "Status": "SUSPENDED"},
+				{"Id": "555555555555", "Arn": "arn:aws-cn:organizations::111111111111:account/o-test/555555555555", "Name": "dev", "Status": "ACTIVE"},
+				{"Id": "999999999999", "Arn": "arn:aws:organizations::111111111111:account/o-test/999999999999", "Status": "ACTIVE"}
+			]}`))
+		case "AWSOrganizationsV20161128.ListParents":
+			w.Write([]byte(fmt.Sprintf(`{"Parents": [{"Id": %q}]}`, parents[input["ChildId"]])))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	credentialFile := t.TempDir() + string(os.PathSeparator) + "credentials"
+	assert.Nil(t, os.WriteFile(credentialFile, []byte("[management]\naws_access_key_id = testing\naws_secret_access_key = testing\n"), 0600))
+
+	savedConfiguration := utilities.CurrentConfiguration()
+	defer func() {
+		utilities.UpdateConfiguration(func(config *utilities.Configuration) { config.Extension = savedConfiguration.Extension })
+		organizationCache = make(map[string]*organizationMembers)
+		timeNow = time.Now
+	}()
+	utilities.UpdateConfiguration(func(config *utilities.Configuration) {
+		config.Extension.ExtConfAws.Accounts = []utilities.ExtensionConfigurationAwsAccount{
+			{
+				ID:                         "111111111111",
+				CredentialFile:             credentialFile,
+				ProfileName:                "management",
+				EndpointURL:                server.URL,
+				IncludeRegions:             []string{"eu-*"},
+				DiscoverOrganization:       true,
+				MemberRoleName:             "audit-{accountName}",
+				ExcludeOrganizationalUnits: []string{"ou-root-sandbox"},
+			},
+			{ID: "999999999999", ProfileName: "explicit"},
+		}
+	})
+
+	accounts := GetAccounts(context.Background())
+	assert.Equal(t, 4, len(accounts))
+	assert.Equal(t, "explicit", accounts[1].ProfileName)
+	assert.Equal(t, "222222222222", accounts[2].ID)
+	assert.Equal(t, "arn:aws:iam::222222222222:role/audit-prod", accounts[2].RoleArn)
+	assert.Equal(t, "management", accounts[2].ProfileName)
+	assert.Equal(t, server.URL, accounts[2].EndpointURL)
+	assert.Equal(t, "555555555555", accounts[3].ID)
+	assert.Equal(t, "arn:aws-cn:iam::555555555555:role/audit-dev", accounts[3].RoleArn)
+	assert.False(t, ShouldProcessRegion(context.Background(), table.QueryContext{}, "unknown_table", "222222222222", "us-east-1"))
+	assert.True(t, ShouldProcessRegion(context.Background(), table.QueryContext{}, "unknown_table", "222222222222", "eu-west-1"))
+
+	// Members are cached until the discovery interval has passed
+	GetAccounts(context.Background())
+	assert.Equal(t, int32(1), listAccountsCalls)
+	timeNow = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	GetAccounts(context.Background())
+	assert.Equal(t, int32(2), listAccountsCalls)
+
+	// Discovery in the background lists the members again right away after a reload
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	StartOrganizationDiscovery(ctx, wg)
+	InvalidateDiscoveredAccounts()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&listAccountsCalls) >= 3 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	// Previously discovered members are kept if listing fails
+	server.Close()
+	timeNow = func() time.Time { return time.Now().Add(4 * time.Hour) }
+	accounts = GetAccounts(context.Background())
+	assert.Equal(t, 4, len(accounts))
+}
@@ -13,7 +13,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -26,73 +25,26 @@ import (
 
 var azureComputeSecurityGroup = "azure_compute_security_group"
 
-// SecurityGroupsColumns returns the list of columns in the table
-func SecurityGroupsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("etag"),
-		table.TextColumn("id"),
-		table.TextColumn("location"),
-		table.TextColumn("name"),
-		// table.TextColumn("properties"),
-		table.TextColumn("default_security_rules"),
-		// table.TextColumn("default_security_rules_etag"),
-		// table.TextColumn("default_security_rules_id"),
-		// table.TextColumn("default_security_rules_name"),
-		// table.TextColumn("default_security_rules_type"),
-		table.TextColumn("flow_logs"),
-		// table.TextColumn("flow_logs_etag"),
-		// table.TextColumn("flow_logs_id"),
-		// table.TextColumn("flow_logs_location"),
-		// table.TextColumn("flow_logs_name"),
-		// table.TextColumn("flow_logs_tags"),
-		// table.TextColumn("flow_logs_type"),
-		table.TextColumn("network_interfaces"),
-		// table.TextColumn("network_interfaces_etag"),
-		// table.TextColumn("network_interfaces_extended_location"),
-		// table.TextColumn("network_interfaces_extended_location_name"),
-		// table.TextColumn("network_interfaces_extended_location_type"),
-		// table.TextColumn("network_interfaces_id"),
-		// table.TextColumn("network_interfaces_location"),
-		// table.TextColumn("network_interfaces_name"),
-		// table.TextColumn("network_interfaces_tags"),
-		// table.TextColumn("network_interfaces_type"),
-		table.TextColumn("provisioning_state"),
-		table.TextColumn("resource_guid"),
-		table.TextColumn("security_rules"),
-		// table.TextColumn("security_rules_etag"),
-		// table.TextColumn("security_rules_id"),
-		// table.TextColumn("security_rules_name"),
-		// table.TextColumn("security_rules_type"),
-		table.TextColumn("subnets"),
-		// table.TextColumn("subnets_etag"),
-		// table.TextColumn("subnets_id"),
-		// table.TextColumn("subnets_name"),
-		// table.TextColumn("subnets_type"),
-		table.TextColumn("tags"),
-		table.TextColumn("type"),
-	}
-}
-
 // SecurityGroupsGenerate returns the rows in the table for all configured accounts
 func SecurityGroupsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": azureComputeSecurityGroup,
 			"account":   "default",
 		}).Info("processing account")
-		results, err := processAccountSecurityGroups(nil)
+		results, err := processAccountSecurityGroups(osqCtx, nil)
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range extazure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": azureComputeSecurityGroup,
 				"account":   account.SubscriptionID,
 			}).Info("processing account")
-			results, err := processAccountSecurityGroups(&account)
+			results, err := processAccountSecurityGroups(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -103,22 +55,14 @@ func SecurityGroupsGenerate(osqCtx context.Context, queryContext table.QueryCont
 	return resultMap, nil
 }
 
-func processAccountSecurityGroups(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processAccountSecurityGroups(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
 	session, err := extazure.GetAuthSession(account)
-	if err != nil {
-		return resultMap, err
-	}
-	groups, err := extazure.GetGroups(session)
-
 	if err != nil {
 		return resultMap, err
 	}
 
-	wg.Add(len(groups))
-
-	tableConfig, ok := utilities.TableConfigurationMap[azureComputeSecurityGroup]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[azureComputeSecurityGroup]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": azureComputeSecurityGroup,
@@ -126,20 +70,16 @@ func processAccountSecurityGroups(account *utilities.ExtensionConfigurationAzure
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
 
-	for _, group := range groups {
-		go getSecurityGroups(session, group, &wg, &resultMap, tableConfig)
-	}
-	wg.Wait()
-	return resultMap, nil
+	return extazure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		getSecurityGroups(session, rg, collector, tableConfig)
+	})
 }
 
-func getSecurityGroups(session *extazure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
-
+func getSecurityGroups(session *extazure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
 	svcClient := network.NewSecurityGroupsClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
+	session.ConfigureClient(&svcClient.Client)
 
-	for resourceItr, err := svcClient.ListComplete(context.Background(), rg); resourceItr.NotDone(); err = resourceItr.Next() {
+	for resourceItr, err := svcClient.ListComplete(collector.Context(), rg); resourceItr.NotDone(); err = resourceItr.Next() {
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName":     azureComputeSecurityGroup,
@@ -150,7 +90,6 @@ func getSecurityGroups(session *extazure.AzureSession, rg string, wg *sync.WaitG
 		}
 
 		resource := resourceItr.Value()
-		structs.DefaultTagName = "json"
 		resMap := structs.Map(resource)
 		utilities.GetLogger().Error(resMap)
 
@@ -165,8 +104,11 @@ func getSecurityGroups(session *extazure.AzureSession, rg string, wg *sync.WaitG
 		}
 		table := utilities.NewTable(byteArr, tableConfig)
 		for _, row := range table.Rows {
+			if !extazure.ShouldProcessRow(collector.Context(), azureComputeSecurityGroup, session.SubscriptionId, rg, row) {
+				continue
+			}
 			result := extazure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-			*resultMap = append(*resultMap, result)
+			collector.Add(result)
 		}
 	}
 }
@@ -13,7 +13,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -28,112 +27,26 @@ import (
 
 var azureComputeSubnet string = "azure_compute_subnet"
 
-// VirtualSubnetColumns returns the list of columns in the table
-func VirtualSubnetColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("etag"),
-		table.TextColumn("id"),
-		table.TextColumn("name"),
-		// table.TextColumn("properties"),
-		table.TextColumn("address_prefix"),
-		table.TextColumn("address_prefixes"),
-		table.TextColumn("application_gateway_ip_configurations"),
-		// table.TextColumn("application_gateway_ip_configurations_etag"),
-		// table.TextColumn("application_gateway_ip_configurations_id"),
-		// table.TextColumn("application_gateway_ip_configurations_name"),
-		// table.TextColumn("application_gateway_ip_configurations_type"),
-		table.TextColumn("delegations"),
-		// table.TextColumn("delegations_etag"),
-		// table.TextColumn("delegations_id"),
-		// table.TextColumn("delegations_name"),
-		// table.TextColumn("delegations_type"),
-		table.TextColumn("ip_allocations"),
-		// table.TextColumn("ip_allocations_id"),
-		table.TextColumn("ip_configuration_profiles"),
-		// table.TextColumn("ip_configuration_profiles_etag"),
-		// table.TextColumn("ip_configuration_profiles_id"),
-		// table.TextColumn("ip_configuration_profiles_name"),
-		// table.TextColumn("ip_configuration_profiles_type"),
-		// table.TextColumn("ip_configurations"),
-		// table.TextColumn("ip_configurations_etag"),
-		// table.TextColumn("ip_configurations_id"),
-		// table.TextColumn("ip_configurations_name"),
-		table.TextColumn("nat_gateway"),
-		// table.TextColumn("nat_gateway_id"),
-		table.TextColumn("network_security_group"),
-		// table.TextColumn("network_security_group_etag"),
-		// table.TextColumn("network_security_group_id"),
-		// table.TextColumn("network_security_group_location"),
-		// table.TextColumn("network_security_group_name"),
-		// table.TextColumn("network_security_group_tags"),
-		// table.TextColumn("network_security_group_type"),
-		table.TextColumn("private_endpoint_network_policies"),
-		table.TextColumn("private_endpoints"),
-		// table.TextColumn("private_endpoints_etag"),
-		// table.TextColumn("private_endpoints_extended_location"),
-		// table.TextColumn("private_endpoints_extended_location_name"),
-		// table.TextColumn("private_endpoints_extended_location_type"),
-		// table.TextColumn("private_endpoints_id"),
-		// table.TextColumn("private_endpoints_location"),
-		// table.TextColumn("private_endpoints_name"),
-		// table.TextColumn("private_endpoints_tags"),
-		// table.TextColumn("private_endpoints_type"),
-		table.TextColumn("private_link_service_network_policies"),
-		// table.TextColumn("provisioning_state"),
-		table.TextColumn("purpose"),
-		table.TextColumn("resource_navigation_links"),
-		// table.TextColumn("resource_navigation_links_etag"),
-		// table.TextColumn("resource_navigation_links_id"),
-		// table.TextColumn("resource_navigation_links_name"),
-		// table.TextColumn("resource_navigation_links_type"),
-		table.TextColumn("route_table"),
-		// table.TextColumn("route_table_etag"),
-		// table.TextColumn("route_table_id"),
-		// table.TextColumn("route_table_location"),
-		// table.TextColumn("route_table_name"),
-		// table.TextColumn("route_table_tags"),
-		// table.TextColumn("route_table_type"),
-		table.TextColumn("service_association_links"),
-		// table.TextColumn("service_association_links_etag"),
-		// table.TextColumn("service_association_links_id"),
-		// table.TextColumn("service_association_links_name"),
-		// table.TextColumn("service_association_links_type"),
-		table.TextColumn("service_endpoint_policies"),
-		// table.TextColumn("service_endpoint_policies_etag"),
-		// table.TextColumn("service_endpoint_policies_id"),
-		// table.TextColumn("service_endpoint_policies_kind"),
-		// table.TextColumn("service_endpoint_policies_location"),
-		// table.TextColumn("service_endpoint_policies_name"),
-		// table.TextColumn("service_endpoint_policies_tags"),
-		// table.TextColumn("service_endpoint_policies_type"),
-		table.TextColumn("service_endpoints"),
-		// table.TextColumn("service_endpoints_locations"),
-		table.TextColumn("service_endpoints_provisioning_state"),
-		// table.TextColumn("service_endpoints_service"),
-		table.TextColumn("type"),
-	}
-}
-
 // VirtualSubnetsGenerate returns the rows in the table for all configured accounts
 func VirtualSubnetsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": azureComputeSubnet,
 			"account":   "default",
 		}).Info("processing account")
-		results, err := processAccountVirtualSubnets(nil)
+		results, err := processAccountVirtualSubnets(osqCtx, nil)
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range azure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": azureComputeSubnet,
 				"account":   account.SubscriptionID,
 			}).Info("processing account")
-			results, err := processAccountVirtualSubnets(&account)
+			results, err := processAccountVirtualSubnets(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -144,22 +57,14 @@ func VirtualSubnetsGenerate(osqCtx context.Context, queryContext table.QueryCont
 	return resultMap, nil
 }
 
-func processAccountVirtualSubnets(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processAccountVirtualSubnets(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
 	session, err := azure.GetAuthSession(account)
-	if err != nil {
-		return resultMap, err
-	}
-	groups, err := azure.GetGroups(session)
-
 	if err != nil {
 		return resultMap, err
 	}
 
-	wg.Add(len(groups))
-
-	tableConfig, ok := utilities.TableConfigurationMap[azureComputeSubnet]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[azureComputeSubnet]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": azureComputeSubnet,
@@ -167,19 +72,15 @@ func processAccountVirtualSubnets(account *utilities.ExtensionConfigurationAzure
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
 
-	for _, group := range groups {
-		go getVirtualNetworksForSubnet(session, group, &wg, &resultMap, tableConfig)
-	}
-	wg.Wait()
-	return resultMap, nil
+	return azure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		getVirtualNetworksForSubnet(session, rg, collector, tableConfig)
+	})
 }
-func getVirtualNetworksForSubnet(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
-
+func getVirtualNetworksForSubnet(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
 	svcClient := network.NewVirtualNetworksClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
+	session.ConfigureClient(&svcClient.Client)
 
-	for resourceItr, err := svcClient.ListComplete(context.Background(), rg); resourceItr.NotDone(); err = resourceItr.Next() {
+	for resourceItr, err := svcClient.ListComplete(collector.Context(), rg); resourceItr.NotDone(); err = resourceItr.Next() {
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName":     azureComputeSubnet,
@@ -191,17 +92,17 @@ func getVirtualNetworksForSubnet(session *azure.AzureSession, rg string, wg *syn
 
 		resource := resourceItr.Value()
 
-		getVirtualSubnets(session, rg, wg, resultMap, tableConfig, *resource.Name)
+		getVirtualSubnets(session, rg, collector, tableConfig, *resource.Name)
 
 	}
 }
 
-func getVirtualSubnets(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig, networkName string) {
+func getVirtualSubnets(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig, networkName string) {
 
 	svcClient := network.NewSubnetsClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
+	session.ConfigureClient(&svcClient.Client)
 
-	for resourceItr, err := svcClient.ListComplete(context.Background(), rg, networkName); resourceItr.NotDone(); err = resourceItr.Next() {
+	for resourceItr, err := svcClient.ListComplete(collector.Context(), rg, networkName); resourceItr.NotDone(); err = resourceItr.Next() {
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName":     azureComputeSubnet,
@@ -213,7 +114,6 @@ func getVirtualSubnets(session *azure.AzureSession, rg string, wg *sync.WaitGrou
 
 		resource := resourceItr.Value()
 
-		structs.DefaultTagName = "json"
 		resMap := structs.Map(resource)
 
 		byteArr, err := json.Marshal(resMap)
@@ -227,8 +127,11 @@ func getVirtualSubnets(session *azure.AzureSession, rg string, wg *sync.WaitGrou
 		}
 		table := utilities.NewTable(byteArr, tableConfig)
 		for _, row := range table.Rows {
+			if !azure.ShouldProcessRow(collector.Context(), azureComputeSubnet, session.SubscriptionId, rg, row) {
+				continue
+			}
 			result := azure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-			*resultMap = append(*resultMap, result)
+			collector.Add(result)
 		}
 	}
 }
@@ -0,0 +1,39 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package compute
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/Uptycs/cloudquery/utilities/vcr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	utilities.CreateLogger(true, 20, 1, 30)
+	os.Exit(m.Run())
+}
+
+func TestGenerate(t *testing.T) {
+	assert.Nil(t, utilities.ReadTableConfig(defaultTableConfig))
+	savedConfiguration := utilities.CurrentConfiguration()
+	defer utilities.UpdateConfiguration(func(config *utilities.Configuration) { config.Extension = savedConfiguration.Extension })
+	utilities.UpdateConfiguration(func(config *utilities.Configuration) {
+		config.Extension.ExtConfAzure.Accounts = []utilities.ExtensionConfigurationAzureAccount{
+			{SubscriptionID: "00000000-0000-0000-0000-000000000001", AuthFile: "testdata/auth.json"},
+		}
+	})
+	vcr.RunGoldenTests(t, map[string]table.GenerateFunc{
+		"azure_compute_disk": DiskGenerate,
+	})
+}
@@ -0,0 +1,30 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package compute
+
+import (
+	_ "embed"
+
+	"github.com/Uptycs/cloudquery/utilities"
+)
+
+//go:embed table_config.json
+var defaultTableConfig []byte
+
+func init() {
+	utilities.RegisterTableConfigFile("azure/compute/table_config.json", defaultTableConfig)
+
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_compute_vm", Generate: VirtualMachinesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_compute_networkinterface", Generate: InterfacesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_compute_virtual_network", Generate: VirtualNetworksGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_compute_subnet", Generate: VirtualSubnetsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_compute_disk", Generate: DiskGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_compute_security_group", Generate: SecurityGroupsGenerate})
+}
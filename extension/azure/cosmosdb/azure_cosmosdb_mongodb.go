@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -19,46 +18,26 @@ import (
 
 const cosmosdbMongodb string = "azure_cosmosdb_mongodb"
 
-// CosmosdbMongodbColumns returns the list of columns in the table
-func CosmosdbMongodbColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("id"),
-		table.TextColumn("location"),
-		table.TextColumn("name"),
-		// table.TextColumn("properties"),
-		table.TextColumn("options"),
-		// table.TextColumn("options_autoscale_settings"),
-		// table.IntegerColumn("options_autoscale_settings_max_throughput"),
-		// table.IntegerColumn("options_throughput"),
-		table.TextColumn("resource"),
-		// table.TextColumn("resource__etag"),
-		// table.TextColumn("resource__rid"),
-		// table.TextColumn("resource_id"),
-		table.TextColumn("tags"),
-		table.TextColumn("type"),
-	}
-}
-
 // CosmosdbMongodbGenerate returns the rows in the table for all configured accounts
 func CosmosdbMongodbGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": cosmosdbMongodb,
 			"account":   "default",
 		}).Info("processing account")
-		results, err := processAccountCosmosdbMongodb(nil)
+		results, err := processAccountCosmosdbMongodb(osqCtx, nil)
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range azure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": cosmosdbMongodb,
 				"account":   account.SubscriptionID,
 			}).Info("processing account")
-			results, err := processAccountCosmosdbMongodb(&account)
+			results, err := processAccountCosmosdbMongodb(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -69,22 +48,14 @@ func CosmosdbMongodbGenerate(osqCtx context.Context, queryContext table.QueryCon
 	return resultMap, nil
 }
 
-func processAccountCosmosdbMongodb(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processAccountCosmosdbMongodb(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
 	session, err := azure.GetAuthSession(account)
 	if err != nil {
 		return resultMap, err
 	}
-	groups, err := azure.GetGroups(session)
-
-	if err != nil {
-		return resultMap, err
-	}
-
-	wg.Add(len(groups))
 
-	tableConfig, ok := utilities.TableConfigurationMap[cosmosdbMongodb]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[cosmosdbMongodb]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": cosmosdbMongodb,
@@ -92,16 +63,13 @@ func processAccountCosmosdbMongodb(account *utilities.ExtensionConfigurationAzur
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
 
-	for _, group := range groups {
-		go getCosmosdbAccountsForMongodb(session, group, &wg, &resultMap, tableConfig)
-	}
-	wg.Wait()
-	return resultMap, nil
+	return azure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		getCosmosdbAccountsForMongodb(session, rg, collector, tableConfig)
+	})
 }
 
-func getCosmosdbAccountsForMongodb(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
-	accoutnamelist, err := getCosmosdbAccountData(session, rg)
+func getCosmosdbAccountsForMongodb(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
+	accoutnamelist, err := getCosmosdbAccountData(collector.Context(), session, rg)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName":      cosmosdbMongodb,
@@ -110,13 +78,13 @@ func getCosmosdbAccountsForMongodb(session *azure.AzureSession, rg string, wg *s
 		}).Error("failed to get cosmosdb account list from api")
 	}
 	for _, accountnameinfo := range *accoutnamelist.Value {
-		setCosmosdbMongodbToTable(session, rg, wg, resultMap, tableConfig, *accountnameinfo.Name)
+		setCosmosdbMongodbToTable(session, rg, collector, tableConfig, *accountnameinfo.Name)
 	}
 
 }
 
-func setCosmosdbMongodbToTable(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig, accountName string) {
-	mongodblist, err := getCosmosdbMongodbData(session, rg, accountName)
+func setCosmosdbMongodbToTable(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig, accountName string) {
+	mongodblist, err := getCosmosdbMongodbData(collector.Context(), session, rg, accountName)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName":     cosmosdbMongodb,
@@ -126,7 +94,6 @@ func setCosmosdbMongodbToTable(session *azure.AzureSession, rg string, wg *sync.
 	}
 
 	for _, mongodb := range *mongodblist.Value {
-		structs.DefaultTagName = "json"
 		resMap := structs.Map(mongodb)
 		byteArr, err := json.Marshal(resMap)
 		if err != nil {
@@ -140,14 +107,17 @@ func setCosmosdbMongodbToTable(session *azure.AzureSession, rg string, wg *sync.
 		table := utilities.NewTable(byteArr, tableConfig)
 
 		for _, row := range table.Rows {
+			if !azure.ShouldProcessRow(collector.Context(), cosmosdbMongodb, session.SubscriptionId, rg, row) {
+				continue
+			}
 			result := azure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-			*resultMap = append(*resultMap, result)
+			collector.Add(result)
 		}
 	}
 }
 
-func getCosmosdbMongodbData(session *azure.AzureSession, rg string, accountName string) (result documentdb.MongoDBDatabaseListResult, err error) {
+func getCosmosdbMongodbData(ctx context.Context, session *azure.AzureSession, rg string, accountName string) (result documentdb.MongoDBDatabaseListResult, err error) {
 	svcClient := documentdb.NewMongoDBResourcesClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
-	return svcClient.ListMongoDBDatabases(context.Background(), rg, accountName)
+	session.ConfigureClient(&svcClient.Client)
+	return svcClient.ListMongoDBDatabases(ctx, rg, accountName)
 }
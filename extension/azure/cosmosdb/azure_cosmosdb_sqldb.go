@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -19,49 +18,26 @@ import (
 
 const cosmosdbSqldb string = "azure_cosmosdb_sqldb"
 
-// CosmosdbSqldbsColumns returns the list of columns in the table
-func CosmosdbSqldbsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("id"),
-		table.TextColumn("location"),
-		table.TextColumn("name"),
-		// table.TextColumn("properties"),
-		table.TextColumn("options"),
-		// table.TextColumn("options_autoscale_settings"),
-		// table.IntegerColumn("options_autoscale_settings_max_throughput"),
-		// table.IntegerColumn("options_throughput"),
-		table.TextColumn("resource"),
-		// table.TextColumn("resource__colls"),
-		// table.TextColumn("resource__etag"),
-		// table.TextColumn("resource__rid"),
-		// table.DoubleColumn("resource__ts"),
-		// table.TextColumn("resource__users"),
-		// table.TextColumn("resource_id"),
-		table.TextColumn("tags"),
-		table.TextColumn("type"),
-	}
-}
-
 // CosmosdbSqldbsGenerate returns the rows in the table for all configured accounts
 func CosmosdbSqldbsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": cosmosdbSqldb,
 			"account":   "default",
 		}).Info("processing account")
-		results, err := processAccountCosmosdbSqldbs(nil)
+		results, err := processAccountCosmosdbSqldbs(osqCtx, nil)
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range azure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": cosmosdbSqldb,
 				"account":   account.SubscriptionID,
 			}).Info("processing account")
-			results, err := processAccountCosmosdbSqldbs(&account)
+			results, err := processAccountCosmosdbSqldbs(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -72,22 +48,14 @@ func CosmosdbSqldbsGenerate(osqCtx context.Context, queryContext table.QueryCont
 	return resultMap, nil
 }
 
-func processAccountCosmosdbSqldbs(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processAccountCosmosdbSqldbs(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
 	session, err := azure.GetAuthSession(account)
 	if err != nil {
 		return resultMap, err
 	}
-	groups, err := azure.GetGroups(session)
-
-	if err != nil {
-		return resultMap, err
-	}
-
-	wg.Add(len(groups))
 
-	tableConfig, ok := utilities.TableConfigurationMap[cosmosdbSqldb]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[cosmosdbSqldb]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": cosmosdbSqldb,
@@ -95,16 +63,13 @@ func processAccountCosmosdbSqldbs(account *utilities.ExtensionConfigurationAzure
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
 
-	for _, group := range groups {
-		go getCosmosdbAccountforsqldb(session, group, &wg, &resultMap, tableConfig)
-	}
-	wg.Wait()
-	return resultMap, nil
+	return azure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		getCosmosdbAccountforsqldb(session, rg, collector, tableConfig)
+	})
 }
 
-func getCosmosdbAccountforsqldb(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
-	accoutnamelist, err := getCosmosdbAccountData(session, rg)
+func getCosmosdbAccountforsqldb(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
+	accoutnamelist, err := getCosmosdbAccountData(collector.Context(), session, rg)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName":      cosmosdbSqldb,
@@ -113,12 +78,12 @@ func getCosmosdbAccountforsqldb(session *azure.AzureSession, rg string, wg *sync
 		}).Error("failed to get cosmosdb account list from api")
 	}
 	for _, accountnameinfo := range *accoutnamelist.Value {
-		setCosmosdbSqldbDataToTable(session, rg, wg, resultMap, tableConfig, *accountnameinfo.Name)
+		setCosmosdbSqldbDataToTable(session, rg, collector, tableConfig, *accountnameinfo.Name)
 	}
 
 }
-func setCosmosdbSqldbDataToTable(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig, accountName string) {
-	sqldblist, err := getCosmosdbSqldbData(session, rg, accountName)
+func setCosmosdbSqldbDataToTable(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig, accountName string) {
+	sqldblist, err := getCosmosdbSqldbData(collector.Context(), session, rg, accountName)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName":     cosmosdbSqldb,
@@ -128,7 +93,6 @@ func setCosmosdbSqldbDataToTable(session *azure.AzureSession, rg string, wg *syn
 	}
 
 	for _, sqldb := range *sqldblist.Value {
-		structs.DefaultTagName = "json"
 		resMap := structs.Map(sqldb)
 		byteArr, err := json.Marshal(resMap)
 		if err != nil {
@@ -142,13 +106,16 @@ func setCosmosdbSqldbDataToTable(session *azure.AzureSession, rg string, wg *syn
 		table := utilities.NewTable(byteArr, tableConfig)
 
 		for _, row := range table.Rows {
+			if !azure.ShouldProcessRow(collector.Context(), cosmosdbSqldb, session.SubscriptionId, rg, row) {
+				continue
+			}
 			result := azure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-			*resultMap = append(*resultMap, result)
+			collector.Add(result)
 		}
 	}
 }
-func getCosmosdbSqldbData(session *azure.AzureSession, rg string, accountName string) (result documentdb.SQLDatabaseListResult, err error) {
+func getCosmosdbSqldbData(ctx context.Context, session *azure.AzureSession, rg string, accountName string) (result documentdb.SQLDatabaseListResult, err error) {
 	svcClient := documentdb.NewSQLResourcesClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
-	return svcClient.ListSQLDatabases(context.Background(), rg, accountName)
+	session.ConfigureClient(&svcClient.Client)
+	return svcClient.ListSQLDatabases(ctx, rg, accountName)
 }
@@ -0,0 +1,28 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package azure
+
+import (
+	"context"
+
+	"github.com/Uptycs/cloudquery/utilities"
+)
+
+// ShouldProcessRow returns false if given row is not supposed to be processed for given table
+// Rows excluded by the filter rules of the table in extension_config.json are skipped
+func ShouldProcessRow(ctx context.Context, tableName string, subscriptionId string, resourceGroup string, row map[string]interface{}) bool {
+	return utilities.MatchesRowFilter(ctx, tableName, row)
+}
+
+// ShouldProcessEvent returns false if given event is not supposed to be processed for given table
+// Events excluded by the filter rules of the table in extension_config.json are skipped
+func ShouldProcessEvent(osqCtx context.Context, tableName string, subscriptionId string, resourceGroup string, row map[string]string) bool {
+	return utilities.MatchesEventFilter(osqCtx, tableName, row)
+}
@@ -13,7 +13,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -27,76 +26,26 @@ import (
 
 var azureMysqlServer = "azure_mysql_server"
 
-// MysqlServerColumns returns the list of columns in the table
-func MysqlServerColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("id"),
-		table.TextColumn("identity"),
-		table.TextColumn("identity_principal_id"),
-		table.TextColumn("identity_tenant_id"),
-		table.TextColumn("identity_type"),
-		table.TextColumn("location"),
-		table.TextColumn("name"),
-		// table.TextColumn("properties"),
-		table.TextColumn("administrator_login"),
-		table.TextColumn("byok_enforcement"),
-		table.TextColumn("earliest_restore_date"),
-		table.TextColumn("fully_qualified_domain_name"),
-		table.TextColumn("infrastructure_encryption"),
-		table.TextColumn("master_server_id"),
-		table.TextColumn("minimal_tls_version"),
-		table.TextColumn("private_endpoint_connections"),
-		// table.TextColumn("private_endpoint_connections_id"),
-		// table.TextColumn("private_endpoint_connections_properties"),
-		// table.TextColumn("private_endpoint_connections_properties_private_endpoint"),
-		// table.TextColumn("private_endpoint_connections_properties_private_endpoint_id"),
-		// table.TextColumn("private_endpoint_connections_properties_private_link_service_connection_state"),
-		// table.TextColumn("private_endpoint_connections_properties_private_link_service_connection_state_actions_required"),
-		// table.TextColumn("private_endpoint_connections_properties_private_link_service_connection_state_description"),
-		// table.TextColumn("private_endpoint_connections_properties_private_link_service_connection_state_status"),
-		// table.TextColumn("private_endpoint_connections_properties_provisioning_state"),
-		table.TextColumn("public_network_access"),
-		table.IntegerColumn("replica_capacity"),
-		table.TextColumn("replication_role"),
-		table.TextColumn("ssl_enforcement"),
-		table.TextColumn("storage_profile"),
-		// table.IntegerColumn("storage_profile_backup_retention_days"),
-		// table.TextColumn("storage_profile_geo_redundant_backup"),
-		// table.TextColumn("storage_profile_storage_autogrow"),
-		// table.IntegerColumn("storage_profile_storage_mb"),
-		table.TextColumn("user_visible_state"),
-		table.TextColumn("version"),
-		table.TextColumn("sku"),
-		table.IntegerColumn("sku_capacity"),
-		table.TextColumn("sku_family"),
-		table.TextColumn("sku_name"),
-		table.TextColumn("sku_size"),
-		table.TextColumn("sku_tier"),
-		table.TextColumn("tags"),
-		table.TextColumn("type"),
-	}
-}
-
 // MysqlServerGenerate returns the rows in the table for all configured accounts
 func MysqlServerGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": azureMysqlServer,
 			"account":   "default",
 		}).Info("processing account")
-		results, err := processAccountMysqlServer(nil)
+		results, err := processAccountMysqlServer(osqCtx, nil)
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range extazure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": azureMysqlServer,
 				"account":   account.SubscriptionID,
 			}).Info("processing account")
-			results, err := processAccountMysqlServer(&account)
+			results, err := processAccountMysqlServer(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -107,41 +56,30 @@ func MysqlServerGenerate(osqCtx context.Context, queryContext table.QueryContext
 	return resultMap, nil
 }
 
-func processAccountMysqlServer(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processAccountMysqlServer(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
 	session, err := extazure.GetAuthSession(account)
-	if err != nil {
-		return resultMap, err
-	}
-	groups, err := extazure.GetGroups(session)
-
 	if err != nil {
 		return resultMap, err
 	}
 
-	wg.Add(len(groups))
-
-	tableConfig, ok := utilities.TableConfigurationMap[azureMysqlServer]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[azureMysqlServer]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": azureMysqlServer,
 		}).Error("failed to get table configuration")
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
-	for _, group := range groups {
-		go getMysqlServer(session, group, &wg, &resultMap, tableConfig)
-	}
-	wg.Wait()
-	return resultMap, nil
-}
 
-func getMysqlServer(session *extazure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
+	return extazure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		getMysqlServer(session, rg, collector, tableConfig)
+	})
+}
 
+func getMysqlServer(session *extazure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
 	svcClient := mysql.NewServersClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
-	resourceItr, err := svcClient.List(context.Background())
+	session.ConfigureClient(&svcClient.Client)
+	resourceItr, err := svcClient.List(collector.Context())
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName":     azureMysqlServer,
@@ -161,7 +99,10 @@ func getMysqlServer(session *extazure.AzureSession, rg string, wg *sync.WaitGrou
 	}
 	table := utilities.NewTable(byteArr, tableConfig)
 	for _, row := range table.Rows {
+		if !extazure.ShouldProcessRow(collector.Context(), azureMysqlServer, session.SubscriptionId, rg, row) {
+			continue
+		}
 		result := extazure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-		*resultMap = append(*resultMap, result)
+		collector.Add(result)
 	}
 }
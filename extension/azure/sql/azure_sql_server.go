@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	"github.com/fatih/structs"
 	log "github.com/sirupsen/logrus"
@@ -18,43 +17,23 @@ import (
 
 const sqlServer string = "azure_sql_server"
 
-// SqlServerCloumns returns the list of cloums in the table
-func SqlServerCloumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("id"),
-		table.TextColumn("kind"),
-		table.TextColumn("location"),
-		table.TextColumn("name"),
-		// table.TextColumn("properties"),
-		table.TextColumn("administrator_login"),
-		table.TextColumn("administrator_login_password"),
-		table.TextColumn("external_administrator_login"),
-		table.TextColumn("external_administrator_sid"),
-		table.TextColumn("fully_qualified_domain_name"),
-		table.TextColumn("state"),
-		table.TextColumn("version"),
-		table.TextColumn("tags"),
-		table.TextColumn("type"),
-	}
-}
-
 // SqlServerGenerate returns the row in the table for all configured sql server
 func SqlServerGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": sqlServer,
 			"account":   "default",
 		}).Info("processing sql server")
 
-		results, err := processSqlServer(nil)
+		results, err := processSqlServer(osqCtx, nil)
 
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range azure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName":      sqlServer,
 				"account":        account,
@@ -62,7 +41,7 @@ func SqlServerGenerate(osqCtx context.Context, queryContext table.QueryContext)
 			}).Info("processing accounts")
 
 			results, err :=
-				processSqlServer(&account)
+				processSqlServer(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -73,43 +52,28 @@ func SqlServerGenerate(osqCtx context.Context, queryContext table.QueryContext)
 	return resultMap, nil
 }
 
-func processSqlServer(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processSqlServer(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
-
 	session, err := azure.GetAuthSession(account)
 	if err != nil {
 		return resultMap, err
 	}
 
-	groups, err := azure.GetGroups(session)
-	if err != nil {
-		return resultMap, err
-	}
-
-	wg.Add(len(groups))
-
-	tableConfig, ok := utilities.TableConfigurationMap[sqlServer]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[sqlServer]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": sqlServer,
 		}).Error("failed to get table configuration")
-
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
 
-	for _, group := range groups {
-		go addSqlServer(session, group, &wg, &resultMap, tableConfig)
-	}
-
-	wg.Wait()
-	return resultMap, nil
+	return azure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		addSqlServer(session, rg, collector, tableConfig)
+	})
 }
 
-func addSqlServer(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
-
-	resources, err := getSqlServer(session, rg)
+func addSqlServer(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
+	resources, err := getSqlServer(collector.Context(), session, rg)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName":      sqlServer,
@@ -118,9 +82,8 @@ func addSqlServer(session *azure.AzureSession, rg string, wg *sync.WaitGroup, re
 		}).Error("failed to get server list from api")
 	}
 
-	for _, sqlServer := range *resources.Value {
-		structs.DefaultTagName = "json"
-		resMap := structs.Map(sqlServer)
+	for _, server := range *resources.Value {
+		resMap := structs.Map(server)
 		byteArr, err := json.Marshal(resMap)
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
@@ -132,14 +95,17 @@ func addSqlServer(session *azure.AzureSession, rg string, wg *sync.WaitGroup, re
 		}
 		table := utilities.NewTable(byteArr, tableConfig)
 		for _, row := range table.Rows {
+			if !azure.ShouldProcessRow(collector.Context(), sqlServer, session.SubscriptionId, rg, row) {
+				continue
+			}
 			result := azure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-			*resultMap = append(*resultMap, result)
+			collector.Add(result)
 		}
 	}
 }
 
-func getSqlServer(session *azure.AzureSession, rg string) (result sql.ServerListResult, err error) {
+func getSqlServer(ctx context.Context, session *azure.AzureSession, rg string) (result sql.ServerListResult, err error) {
 	svcClient := sql.NewServersClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
-	return svcClient.ListByResourceGroup(context.Background(), rg)
+	session.ConfigureClient(&svcClient.Client)
+	return svcClient.ListByResourceGroup(ctx, rg)
 }
@@ -13,7 +13,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	"github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2021-04-01/storage"
 	log "github.com/sirupsen/logrus"
@@ -27,72 +26,26 @@ import (
 
 const storageBlobContainer string = "azure_storage_blob_container"
 
-// StorageBlobContainerColumns returns the list of columns in the table
-func StorageBlobContainerColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("etag"),
-		table.TextColumn("id"),
-		table.TextColumn("name"),
-		table.TextColumn("properties"),
-		table.TextColumn("default_encryption_scope"),
-		table.TextColumn("deleted"),
-		table.TextColumn("deleted_time"),
-		table.TextColumn("deny_encryption_scope_override"),
-		table.TextColumn("has_immutability_policy"),
-		table.TextColumn("has_legal_hold"),
-		table.TextColumn("immutability_policy"),
-		// table.TextColumn("immutability_policy_etag"),
-		// table.TextColumn("immutability_policy_update_history"),
-		// table.IntegerColumn("immutability_policy_update_history_immutability_period_since_creation_in_days"),
-		// table.TextColumn("immutability_policy_update_history_object_identifier"),
-		// table.TextColumn("immutability_policy_update_history_tenant_id"),
-		// table.TextColumn("immutability_policy_update_history_timestamp"),
-		// table.TextColumn("immutability_policy_update_history_update"),
-		// table.TextColumn("immutability_policy_update_history_upn"),
-		table.TextColumn("immutable_storage_with_versioning"),
-		// table.TextColumn("immutable_storage_with_versioning_enabled"),
-		// table.TextColumn("immutable_storage_with_versioning_migration_state"),
-		// table.TextColumn("immutable_storage_with_versioning_time_stamp"),
-		table.TextColumn("last_modified_time"),
-		table.TextColumn("lease_duration"),
-		table.TextColumn("lease_state"),
-		table.TextColumn("lease_status"),
-		table.TextColumn("legal_hold"),
-		// table.TextColumn("legal_hold_has_legal_hold"),
-		// table.TextColumn("legal_hold_tags"),
-		// table.TextColumn("legal_hold_tags_object_identifier"),
-		// table.TextColumn("legal_hold_tags_tag"),
-		// table.TextColumn("legal_hold_tags_tenant_id"),
-		// table.TextColumn("legal_hold_tags_timestamp"),
-		// table.TextColumn("legal_hold_tags_upn"),
-		table.TextColumn("metadata"),
-		table.TextColumn("public_access"),
-		table.IntegerColumn("remaining_retention_days"),
-		table.TextColumn("version"),
-		table.TextColumn("type"),
-	}
-}
-
 // StorageBlobContainerGenerate returns the rows in the table for all configured accounts
 func StorageBlobContainerGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageBlobContainer,
 			"account":   "default",
 		}).Info("processing account")
-		results, err := processStorageBlobContainer(nil)
+		results, err := processStorageBlobContainer(osqCtx, nil)
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range azure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": storageBlobContainer,
 				"account":   account.SubscriptionID,
 			}).Info("processing account")
-			results, err := processStorageBlobContainer(&account)
+			results, err := processStorageBlobContainer(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -103,22 +56,14 @@ func StorageBlobContainerGenerate(osqCtx context.Context, queryContext table.Que
 	return resultMap, nil
 }
 
-func processStorageBlobContainer(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processStorageBlobContainer(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
 	session, err := azure.GetAuthSession(account)
-	if err != nil {
-		return resultMap, err
-	}
-	groups, err := azure.GetGroups(session)
-
 	if err != nil {
 		return resultMap, err
 	}
 
-	wg.Add(len(groups))
-
-	tableConfig, ok := utilities.TableConfigurationMap[storageBlobContainer]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[storageBlobContainer]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageBlobContainer,
@@ -126,17 +71,13 @@ func processStorageBlobContainer(account *utilities.ExtensionConfigurationAzureA
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
 
-	for _, group := range groups {
-		go getStorageAccountsForBlobContainer(session, group, &wg, &resultMap, tableConfig)
-	}
-	wg.Wait()
-	return resultMap, nil
+	return azure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		getStorageAccountsForBlobContainer(session, rg, collector, tableConfig)
+	})
 }
 
-func getStorageAccountsForBlobContainer(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
-
-	for resourceItr, err := getStorageAccounts(session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
+func getStorageAccountsForBlobContainer(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
+	for resourceItr, err := getStorageAccounts(collector.Context(), session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName":     storageBlobContainer,
@@ -147,13 +88,13 @@ func getStorageAccountsForBlobContainer(session *azure.AzureSession, rg string,
 		}
 
 		resource := resourceItr.Value()
-		setStorageBlobContainerToTable(session, rg, wg, resultMap, tableConfig, *resource.Name)
+		setStorageBlobContainerToTable(session, rg, collector, tableConfig, *resource.Name)
 	}
 }
 
-func setStorageBlobContainerToTable(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig, accountName string) {
+func setStorageBlobContainerToTable(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig, accountName string) {
 
-	for resourceItr, err := getStorageBlobContainerData(session, rg, accountName); resourceItr.NotDone(); err = resourceItr.Next() {
+	for resourceItr, err := getStorageBlobContainerData(collector.Context(), session, rg, accountName); resourceItr.NotDone(); err = resourceItr.Next() {
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName":     storageBlobContainer,
@@ -164,7 +105,6 @@ func setStorageBlobContainerToTable(session *azure.AzureSession, rg string, wg *
 		}
 
 		resource := resourceItr.Value()
-		structs.DefaultTagName = "json"
 		resMap := structs.Map(resource)
 		byteArr, err := json.Marshal(resMap)
 
@@ -179,14 +119,17 @@ func setStorageBlobContainerToTable(session *azure.AzureSession, rg string, wg *
 
 		table := utilities.NewTable(byteArr, tableConfig)
 		for _, row := range table.Rows {
+			if !azure.ShouldProcessRow(collector.Context(), storageBlobContainer, session.SubscriptionId, rg, row) {
+				continue
+			}
 			result := azure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-			*resultMap = append(*resultMap, result)
+			collector.Add(result)
 		}
 	}
 }
 
-func getStorageBlobContainerData(session *azure.AzureSession, rg string, accountName string) (result storage.ListContainerItemsIterator, err error) {
+func getStorageBlobContainerData(ctx context.Context, session *azure.AzureSession, rg string, accountName string) (result storage.ListContainerItemsIterator, err error) {
 	svcClient := storage.NewBlobContainersClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
-	return svcClient.ListComplete(context.Background(), rg, accountName, "", "", storage.ListContainersIncludeDeleted)
+	session.ConfigureClient(&svcClient.Client)
+	return svcClient.ListComplete(ctx, rg, accountName, "", "", storage.ListContainersIncludeDeleted)
 }
\ No newline at end of file
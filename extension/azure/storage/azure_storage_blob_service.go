@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -18,68 +17,26 @@ import (
 
 const storageBlobService string = "azure_storage_blob_service"
 
-// StorageBlobServiceColumns returns the list of columns in the table
-func StorageBlobServiceColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("id"),
-		table.TextColumn("name"),
-		// table.TextColumn("properties"),
-		table.TextColumn("automatic_snapshot_policy_enabled"),
-		table.TextColumn("change_feed"),
-		// table.TextColumn("change_feed_enabled"),
-		// table.IntegerColumn("change_feed_retention_in_days"),
-		table.TextColumn("container_delete_retention_policy"),
-		// table.IntegerColumn("container_delete_retention_policy_days"),
-		// table.TextColumn("container_delete_retention_policy_enabled"),
-		table.TextColumn("cors"),
-		// table.TextColumn("cors_cors_rules"),
-		// table.TextColumn("cors_cors_rules_allowed_headers"),
-		// table.TextColumn("cors_cors_rules_allowed_methods"),
-		// table.TextColumn("cors_cors_rules_allowed_origins"),
-		// table.TextColumn("cors_cors_rules_exposed_headers"),
-		// table.IntegerColumn("cors_cors_rules_max_age_in_seconds"),
-		table.TextColumn("default_service_version"),
-		table.TextColumn("delete_retention_policy"),
-		// table.IntegerColumn("delete_retention_policy_days"),
-		// table.TextColumn("delete_retention_policy_enabled"),
-		table.TextColumn("is_versioning_enabled"),
-		table.TextColumn("last_access_time_tracking_policy"),
-		// table.TextColumn("last_access_time_tracking_policy_blob_type"),
-		// table.TextColumn("last_access_time_tracking_policy_enable"),
-		// table.TextColumn("last_access_time_tracking_policy_name"),
-		// table.IntegerColumn("last_access_time_tracking_policy_tracking_granularity_in_days"),
-		table.TextColumn("restore_policy"),
-		// table.IntegerColumn("restore_policy_days"),
-		// table.TextColumn("restore_policy_enabled"),
-		// table.TextColumn("restore_policy_last_enabled_time"),
-		// table.TextColumn("restore_policy_min_restore_time"),
-		table.TextColumn("sku"),
-		table.TextColumn("sku_name"),
-		table.TextColumn("sku_tier"),
-		table.TextColumn("type"),
-	}
-}
-
 // StorageBlobServicesGenerate returns the rows in the table for all configured accounts
 func StorageBlobServicesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageBlobService,
 			"account":   "default",
 		}).Info("processing account")
-		results, err := processAccountStorageBlobServices(nil)
+		results, err := processAccountStorageBlobServices(osqCtx, nil)
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range azure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": storageBlobService,
 				"account":   account.SubscriptionID,
 			}).Info("processing account")
-			results, err := processAccountStorageBlobServices(&account)
+			results, err := processAccountStorageBlobServices(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -90,22 +47,14 @@ func StorageBlobServicesGenerate(osqCtx context.Context, queryContext table.Quer
 	return resultMap, nil
 }
 
-func processAccountStorageBlobServices(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processAccountStorageBlobServices(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
 	session, err := azure.GetAuthSession(account)
-	if err != nil {
-		return resultMap, err
-	}
-	groups, err := azure.GetGroups(session)
-
 	if err != nil {
 		return resultMap, err
 	}
 
-	wg.Add(len(groups))
-
-	tableConfig, ok := utilities.TableConfigurationMap[storageBlobService]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[storageBlobService]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageBlobService,
@@ -113,16 +62,12 @@ func processAccountStorageBlobServices(account *utilities.ExtensionConfiguration
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
 
-	for _, group := range groups {
-		go getAccountsForStorageBlobServices(session, group, &wg, &resultMap, tableConfig)
-	}
-	wg.Wait()
-	return resultMap, nil
+	return azure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		getAccountsForStorageBlobServices(session, rg, collector, tableConfig)
+	})
 }
-func getAccountsForStorageBlobServices(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
-
-	for resourceItr, err := getStorageAccounts(session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
+func getAccountsForStorageBlobServices(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
+	for resourceItr, err := getStorageAccounts(collector.Context(), session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName":     storageBlobService,
@@ -133,18 +78,17 @@ func getAccountsForStorageBlobServices(session *azure.AzureSession, rg string, w
 		}
 
 		resource := resourceItr.Value()
-		setStorageBlobServicesToTable(session, rg, wg, resultMap, tableConfig, *resource.Name)
+		setStorageBlobServicesToTable(session, rg, collector, tableConfig, *resource.Name)
 	}
 }
-func setStorageBlobServicesToTable(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig, accountName string) {
+func setStorageBlobServicesToTable(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig, accountName string) {
 
 	Blobservices := make([]storage.BlobServiceProperties, 0)
 
-	getStorageBlobServicesData(session, rg, accountName, &Blobservices)
+	getStorageBlobServicesData(collector.Context(), session, rg, accountName, &Blobservices)
 
 	for _, BlobService := range Blobservices {
 
-		structs.DefaultTagName = "json"
 		resMap := structs.Map(BlobService)
 		byteArr, err := json.Marshal(resMap)
 
@@ -159,18 +103,21 @@ func setStorageBlobServicesToTable(session *azure.AzureSession, rg string, wg *s
 
 		table := utilities.NewTable(byteArr, tableConfig)
 		for _, row := range table.Rows {
+			if !azure.ShouldProcessRow(collector.Context(), storageBlobService, session.SubscriptionId, rg, row) {
+				continue
+			}
 			result := azure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-			*resultMap = append(*resultMap, result)
+			collector.Add(result)
 		}
 	}
 }
 
-func getStorageBlobServicesData(session *azure.AzureSession, rg string, accountName string, BlobService *[]storage.BlobServiceProperties) {
+func getStorageBlobServicesData(ctx context.Context, session *azure.AzureSession, rg string, accountName string, BlobService *[]storage.BlobServiceProperties) {
 
 	svcClient := storage.NewBlobServicesClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
+	session.ConfigureClient(&svcClient.Client)
 
-	resourceItr, err := svcClient.List(context.Background(), rg, accountName)
+	resourceItr, err := svcClient.List(ctx, rg, accountName)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName":     storageBlobService,
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	"github.com/fatih/structs"
 	log "github.com/sirupsen/logrus"
@@ -29,57 +28,26 @@ const (
 	tableService   serviceName = "tableservices"
 )
 
-// StorageDiagnosticSettingsColumns returns the list of columns in the table
-func StorageDiagnosticSettingColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("id"),
-		table.TextColumn("name"),
-		table.TextColumn("storageAccountId"),
-		table.TextColumn("type"),
-		table.TextColumn("properties_log"),
-		table.TextColumn("properties_metrics"),
-		table.TextColumn("event_hub_authorization_rule_id"),
-		table.TextColumn("event_hub_name"),
-		table.TextColumn("log_analytics_destination_type"),
-		table.TextColumn("logs"),
-		// table.TextColumn("logs_category"),
-		// table.TextColumn("logs_enabled"),
-		// table.TextColumn("logs_retention_policy"),
-		// table.IntegerColumn("logs_retention_policy_days"),
-		// table.TextColumn("logs_retention_policy_enabled"),
-		table.TextColumn("metrics"),
-		// table.TextColumn("metrics_category"),
-		// table.TextColumn("metrics_enabled"),
-		// table.TextColumn("metrics_retention_policy"),
-		// table.IntegerColumn("metrics_retention_policy_days"),
-		// table.TextColumn("metrics_retention_policy_enabled"),
-		// table.TextColumn("metrics_time_grain"),
-		table.TextColumn("service_bus_rule_id"),
-		table.TextColumn("storage_account_id"),
-		table.TextColumn("workspace_id"),
-	}
-}
-
 // StorageDiagnosticSettingsGenerate returns the rows in the table for all configured diagnostic settings
 func StorageDiagnosticSettingsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageDiagnosticSetting,
 			"account":   "default",
 		}).Info("processing diagnostic setting")
-		results, err := processStorageDiagnosticSetting(nil)
+		results, err := processStorageDiagnosticSetting(osqCtx, nil)
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range azure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": storageDiagnosticSetting,
 				"account":   account.SubscriptionID,
 			}).Info("processing diagnostic setting")
-			results, err := processStorageDiagnosticSetting(&account)
+			results, err := processStorageDiagnosticSetting(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -90,42 +58,29 @@ func StorageDiagnosticSettingsGenerate(osqCtx context.Context, queryContext tabl
 	return resultMap, nil
 }
 
-func processStorageDiagnosticSetting(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processStorageDiagnosticSetting(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
 	session, err := azure.GetAuthSession(account)
-	if err != nil {
-		return resultMap, err
-	}
-	groups, err := azure.GetGroups(session)
-
 	if err != nil {
 		return resultMap, err
 	}
 
-	wg.Add(len(groups))
-
-	tableConfig, ok := utilities.TableConfigurationMap[storageDiagnosticSetting]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[storageDiagnosticSetting]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageDiagnosticSetting,
-			"error":     err.Error(),
 		}).Error("failed to get table configuration")
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
 
-	for _, group := range groups {
-		go getStorageAccountId(session, group, &wg, &resultMap, tableConfig)
-	}
-	wg.Wait()
-	return resultMap, nil
+	return azure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		getStorageAccountId(session, rg, collector, tableConfig)
+	})
 }
 
-func getStorageAccountId(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
-
+func getStorageAccountId(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
 	diagnosticSettings := make([]diagnostic.DiagnosticSettingsResource, 0)
-	for resourceItr, err := getStorageAccounts(session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
+	for resourceItr, err := getStorageAccounts(collector.Context(), session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName":     storageAccount,
@@ -136,19 +91,18 @@ func getStorageAccountId(session *azure.AzureSession, rg string, wg *sync.WaitGr
 		}
 
 		resource := resourceItr.Value()
-		getStorageDiagnosticSetting(session, rg, *resource.ID, &diagnosticSettings, storageService)
-		getStorageDiagnosticSetting(session, rg, *resource.ID, &diagnosticSettings, fileService)
-		getStorageDiagnosticSetting(session, rg, *resource.ID, &diagnosticSettings, blobService)
-		getStorageDiagnosticSetting(session, rg, *resource.ID, &diagnosticSettings, queueService)
-		getStorageDiagnosticSetting(session, rg, *resource.ID, &diagnosticSettings, tableService)
+		getStorageDiagnosticSetting(collector.Context(), session, rg, *resource.ID, &diagnosticSettings, storageService)
+		getStorageDiagnosticSetting(collector.Context(), session, rg, *resource.ID, &diagnosticSettings, fileService)
+		getStorageDiagnosticSetting(collector.Context(), session, rg, *resource.ID, &diagnosticSettings, blobService)
+		getStorageDiagnosticSetting(collector.Context(), session, rg, *resource.ID, &diagnosticSettings, queueService)
+		getStorageDiagnosticSetting(collector.Context(), session, rg, *resource.ID, &diagnosticSettings, tableService)
 	}
 
-	addStorageDiagnosticSetting(session, rg, resultMap, tableConfig, diagnosticSettings)
+	addStorageDiagnosticSetting(session, rg, collector, tableConfig, diagnosticSettings)
 }
 
-func addStorageDiagnosticSetting(session *azure.AzureSession, rg string, resultMap *[]map[string]string, tableConfig *utilities.TableConfig, diagnosticSettings []diagnostic.DiagnosticSettingsResource) {
+func addStorageDiagnosticSetting(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig, diagnosticSettings []diagnostic.DiagnosticSettingsResource) {
 	for _, diagnosticSetting := range diagnosticSettings {
-		structs.DefaultTagName = "json"
 		resMap := structs.Map(diagnosticSetting)
 		byteArr, err := json.Marshal(resMap)
 		if err != nil {
@@ -162,15 +116,18 @@ func addStorageDiagnosticSetting(session *azure.AzureSession, rg string, resultM
 
 		table := utilities.NewTable(byteArr, tableConfig)
 		for _, row := range table.Rows {
+			if !azure.ShouldProcessRow(collector.Context(), storageDiagnosticSetting, session.SubscriptionId, rg, row) {
+				continue
+			}
 			result := azure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-			*resultMap = append(*resultMap, result)
+			collector.Add(result)
 		}
 	}
 }
 
-func getStorageDiagnosticSetting(session *azure.AzureSession, rg string, resourceURI string, diagnosticSettings *[]diagnostic.DiagnosticSettingsResource, serviceNameString serviceName) {
+func getStorageDiagnosticSetting(ctx context.Context, session *azure.AzureSession, rg string, resourceURI string, diagnosticSettings *[]diagnostic.DiagnosticSettingsResource, serviceNameString serviceName) {
 	svcClient := diagnostic.NewDiagnosticSettingsClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
+	session.ConfigureClient(&svcClient.Client)
 
 	if serviceNameString != storageService {
 		resourceURI += "/" + string(serviceNameString) + "/deafult"
@@ -182,7 +139,7 @@ func getStorageDiagnosticSetting(session *azure.AzureSession, rg string, resourc
 		"resourceURI":   resourceURI,
 	}).Info("Getting data from")
 
-	returnObj, err := svcClient.List(context.Background(), resourceURI)
+	returnObj, err := svcClient.List(ctx, resourceURI)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName":     storageDiagnosticSetting,
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -19,57 +18,26 @@ import (
 
 const storageFileService string = "azure_storage_file_service"
 
-// StorageFileServiceColumns returns the list of columns in the table
-func StorageFileServiceColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("id"),
-		table.TextColumn("name"),
-		// table.TextColumn("properties"),
-		table.TextColumn("cors"),
-		// table.TextColumn("cors_cors_rules"),
-		// table.TextColumn("cors_cors_rules_allowed_headers"),
-		// table.TextColumn("cors_cors_rules_allowed_methods"),
-		// table.TextColumn("cors_cors_rules_allowed_origins"),
-		// table.TextColumn("cors_cors_rules_exposed_headers"),
-		// table.IntegerColumn("cors_cors_rules_max_age_in_seconds"),
-		table.TextColumn("protocol_settings"),
-		// table.TextColumn("protocol_settings_smb"),
-		// table.TextColumn("protocol_settings_smb_authentication_methods"),
-		// table.TextColumn("protocol_settings_smb_channel_encryption"),
-		// table.TextColumn("protocol_settings_smb_kerberos_ticket_encryption"),
-		// table.TextColumn("protocol_settings_smb_multichannel"),
-		// table.TextColumn("protocol_settings_smb_multichannel_enabled"),
-		// table.TextColumn("protocol_settings_smb_versions"),
-		table.TextColumn("share_delete_retention_policy"),
-		// table.IntegerColumn("share_delete_retention_policy_days"),
-		// table.TextColumn("share_delete_retention_policy_enabled"),
-		table.TextColumn("sku"),
-		table.TextColumn("sku_name"),
-		table.TextColumn("sku_tier"),
-		table.TextColumn("type"),
-	}
-}
-
 // StorageFileServicesGenerate returns the rows in the table for all configured accounts
 func StorageFileServicesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageFileService,
 			"account":   "default",
 		}).Info("processing account")
-		results, err := processAccountStorageFileServices(nil)
+		results, err := processAccountStorageFileServices(osqCtx, nil)
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range azure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": storageFileService,
 				"account":   account.SubscriptionID,
 			}).Info("processing account")
-			results, err := processAccountStorageFileServices(&account)
+			results, err := processAccountStorageFileServices(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -80,22 +48,14 @@ func StorageFileServicesGenerate(osqCtx context.Context, queryContext table.Quer
 	return resultMap, nil
 }
 
-func processAccountStorageFileServices(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processAccountStorageFileServices(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
 	session, err := azure.GetAuthSession(account)
-	if err != nil {
-		return resultMap, err
-	}
-	groups, err := azure.GetGroups(session)
-
 	if err != nil {
 		return resultMap, err
 	}
 
-	wg.Add(len(groups))
-
-	tableConfig, ok := utilities.TableConfigurationMap[storageFileService]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[storageFileService]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageFileService,
@@ -103,17 +63,13 @@ func processAccountStorageFileServices(account *utilities.ExtensionConfiguration
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
 
-	for _, group := range groups {
-		go getAccountsForStorageFileServices(session, group, &wg, &resultMap, tableConfig)
-	}
-	wg.Wait()
-	return resultMap, nil
+	return azure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		getAccountsForStorageFileServices(session, rg, collector, tableConfig)
+	})
 }
 
-func getAccountsForStorageFileServices(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
-
-	for resourceItr, err := getStorageAccounts(session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
+func getAccountsForStorageFileServices(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
+	for resourceItr, err := getStorageAccounts(collector.Context(), session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName":     storageFileService,
@@ -124,18 +80,17 @@ func getAccountsForStorageFileServices(session *azure.AzureSession, rg string, w
 		}
 
 		resource := resourceItr.Value()
-		setStorageFileServicesToTable(session, rg, wg, resultMap, tableConfig, *resource.Name)
+		setStorageFileServicesToTable(session, rg, collector, tableConfig, *resource.Name)
 	}
 }
-func setStorageFileServicesToTable(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig, accountName string) {
+func setStorageFileServicesToTable(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig, accountName string) {
 
 	Fileservices := make([]storage.FileServiceProperties, 0)
 
-	getStorageFileServicesData(session, rg, accountName, &Fileservices)
+	getStorageFileServicesData(collector.Context(), session, rg, accountName, &Fileservices)
 
 	for _, Fileservice := range Fileservices {
 
-		structs.DefaultTagName = "json"
 		resMap := structs.Map(Fileservice)
 		byteArr, err := json.Marshal(resMap)
 
@@ -150,18 +105,21 @@ func setStorageFileServicesToTable(session *azure.AzureSession, rg string, wg *s
 
 		table := utilities.NewTable(byteArr, tableConfig)
 		for _, row := range table.Rows {
+			if !azure.ShouldProcessRow(collector.Context(), storageFileService, session.SubscriptionId, rg, row) {
+				continue
+			}
 			result := azure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-			*resultMap = append(*resultMap, result)
+			collector.Add(result)
 		}
 	}
 }
 
-func getStorageFileServicesData(session *azure.AzureSession, rg string, accountName string, Fileservice *[]storage.FileServiceProperties) {
+func getStorageFileServicesData(ctx context.Context, session *azure.AzureSession, rg string, accountName string, Fileservice *[]storage.FileServiceProperties) {
 
 	svcClient := storage.NewFileServicesClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
+	session.ConfigureClient(&svcClient.Client)
 
-	resourceItr, err := svcClient.List(context.Background(), rg, accountName)
+	resourceItr, err := svcClient.List(ctx, rg, accountName)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName":     storageFileService,
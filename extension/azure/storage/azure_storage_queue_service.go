@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -19,43 +18,26 @@ import (
 
 const storageQueueService string = "azure_storage_queue_service"
 
-// StorageQueueServicesColumns returns the list of columns in the table
-func StorageQueueServicesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("id"),
-		table.TextColumn("name"),
-		// table.TextColumn("properties"),
-		table.TextColumn("cors"),
-		// table.TextColumn("cors_cors_rules"),
-		// table.TextColumn("cors_cors_rules_allowed_headers"),
-		// table.TextColumn("cors_cors_rules_allowed_methods"),
-		// table.TextColumn("cors_cors_rules_allowed_origins"),
-		// table.TextColumn("cors_cors_rules_exposed_headers"),
-		// table.TextColumn("cors_cors_rules_max_age_in_seconds"),
-		table.TextColumn("type"),
-	}
-}
-
 // StorageQueueServicesGenerate returns the rows in the table for all configured accounts
 func StorageQueueServicesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageQueueService,
 			"account":   "default",
 		}).Info("processing account")
-		results, err := processAccountStorageQueueServices(nil)
+		results, err := processAccountStorageQueueServices(osqCtx, nil)
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range azure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": storageQueueService,
 				"account":   account.SubscriptionID,
 			}).Info("processing account")
-			results, err := processAccountStorageQueueServices(&account)
+			results, err := processAccountStorageQueueServices(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -66,22 +48,14 @@ func StorageQueueServicesGenerate(osqCtx context.Context, queryContext table.Que
 	return resultMap, nil
 }
 
-func processAccountStorageQueueServices(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processAccountStorageQueueServices(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
 	session, err := azure.GetAuthSession(account)
-	if err != nil {
-		return resultMap, err
-	}
-	groups, err := azure.GetGroups(session)
-
 	if err != nil {
 		return resultMap, err
 	}
 
-	wg.Add(len(groups))
-
-	tableConfig, ok := utilities.TableConfigurationMap[storageQueueService]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[storageQueueService]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageQueueService,
@@ -89,16 +63,12 @@ func processAccountStorageQueueServices(account *utilities.ExtensionConfiguratio
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
 
-	for _, group := range groups {
-		go getStorageAccountsForStorageQueueServices(session, group, &wg, &resultMap, tableConfig)
-	}
-	wg.Wait()
-	return resultMap, nil
+	return azure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		getStorageAccountsForStorageQueueServices(session, rg, collector, tableConfig)
+	})
 }
-func getStorageAccountsForStorageQueueServices(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
-
-	for resourceItr, err := getStorageAccounts(session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
+func getStorageAccountsForStorageQueueServices(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
+	for resourceItr, err := getStorageAccounts(collector.Context(), session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName":     storageQueueService,
@@ -109,12 +79,12 @@ func getStorageAccountsForStorageQueueServices(session *azure.AzureSession, rg s
 		}
 
 		resource := resourceItr.Value()
-		setStorageQueueServicesToTable(session, rg, wg, resultMap, tableConfig, *resource.Name)
+		setStorageQueueServicesToTable(session, rg, collector, tableConfig, *resource.Name)
 	}
 }
-func setStorageQueueServicesToTable(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig, accountName string) {
+func setStorageQueueServicesToTable(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig, accountName string) {
 
-	resource, err := getStorageQueueServicesData(session, rg, accountName)
+	resource, err := getStorageQueueServicesData(collector.Context(), session, rg, accountName)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName":     storageQueueService,
@@ -125,7 +95,6 @@ func setStorageQueueServicesToTable(session *azure.AzureSession, rg string, wg *
 
 	for _, Queueservice := range *resource.Value {
 
-		structs.DefaultTagName = "json"
 		resMap := structs.Map(Queueservice)
 		byteArr, err := json.Marshal(resMap)
 
@@ -140,16 +109,19 @@ func setStorageQueueServicesToTable(session *azure.AzureSession, rg string, wg *
 
 		table := utilities.NewTable(byteArr, tableConfig)
 		for _, row := range table.Rows {
+			if !azure.ShouldProcessRow(collector.Context(), storageQueueService, session.SubscriptionId, rg, row) {
+				continue
+			}
 			result := azure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-			*resultMap = append(*resultMap, result)
+			collector.Add(result)
 		}
 	}
 }
 
-func getStorageQueueServicesData(session *azure.AzureSession, rg string, accountName string) (result storage.ListQueueServices, err error) {
+func getStorageQueueServicesData(ctx context.Context, session *azure.AzureSession, rg string, accountName string) (result storage.ListQueueServices, err error) {
 
 	svcClient := storage.NewQueueServicesClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
-	return svcClient.List(context.Background(), rg, accountName)
+	session.ConfigureClient(&svcClient.Client)
+	return svcClient.List(ctx, rg, accountName)
 
 }
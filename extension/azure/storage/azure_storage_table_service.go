@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -19,43 +18,26 @@ import (
 
 const storageTableService string = "azure_storage_table_service"
 
-// StorageTableServicesColumns returns the list of columns in the table
-func StorageTableServicesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("id"),
-		table.TextColumn("name"),
-		// table.TextColumn("properties"),
-		table.TextColumn("cors"),
-		// table.TextColumn("cors_cors_rules"),
-		// table.TextColumn("cors_cors_rules_allowed_headers"),
-		// table.TextColumn("cors_cors_rules_allowed_methods"),
-		// table.TextColumn("cors_cors_rules_allowed_origins"),
-		// table.TextColumn("cors_cors_rules_exposed_headers"),
-		// table.TextColumn("cors_cors_rules_max_age_in_seconds"),
-		table.TextColumn("type"),
-	}
-}
-
 // StorageTableServicesGenerate returns the rows in the table for all configured accounts
 func StorageTableServicesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	if len(utilities.ExtConfiguration.ExtConfAzure.Accounts) == 0 {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfAzure.Accounts) == 0 {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageTableService,
 			"account":   "default",
 		}).Info("processing account")
-		results, err := processAccountStorageTableServices(nil)
+		results, err := processAccountStorageTableServices(osqCtx, nil)
 		if err != nil {
 			return resultMap, err
 		}
 		resultMap = append(resultMap, results...)
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfAzure.Accounts {
+		for _, account := range azure.GetAccounts(osqCtx) {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName": storageTableService,
 				"account":   account.SubscriptionID,
 			}).Info("processing account")
-			results, err := processAccountStorageTableServices(&account)
+			results, err := processAccountStorageTableServices(osqCtx, &account)
 			if err != nil {
 				continue
 			}
@@ -66,22 +48,14 @@ func StorageTableServicesGenerate(osqCtx context.Context, queryContext table.Que
 	return resultMap, nil
 }
 
-func processAccountStorageTableServices(account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
+func processAccountStorageTableServices(osqCtx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]map[string]string, error) {
 	resultMap := make([]map[string]string, 0)
-	var wg sync.WaitGroup
 	session, err := azure.GetAuthSession(account)
-	if err != nil {
-		return resultMap, err
-	}
-	groups, err := azure.GetGroups(session)
-
 	if err != nil {
 		return resultMap, err
 	}
 
-	wg.Add(len(groups))
-
-	tableConfig, ok := utilities.TableConfigurationMap[storageTableService]
+	tableConfig, ok := utilities.GetConfiguration(osqCtx).Tables[storageTableService]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": storageTableService,
@@ -89,17 +63,13 @@ func processAccountStorageTableServices(account *utilities.ExtensionConfiguratio
 		return resultMap, fmt.Errorf("table configuration not found")
 	}
 
-	for _, group := range groups {
-		go getAccountsForStorageTableServices(session, group, &wg, &resultMap, tableConfig)
-	}
-	wg.Wait()
-	return resultMap, nil
+	return azure.ProcessResourceGroups(osqCtx, session, func(collector *utilities.RowCollector, rg string) {
+		getAccountsForStorageTableServices(session, rg, collector, tableConfig)
+	})
 }
 
-func getAccountsForStorageTableServices(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig) {
-	defer wg.Done()
-
-	for resourceItr, err := getStorageAccounts(session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
+func getAccountsForStorageTableServices(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig) {
+	for resourceItr, err := getStorageAccounts(collector.Context(), session, rg); resourceItr.NotDone(); err = resourceItr.Next() {
 		if err != nil {
 			utilities.GetLogger().WithFields(log.Fields{
 				"tableName":     storageTableService,
@@ -110,12 +80,12 @@ func getAccountsForStorageTableServices(session *azure.AzureSession, rg string,
 		}
 
 		resource := resourceItr.Value()
-		setStorageTableServicesToTable(session, rg, wg, resultMap, tableConfig, *resource.Name)
+		setStorageTableServicesToTable(session, rg, collector, tableConfig, *resource.Name)
 	}
 }
-func setStorageTableServicesToTable(session *azure.AzureSession, rg string, wg *sync.WaitGroup, resultMap *[]map[string]string, tableConfig *utilities.TableConfig, accountName string) {
+func setStorageTableServicesToTable(session *azure.AzureSession, rg string, collector *utilities.RowCollector, tableConfig *utilities.TableConfig, accountName string) {
 
-	resource, err := getStorageTableServicesData(session, rg, accountName)
+	resource, err := getStorageTableServicesData(collector.Context(), session, rg, accountName)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName":     storageTableService,
@@ -126,7 +96,6 @@ func setStorageTableServicesToTable(session *azure.AzureSession, rg string, wg *
 
 	for _, Tableservice := range *resource.Value {
 
-		structs.DefaultTagName = "json"
 		resMap := structs.Map(Tableservice)
 		byteArr, err := json.Marshal(resMap)
 
@@ -141,17 +110,20 @@ func setStorageTableServicesToTable(session *azure.AzureSession, rg string, wg *
 
 		table := utilities.NewTable(byteArr, tableConfig)
 		for _, row := range table.Rows {
+			if !azure.ShouldProcessRow(collector.Context(), storageTableService, session.SubscriptionId, rg, row) {
+				continue
+			}
 			result := azure.RowToMap(row, session.SubscriptionId, "", rg, tableConfig)
-			*resultMap = append(*resultMap, result)
+			collector.Add(result)
 		}
 	}
 }
 
-func getStorageTableServicesData(session *azure.AzureSession, rg string, accountName string) (result storage.ListTableServices, err error) {
+func getStorageTableServicesData(ctx context.Context, session *azure.AzureSession, rg string, accountName string) (result storage.ListTableServices, err error) {
 
 	svcClient := storage.NewTableServicesClient(session.SubscriptionId)
-	svcClient.Authorizer = session.Authorizer
+	session.ConfigureClient(&svcClient.Client)
 
-	return svcClient.List(context.Background(), rg, accountName)
+	return svcClient.List(ctx, rg, accountName)
 
 }
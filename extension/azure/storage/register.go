@@ -0,0 +1,32 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package storage
+
+import (
+	_ "embed"
+
+	"github.com/Uptycs/cloudquery/utilities"
+)
+
+//go:embed table_config.json
+var defaultTableConfig []byte
+
+func init() {
+	utilities.RegisterTableConfigFile("azure/storage/table_config.json", defaultTableConfig)
+
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_storage_account", Generate: StorageAccountsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_storage_blob_container", Generate: StorageBlobContainerGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_storage_diagnostic_setting", Generate: StorageDiagnosticSettingsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_storage_file_service", Generate: StorageFileServicesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_storage_blob_service", Generate: StorageBlobServicesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_storage_queue_service", Generate: StorageQueueServicesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_storage_table_service", Generate: StorageTableServicesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "azure_storage_blob", Generate: StorageBlobGenerate})
+}
@@ -0,0 +1,155 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package azure
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2021-01-01/subscriptions"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/Uptycs/cloudquery/utilities"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultDiscoveryInterval = 3600 // seconds
+
+// discoveredSubscriptions holds the subscriptions discovered for a credential
+type discoveredSubscriptions struct {
+	accounts  []utilities.ExtensionConfigurationAzureAccount
+	refreshed time.Time
+}
+
+var (
+	subscriptionMutex sync.Mutex
+	// subscriptionCache is credential key => discovered subscriptions
+	subscriptionCache = make(map[string]*discoveredSubscriptions)
+	timeNow           = time.Now
+)
+
+// GetAccounts returns the configured Azure accounts followed by the subscriptions discovered for the
+// accounts with discoverSubscriptions set. Subscriptions are listed again when the discovery interval
+// has passed. If listing fails, previously discovered subscriptions are returned.
+// Subscriptions which are configured explicitly are not duplicated
+func GetAccounts(ctx context.Context) []utilities.ExtensionConfigurationAzureAccount {
+	configured := utilities.GetConfiguration(ctx).Extension.ExtConfAzure.Accounts
+	accounts := make([]utilities.ExtensionConfigurationAzureAccount, 0, len(configured))
+	subscriptionIds := make(map[string]bool)
+	for _, account := range configured {
+		if !account.DiscoverSubscriptions {
+			accounts = append(accounts, account)
+			subscriptionIds[account.SubscriptionID] = true
+		}
+	}
+	for idx := range configured {
+		if !configured[idx].DiscoverSubscriptions {
+			continue
+		}
+		for _, subscription := range getDiscoveredSubscriptions(ctx, &configured[idx]) {
+			if subscriptionIds[subscription.SubscriptionID] {
+				continue
+			}
+			subscriptionIds[subscription.SubscriptionID] = true
+			accounts = append(accounts, subscription)
+		}
+	}
+	return accounts
+}
+
+// InvalidateDiscoveredSubscriptions forgets the discovered subscriptions, e.g. after extension configuration has changed.
+// Subscriptions are listed again when they are needed
+func InvalidateDiscoveredSubscriptions() {
+	subscriptionMutex.Lock()
+	defer subscriptionMutex.Unlock()
+	subscriptionCache = make(map[string]*discoveredSubscriptions)
+}
+
+func getDiscoveredSubscriptions(ctx context.Context, account *utilities.ExtensionConfigurationAzureAccount) []utilities.ExtensionConfigurationAzureAccount {
+	subscriptionMutex.Lock()
+	defer subscriptionMutex.Unlock()
+
+	interval := account.DiscoveryInterval
+	if interval <= 0 {
+		interval = defaultDiscoveryInterval
+	}
+	key := getCredentialKey(account)
+	discovered, found := subscriptionCache[key]
+	if found && timeNow().Sub(discovered.refreshed) < time.Duration(interval)*time.Second {
+		return discovered.accounts
+	}
+
+	accounts, err := discoverSubscriptions(ctx, account)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tenantId":  account.TenantID,
+			"task":      "ListSubscriptions",
+			"errString": err.Error(),
+		}).Error("failed to discover subscriptions")
+		if found {
+			// Retry after the interval rather than on every query
+			discovered.refreshed = timeNow()
+			return discovered.accounts
+		}
+		subscriptionCache[key] = &discoveredSubscriptions{refreshed: timeNow()}
+		return nil
+	}
+	utilities.GetLogger().WithFields(log.Fields{
+		"tenantId":      account.TenantID,
+		"subscriptions": len(accounts),
+	}).Info("discovered subscriptions")
+	subscriptionCache[key] = &discoveredSubscriptions{accounts: accounts, refreshed: timeNow()}
+	return accounts
+}
+
+// discoverSubscriptions lists the Enabled subscriptions visible to the credentials of given account
+func discoverSubscriptions(ctx context.Context, account *utilities.ExtensionConfigurationAzureAccount) ([]utilities.ExtensionConfigurationAzureAccount, error) {
+	session, err := GetAuthSession(account)
+	if err != nil {
+		return nil, err
+	}
+	client := subscriptions.NewClient()
+	session.ConfigureClient(&client.Client)
+
+	accounts := make([]utilities.ExtensionConfigurationAzureAccount, 0)
+	list, err := client.ListComplete(ctx)
+	for ; err == nil && list.NotDone(); err = list.NextWithContext(ctx) {
+		subscription := list.Value()
+		if subscription.State != subscriptions.StateEnabled {
+			continue
+		}
+		subscriptionId, name := to.String(subscription.SubscriptionID), to.String(subscription.DisplayName)
+		if matchesAnyPattern(account.ExcludeSubscriptions, subscriptionId, name) ||
+			(len(account.IncludeSubscriptions) > 0 && !matchesAnyPattern(account.IncludeSubscriptions, subscriptionId, name)) {
+			continue
+		}
+		member := *account
+		member.SubscriptionID = subscriptionId
+		member.DiscoverSubscriptions = false
+		accounts = append(accounts, member)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// matchesAnyPattern returns true if any of given values matches one of the glob patterns
+func matchesAnyPattern(patterns []string, values ...string) bool {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if matched, _ := path.Match(pattern, value); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
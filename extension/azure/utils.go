@@ -13,26 +13,49 @@ import (
 	"context"
 	"encoding/json"
 	"io/ioutil"
+	"net/http"
 	"os"
-	"sync"
 
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2018-02-01/resources"
 	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/fatih/structs"
 	"github.com/pkg/errors"
 )
 
+const defaultMaxWorkers = 8
+
 // AzureSession is an object representing session for subscription
 type AzureSession struct {
 	SubscriptionId string
 	Authorizer     autorest.Authorizer
+	// Sender overrides the HTTP sender of the clients created for this session. Used by tests.
+	Sender autorest.Sender
 }
 
-var (
-	authGeneratorMutex sync.Mutex
-)
+func init() {
+	// Set once here. Tables used to set it per resource group which is a data race
+	structs.DefaultTagName = "json"
+}
+
+// ConfigureClient sets the authorizer (and sender, if any) of given session on an API client
+func (session *AzureSession) ConfigureClient(client *autorest.Client) {
+	client.Authorizer = session.Authorizer
+	if session.Sender != nil {
+		client.Sender = session.Sender
+	}
+}
+
+func getMaxWorkers() int {
+	maxWorkers := utilities.CurrentConfiguration().Extension.ExtConfAzure.Concurrency.MaxWorkers
+	if maxWorkers <= 0 {
+		return defaultMaxWorkers
+	}
+	return maxWorkers
+}
 
 func readJSON(path string) (*map[string]interface{}, error) {
 	data, err := ioutil.ReadFile(path)
@@ -51,32 +74,123 @@ func readJSON(path string) (*map[string]interface{}, error) {
 	return &contents, err
 }
 
-// GetAuthSession creates an authorizer for the given account
-// If account is nil, it creates an authorizer for the default account,
-// by locating the auth file by reading "AZURE_AUTH_LOCATION" env variable
+// GetAuthSession creates a session for given account.
+// If account is nil, credentials and subscription are read from the auth file
+// located by "AZURE_AUTH_LOCATION" env variable.
+// Authorizers are created once per credentials and shared by all subscriptions using them.
 func GetAuthSession(account *utilities.ExtensionConfigurationAzureAccount) (*AzureSession, error) {
-	authGeneratorMutex.Lock()
-	defer authGeneratorMutex.Unlock()
-
-	if account != nil {
-		os.Setenv("AZURE_AUTH_LOCATION", account.AuthFile)
+	if account == nil {
+		account = &utilities.ExtensionConfigurationAzureAccount{AuthFile: os.Getenv("AZURE_AUTH_LOCATION")}
 	}
-	authorizer, err := auth.NewAuthorizerFromFile(azure.PublicCloud.ResourceManagerEndpoint)
-	if err != nil {
-		return nil, errors.Wrap(err, "Can't initialize authorizer")
+	subscriptionId := account.SubscriptionID
+	if subscriptionId == "" && account.AuthFile != "" {
+		authInfo, err := readJSON(account.AuthFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't get authinfo")
+		}
+		subscriptionId, _ = (*authInfo)["subscriptionId"].(string)
 	}
-	authInfo, err := readJSON(os.Getenv("AZURE_AUTH_LOCATION"))
+	authorizer, err := getAuthorizer(account)
 	if err != nil {
-		return nil, errors.Wrap(err, "Can't get authinfo")
+		return nil, errors.Wrap(err, "Can't initialize authorizer")
 	}
 	session := AzureSession{
-		SubscriptionId: (*authInfo)["subscriptionId"].(string),
+		SubscriptionId: subscriptionId,
 		Authorizer:     authorizer,
 	}
+	if transport := utilities.GetHTTPTransport(); transport != nil {
+		session.Sender = &http.Client{Transport: transport}
+	}
 
 	return &session, nil
 }
 
+// getCredentialKey identifies the credentials of given account
+func getCredentialKey(account *utilities.ExtensionConfigurationAzureAccount) string {
+	switch {
+	case account.UseEnvironment:
+		return "env"
+	case account.ClientID != "" && account.CertificatePath != "":
+		return "certificate:" + account.TenantID + "/" + account.ClientID + "/" + account.CertificatePath
+	case account.ClientID != "":
+		return "secret:" + account.TenantID + "/" + account.ClientID
+	}
+	return "file:" + account.AuthFile
+}
+
+// getAuthorizer returns the authorizer cached for the credentials of given account (see utilities.GetCachedSession)
+func getAuthorizer(account *utilities.ExtensionConfigurationAzureAccount) (autorest.Authorizer, error) {
+	key := utilities.GetSessionCacheKey(utilities.ProviderAzure, getCredentialKey(account))
+	authorizer, err := utilities.GetCachedSession(utilities.ProviderAzure, key, func() (interface{}, error) {
+		return newAuthorizer(account)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return authorizer.(autorest.Authorizer), nil
+}
+
+func newAuthorizer(account *utilities.ExtensionConfigurationAzureAccount) (autorest.Authorizer, error) {
+	switch {
+	case account.UseEnvironment:
+		return auth.NewAuthorizerFromEnvironment()
+	case account.ClientID != "" && account.CertificatePath != "":
+		return newBearerAuthorizer(auth.NewClientCertificateConfig(account.CertificatePath, account.CertificatePassword, account.ClientID, account.TenantID).ServicePrincipalToken())
+	case account.ClientID != "":
+		return newBearerAuthorizer(auth.NewClientCredentialsConfig(account.ClientID, account.ClientSecret, account.TenantID).ServicePrincipalToken())
+	case account.AuthFile != "":
+		return getFileAuthorizer(account.AuthFile)
+	}
+	return nil, errors.New("no credentials configured")
+}
+
+// getFileAuthorizer creates an authorizer from the client secret or certificate in given SDK auth file
+// (e.g. created by "az ad sp create-for-rbac --sdk-auth")
+func getFileAuthorizer(path string) (autorest.Authorizer, error) {
+	authInfo, err := readJSON(path)
+	if err != nil {
+		return nil, err
+	}
+	getSetting := func(name string) string {
+		value, _ := (*authInfo)[name].(string)
+		return value
+	}
+	aadEndpoint := getSetting("activeDirectoryEndpointUrl")
+	if aadEndpoint == "" {
+		aadEndpoint = azure.PublicCloud.ActiveDirectoryEndpoint
+	}
+	resource := getSetting("resourceManagerEndpointUrl")
+	if resource == "" {
+		resource = azure.PublicCloud.ResourceManagerEndpoint
+	}
+	if getSetting("clientSecret") != "" {
+		config := auth.NewClientCredentialsConfig(getSetting("clientId"), getSetting("clientSecret"), getSetting("tenantId"))
+		config.AADEndpoint = aadEndpoint
+		config.Resource = resource
+		return newBearerAuthorizer(config.ServicePrincipalToken())
+	}
+	if getSetting("clientCertificate") != "" {
+		config := auth.NewClientCertificateConfig(getSetting("clientCertificate"), getSetting("clientCertificatePassword"),
+			getSetting("clientId"), getSetting("tenantId"))
+		config.AADEndpoint = aadEndpoint
+		config.Resource = resource
+		return newBearerAuthorizer(config.ServicePrincipalToken())
+	}
+	return nil, errors.New("auth file has neither clientSecret nor clientCertificate")
+}
+
+// newBearerAuthorizer creates an authorizer using token. If a transport is set with utilities.SetHTTPTransport,
+// token requests are sent through it
+func newBearerAuthorizer(token *adal.ServicePrincipalToken, err error) (autorest.Authorizer, error) {
+	if err != nil {
+		return nil, err
+	}
+	if transport := utilities.GetHTTPTransport(); transport != nil {
+		token.SetSender(&http.Client{Transport: transport})
+	}
+	return autorest.NewBearerAuthorizer(token), nil
+}
+
 // RowToMap converts JSON row into osquery row.
 // If configured it will copy some metadata vaues into appropriate columns
 func RowToMap(row map[string]interface{}, subscriptionId string, tenantId string, resourceGroup string, tableConfig *utilities.TableConfig) map[string]string {
@@ -96,14 +210,14 @@ func RowToMap(row map[string]interface{}, subscriptionId string, tenantId string
 }
 
 // GetGroups returns the list of resource groups for given azure session
-func GetGroups(session *AzureSession) ([]string, error) {
+func GetGroups(ctx context.Context, session *AzureSession) ([]string, error) {
 	tab := make([]string, 0)
 	var err error
 
 	grClient := resources.NewGroupsClient(session.SubscriptionId)
-	grClient.Authorizer = session.Authorizer
+	session.ConfigureClient(&grClient.Client)
 
-	for list, err := grClient.ListComplete(context.Background(), "", nil); list.NotDone(); err = list.Next() {
+	for list, err := grClient.ListComplete(ctx, "", nil); list.NotDone(); err = list.Next() {
 		if err != nil {
 			return nil, errors.Wrap(err, "error traverising resource group list")
 		}
@@ -112,3 +226,21 @@ func GetGroups(session *AzureSession) ([]string, error) {
 	}
 	return tab, err
 }
+
+// ProcessResourceGroups calls processGroup for every resource group of the session's subscription
+// and returns the rows added to the collector. Resource groups are processed in parallel by at most
+// "maxWorkers" workers and are skipped once osqCtx is cancelled.
+func ProcessResourceGroups(osqCtx context.Context, session *AzureSession, processGroup func(collector *utilities.RowCollector, rg string)) ([]map[string]string, error) {
+	groups, err := GetGroups(osqCtx, session)
+	if err != nil {
+		return make([]map[string]string, 0), err
+	}
+	collector := utilities.NewRowCollector(osqCtx, getMaxWorkers())
+	for _, group := range groups {
+		rg := group
+		collector.Go(func() {
+			processGroup(collector, rg)
+		})
+	}
+	return collector.Wait(), nil
+}
@@ -10,10 +10,22 @@
 package azure
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2018-02-01/resources"
+	"github.com/Azure/go-autorest/autorest"
 	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/fatih/structs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,6 +39,22 @@ var tableConfigJSON = `
 			"tenantIdAttribute": "abc"
 		},
     	"parsedAttributes": []
+	},
+	"test_table_2": {
+		"aws": {},
+		"gcp": {},
+		"azure": {
+			"subscriptionIdAttribute": "subscription_id",
+			"resourceGroupAttribute": "resource_group"
+		},
+		"parsedAttributes": [
+			{
+				"sourceName": "name",
+				"targetName": "name",
+				"targetType": "TEXT",
+				"enabled": true
+			}
+		]
 	}
 }`
 
@@ -41,9 +69,170 @@ func TestRowToMap(t *testing.T) {
 
 	subID, tenantID, rscGroup := "test-account", "us-east4", ""
 	inRow := make(map[string]interface{})
-	tabConfig := utilities.TableConfigurationMap["test_table_1"]
+	tabConfig := utilities.CurrentConfiguration().Tables["test_table_1"]
 	outRow := RowToMap(inRow, subID, tenantID, rscGroup, tabConfig)
 
 	assert.Equal(t, subID, outRow["subscription_id"])
 	assert.Equal(t, tenantID, outRow["abc"])
 }
+
+// fakeResourceGroupsSender serves resource group list and get requests without a network
+func fakeResourceGroupsSender(groupCount int) autorest.Sender {
+	return autorest.SenderFunc(func(req *http.Request) (*http.Response, error) {
+		var body []byte
+		if strings.HasSuffix(req.URL.Path, "/resourcegroups") {
+			groups := make([]map[string]string, 0)
+			for i := 0; i < groupCount; i++ {
+				groups = append(groups, map[string]string{"name": fmt.Sprintf("rg-%d", i)})
+			}
+			body, _ = json.Marshal(map[string]interface{}{"value": groups})
+		} else {
+			name := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+			body, _ = json.Marshal(map[string]string{"name": name})
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(bytes.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+}
+
+func TestProcessResourceGroups(t *testing.T) {
+	err := utilities.ReadTableConfig([]byte(tableConfigJSON))
+	assert.Nil(t, err)
+	tableConfig := utilities.CurrentConfiguration().Tables["test_table_2"]
+
+	groupCount := 200
+	session := &AzureSession{
+		SubscriptionId: "test-subscription",
+		Authorizer:     autorest.NullAuthorizer{},
+		Sender:         fakeResourceGroupsSender(groupCount),
+	}
+	rows, err := ProcessResourceGroups(context.Background(), session, func(collector *utilities.RowCollector, rg string) {
+		client := resources.NewGroupsClient(session.SubscriptionId)
+		session.ConfigureClient(&client.Client)
+		group, err := client.Get(collector.Context(), rg)
+		if !assert.Nil(t, err) {
+			return
+		}
+		byteArr, err := json.Marshal(structs.Map(group))
+		if !assert.Nil(t, err) {
+			return
+		}
+		for _, row := range utilities.NewTable(byteArr, tableConfig).Rows {
+			collector.Add(RowToMap(row, session.SubscriptionId, "", rg, tableConfig))
+		}
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, groupCount, len(rows))
+
+	names := make(map[string]bool)
+	for _, row := range rows {
+		assert.Equal(t, "test-subscription", row["subscription_id"])
+		assert.Equal(t, row["resource_group"], row["name"])
+		names[row["name"]] = true
+	}
+	assert.Equal(t, groupCount, len(names))
+}
+
+func TestProcessResourceGroups_cancelled(t *testing.T) {
+	session := &AzureSession{
+		SubscriptionId: "test-subscription",
+		Authorizer:     autorest.NullAuthorizer{},
+		Sender:         fakeResourceGroupsSender(50),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rows, _ := ProcessResourceGroups(ctx, session, func(collector *utilities.RowCollector, rg string) {
+		collector.Add(map[string]string{"name": rg})
+	})
+	assert.Equal(t, 0, len(rows))
+}
+
+func TestGetAuthSession_sharedAuthorizer(t *testing.T) {
+	defer utilities.InvalidateSessionCache(utilities.ProviderAzure)
+	t.Setenv("AZURE_AUTH_LOCATION", "")
+
+	account := utilities.ExtensionConfigurationAzureAccount{
+		SubscriptionID: "subscription-1",
+		TenantID:       "tenant",
+		ClientID:       "client",
+		ClientSecret:   "secret",
+	}
+	session1, err := GetAuthSession(&account)
+	assert.Nil(t, err)
+	account.SubscriptionID = "subscription-2"
+	session2, err := GetAuthSession(&account)
+	assert.Nil(t, err)
+	assert.Equal(t, "subscription-2", session2.SubscriptionId)
+	assert.Same(t, session1.Authorizer, session2.Authorizer)
+	assert.Equal(t, "", os.Getenv("AZURE_AUTH_LOCATION"))
+
+	_, err = GetAuthSession(&utilities.ExtensionConfigurationAzureAccount{SubscriptionID: "subscription-3"})
+	assert.NotNil(t, err)
+}
+
+// handlerTransport answers requests with a handler, token requests with a fake token
+type handlerTransport struct {
+	handler http.HandlerFunc
+}
+
+func (transport *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	if strings.HasSuffix(req.URL.Path, "/oauth2/token") {
+		recorder.Header().Set("Content-Type", "application/json")
+		recorder.Write([]byte(`{"access_token": "test", "token_type": "Bearer", "expires_in": "3600", "expires_on": "4102444800"}`))
+		return recorder.Result(), nil
+	}
+	transport.handler(recorder, req)
+	return recorder.Result(), nil
+}
+
+func TestGetAccounts_discoverSubscriptions(t *testing.T) {
+	listCalls := 0
+	utilities.SetHTTPTransport(&handlerTransport{handler: func(w http.ResponseWriter, r *http.Request) {
+		listCalls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"value": [
+			{"subscriptionId": "sub-prod", "displayName": "Production", "state": "Enabled"},
+			{"subscriptionId": "sub-dev", "displayName": "Development", "state": "Enabled"},
+			{"subscriptionId": "sub-old", "displayName": "Old production", "state": "Disabled"},
+			{"subscriptionId": "sub-explicit", "displayName": "Explicit", "state": "Enabled"}
+		]}`))
+	}})
+	savedConfiguration := utilities.CurrentConfiguration()
+	defer func() {
+		utilities.SetHTTPTransport(nil)
+		utilities.UpdateConfiguration(func(config *utilities.Configuration) { config.Extension = savedConfiguration.Extension })
+		subscriptionCache = make(map[string]*discoveredSubscriptions)
+		timeNow = time.Now
+	}()
+	utilities.UpdateConfiguration(func(config *utilities.Configuration) {
+		config.Extension.ExtConfAzure.Accounts = []utilities.ExtensionConfigurationAzureAccount{
+			{
+				TenantID:              "tenant",
+				ClientID:              "client",
+				ClientSecret:          "secret",
+				DiscoverSubscriptions: true,
+				ExcludeSubscriptions:  []string{"Dev*"},
+			},
+			{SubscriptionID: "sub-explicit", AuthFile: "auth.json"},
+		}
+	})
+
+	accounts := GetAccounts(context.Background())
+	assert.Equal(t, 2, len(accounts))
+	assert.Equal(t, "sub-explicit", accounts[0].SubscriptionID)
+	assert.Equal(t, "sub-prod", accounts[1].SubscriptionID)
+	assert.Equal(t, "client", accounts[1].ClientID)
+	assert.False(t, accounts[1].DiscoverSubscriptions)
+
+	// Subscriptions are cached until the discovery interval has passed
+	GetAccounts(context.Background())
+	assert.Equal(t, 1, listCalls)
+	timeNow = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	GetAccounts(context.Background())
+	assert.Equal(t, 2, listCalls)
+}
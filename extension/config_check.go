@@ -0,0 +1,285 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package extension
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// ConfigReport holds the problems found by CheckConfigurations
+type ConfigReport struct {
+	// Files is the list of configuration files read
+	Files    []string
+	Errors   []string
+	Warnings []string
+}
+
+func (report *ConfigReport) addError(format string, args ...interface{}) {
+	report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+}
+
+func (report *ConfigReport) addWarning(format string, args ...interface{}) {
+	report.Warnings = append(report.Warnings, fmt.Sprintf(format, args...))
+}
+
+// HasErrors returns true if any error was found
+func (report *ConfigReport) HasErrors() bool {
+	return len(report.Errors) > 0
+}
+
+// Print writes the report to w
+func (report *ConfigReport) Print(w io.Writer) {
+	for _, file := range report.Files {
+		fmt.Fprintf(w, "checked  %s\n", file)
+	}
+	for _, message := range report.Errors {
+		fmt.Fprintf(w, "ERROR    %s\n", message)
+	}
+	for _, message := range report.Warnings {
+		fmt.Fprintf(w, "WARNING  %s\n", message)
+	}
+	fmt.Fprintf(w, "%d error(s), %d warning(s)\n", len(report.Errors), len(report.Warnings))
+}
+
+// decodeStrict decodes jsonEncoded into v, failing on attributes which v doesn't have
+func decodeStrict(jsonEncoded []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(jsonEncoded))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// checkReadable returns an error if the file at path can't be read
+func checkReadable(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// checkKeyFile returns an error if the GCP key file at path can't be read or has no project id
+func checkKeyFile(path string) error {
+	jsonEncoded, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var keyFile struct {
+		ProjectID string `json:"project_id"`
+	}
+	if err := json.Unmarshal(jsonEncoded, &keyFile); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if keyFile.ProjectID == "" {
+		return fmt.Errorf("%s has no project_id", path)
+	}
+	return nil
+}
+
+// CheckConfigurations validates extension_config.json and the table configurations under homeDir
+// without changing the active configuration or calling any cloud API
+func CheckConfigurations(homeDir string) *ConfigReport {
+	report := &ConfigReport{}
+	checkExtensionConfiguration(report, GetExtensionConfigPath(homeDir))
+	checkTableConfigurations(report, homeDir)
+	return report
+}
+
+func checkExtensionConfiguration(report *ConfigReport, filePath string) {
+	report.Files = append(report.Files, filePath)
+	jsonEncoded, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		report.addError("%s: %s", filePath, err.Error())
+		return
+	}
+	var extConfig utilities.ExtensionConfiguration
+	if err := decodeStrict(jsonEncoded, &extConfig); err != nil {
+		report.addError("%s: %s", filePath, err.Error())
+		return
+	}
+	if _, err := loadExtensionConfiguration(filePath); err != nil {
+		report.addError("%s: %s", filePath, err.Error())
+	}
+
+	for idx, account := range extConfig.ExtConfAws.Accounts {
+		name := fmt.Sprintf("aws account %d (%s)", idx, account.ID)
+		if account.ID == "" {
+			report.addError("%s: id is not set", name)
+		}
+		if account.CredentialFile != "" {
+			if err := checkReadable(account.CredentialFile); err != nil {
+				report.addError("%s: credentialFile: %s", name, err.Error())
+			}
+		}
+		for _, roleArn := range []string{account.RoleArn, account.SourceRoleArn} {
+			if _, err := arn.Parse(roleArn); roleArn != "" && err != nil {
+				report.addError("%s: invalid role %q: %s", name, roleArn, err.Error())
+			}
+		}
+		for _, bucket := range account.CtS3Buckets {
+			if bucket.Name == "" || bucket.Region == "" {
+				report.addError("%s: ctS3Buckets: name and region must be set: %+v", name, bucket)
+			}
+		}
+	}
+
+	for idx, account := range extConfig.ExtConfGcp.Accounts {
+		name := fmt.Sprintf("gcp account %d (%s)", idx, account.ProjectID)
+		if account.KeyFile != "" {
+			if err := checkKeyFile(account.KeyFile); err != nil {
+				report.addError("%s: keyFile: %s", name, err.Error())
+			}
+		} else if account.ProjectID == "" && account.OrganizationID == "" && account.FolderID == "" {
+			report.addError("%s: neither keyFile nor projectId (or organizationId, folderId) is set", name)
+		}
+		if account.OrganizationID != "" && account.FolderID != "" {
+			report.addWarning("%s: both organizationId and folderId are set, folderId is ignored", name)
+		}
+	}
+
+	for idx, account := range extConfig.ExtConfAzure.Accounts {
+		name := fmt.Sprintf("azure account %d (%s)", idx, account.SubscriptionID)
+		if account.AuthFile != "" {
+			if err := checkReadable(account.AuthFile); err != nil {
+				report.addError("%s: authFile: %s", name, err.Error())
+			}
+		}
+		if account.CertificatePath != "" {
+			if err := checkReadable(account.CertificatePath); err != nil {
+				report.addError("%s: certificatePath: %s", name, err.Error())
+			}
+		}
+		switch {
+		case account.UseEnvironment, account.AuthFile != "":
+		case account.ClientID != "":
+			if account.TenantID == "" {
+				report.addError("%s: clientId requires tenantId", name)
+			}
+			if account.ClientSecret == "" && account.CertificatePath == "" {
+				report.addError("%s: clientId requires clientSecret or certificatePath", name)
+			}
+		default:
+			report.addError("%s: no credentials configured (authFile, clientId or useEnvironment)", name)
+		}
+		if account.SubscriptionID == "" && !account.DiscoverSubscriptions && account.AuthFile == "" {
+			report.addError("%s: subscriptionId is not set", name)
+		}
+	}
+
+	registered := map[string]bool{sessionCacheTableName: true}
+	for _, definition := range utilities.GetRegisteredTables() {
+		registered[definition.Name] = true
+	}
+	for _, eventTable := range getAllEventTables() {
+		registered[eventTable.GetName()] = true
+	}
+	tablesConfig := extConfig.ExtConfTables
+	for _, tableName := range append(append([]string{}, tablesConfig.EnabledTables...), tablesConfig.DisabledTables...) {
+		if !registered[tableName] {
+			report.addWarning("tables: unknown table %s", tableName)
+		}
+	}
+	for _, provider := range tablesConfig.DisabledProviders {
+		if provider != utilities.ProviderAws && provider != utilities.ProviderGcp && provider != utilities.ProviderAzure {
+			report.addWarning("tables: unknown provider %s", provider)
+		}
+	}
+	filterTables := make([]string, 0, len(extConfig.ExtConfFilters))
+	for tableName := range extConfig.ExtConfFilters {
+		filterTables = append(filterTables, tableName)
+	}
+	sort.Strings(filterTables)
+	for _, tableName := range filterTables {
+		if !registered[tableName] {
+			report.addWarning("filters: unknown table %s", tableName)
+		}
+	}
+}
+
+func checkTableConfigurations(report *ConfigReport, homeDir string) {
+	// tableFiles is table name => file defining it
+	tableFiles := make(map[string]string)
+	tableConfigs := make(map[string]*utilities.TableConfig)
+	for _, configFile := range utilities.GetTableConfigFiles() {
+		jsonEncoded := configFile.Data
+		filePath := homeDir + string(os.PathSeparator) + configFile.Path
+		override, err := ioutil.ReadFile(filePath)
+		if err == nil {
+			report.Files = append(report.Files, filePath)
+			var overrideConfigs map[string]*utilities.TableConfig
+			if err := decodeStrict(override, &overrideConfigs); err != nil {
+				report.addError("%s: %s", filePath, err.Error())
+				continue
+			}
+			merged, err := utilities.MergeTableConfig(configFile.Data, override)
+			if err != nil {
+				report.addError("%s: %s", filePath, err.Error())
+				continue
+			}
+			jsonEncoded = merged
+		} else if !os.IsNotExist(err) {
+			report.addError("%s: %s", filePath, err.Error())
+			continue
+		} else {
+			filePath = configFile.Path + " (default)"
+		}
+
+		configs, err := utilities.ParseTableConfig(jsonEncoded)
+		if err != nil {
+			report.addError("%s: %s", filePath, err.Error())
+			continue
+		}
+		for tableName, tableConfig := range configs {
+			if previous, found := tableFiles[tableName]; found {
+				report.addError("%s: table %s is already defined in %s", filePath, tableName, previous)
+				continue
+			}
+			tableFiles[tableName] = filePath
+			tableConfigs[tableName] = tableConfig
+		}
+	}
+
+	registered := make(map[string]bool)
+	for _, definition := range utilities.GetRegisteredTables() {
+		registered[definition.Name] = true
+		if _, found := tableConfigs[definition.Name]; !found {
+			report.addError("table %s has no configuration", definition.Name)
+		}
+	}
+	for _, eventTable := range getAllEventTables() {
+		registered[eventTable.GetName()] = true
+		tableConfig, found := tableConfigs[eventTable.GetName()]
+		if !found {
+			report.addError("table %s has no configuration", eventTable.GetName())
+			continue
+		}
+		if err := tableConfig.ValidateColumns(eventTable.GetColumns()); err != nil {
+			report.addError("%s: table %s: %s", tableFiles[eventTable.GetName()], eventTable.GetName(), err.Error())
+		}
+	}
+	tableNames := make([]string, 0, len(tableConfigs))
+	for tableName := range tableConfigs {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+	for _, tableName := range tableNames {
+		if !registered[tableName] {
+			report.addWarning("%s: table %s is not provided by the extension", tableFiles[tableName], tableName)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package extension
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConfigurations(t *testing.T) {
+	homeDir := t.TempDir()
+	keyFile := filepath.Join(homeDir, "key.json")
+	writeFile(t, keyFile, `{"project_id": "project-1"}`)
+	writeFile(t, GetExtensionConfigPath(homeDir), `{"gcp": {"accounts": [{"keyFile": "`+keyFile+`"}]}}`)
+
+	report := CheckConfigurations(homeDir)
+	assert.Empty(t, report.Errors)
+
+	writeFile(t, GetExtensionConfigPath(homeDir), `{"gcp": {"accounts": [{"projectName": "project-1"}]}}`)
+	report = CheckConfigurations(homeDir)
+	assert.Equal(t, 1, len(report.Errors))
+	assert.Contains(t, report.Errors[0], `unknown field "projectName"`)
+
+	writeFile(t, GetExtensionConfigPath(homeDir), `{
+		"aws": {"accounts": [{"id": "111111111111", "credentialFile": "`+filepath.Join(homeDir, "missing")+`"}]},
+		"gcp": {"accounts": [{}]},
+		"azure": {"accounts": [{"subscriptionId": "subscription-1", "clientId": "client"}]},
+		"tables": {"disabledTables": ["aws_unknown_table"]}
+	}`)
+	writeFile(t, filepath.Join(homeDir, "aws", "s3", "table_config.json"), `{"aws_s3_bucket": {"parsedAttribute": []}}`)
+	writeFile(t, filepath.Join(homeDir, "aws", "sqs", "table_config.json"), `{"aws_ec2_instance": {"parsedAttributes": []}}`)
+	report = CheckConfigurations(homeDir)
+	assert.Equal(t, 7, len(report.Errors), report.Errors)
+	assert.Equal(t, 2, len(report.Warnings), report.Warnings)
+
+	var output bytes.Buffer
+	report.Print(&output)
+	assert.True(t, strings.HasSuffix(output.String(), "7 error(s), 2 warning(s)\n"))
+}
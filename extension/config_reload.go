@@ -0,0 +1,129 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package extension
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	extaws "github.com/Uptycs/cloudquery/extension/aws"
+	extazure "github.com/Uptycs/cloudquery/extension/azure"
+	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
+	"github.com/Uptycs/cloudquery/utilities"
+	log "github.com/sirupsen/logrus"
+)
+
+var reloadMutex sync.Mutex
+
+// ReloadConfigurations reads extension_config.json and the table configurations under homeDir again.
+// If all of them are valid and agree with the columns of the registered tables, they replace the current
+// configuration at once (see utilities.SetConfiguration), discovered accounts are listed again and event
+// tables are notified. Otherwise the current configuration stays active and the error is returned.
+// Attributes can be enabled or disabled, as columns are registered for all of them (see TableConfig.GetColumns).
+// Enabling or disabling tables, adding attributes and changing their types take effect after restart.
+func ReloadConfigurations(homeDir string) error {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	filePath := GetExtensionConfigPath(homeDir)
+	extConfig, err := loadExtensionConfiguration(filePath)
+	if err != nil {
+		return fmt.Errorf("invalid configuration file %s: %w", filePath, err)
+	}
+	setGcpProjectIDs(&extConfig)
+	tableConfigs, err := loadTableConfigurations(homeDir, true)
+	if err != nil {
+		return err
+	}
+	if err := validateRegisteredTables(tableConfigs); err != nil {
+		return err
+	}
+
+	utilities.SetConfiguration(extConfig, tableConfigs)
+	extaws.InvalidateDiscoveredAccounts()
+	extgcp.InvalidateDiscoveredProjects()
+	extazure.InvalidateDiscoveredSubscriptions()
+	for _, eventTable := range GetEventTables() {
+		eventTable.ConfigurationChanged()
+	}
+	utilities.GetLogger().WithFields(log.Fields{
+		"totalTables": len(tableConfigs),
+	}).Info("reloaded configuration")
+	return nil
+}
+
+// getConfigFileStates returns the modification time and size of extension_config.json and of the
+// table_config.json files under homeDir, by path. Missing files are included with empty state
+func getConfigFileStates(homeDir string) map[string]string {
+	paths := []string{GetExtensionConfigPath(homeDir)}
+	for _, configFile := range utilities.GetTableConfigFiles() {
+		paths = append(paths, homeDir+string(os.PathSeparator)+configFile.Path)
+	}
+	states := make(map[string]string, len(paths))
+	for _, path := range paths {
+		states[path] = ""
+		if info, err := os.Stat(path); err == nil {
+			states[path] = fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return states
+}
+
+func configFilesChanged(previous, current map[string]string) bool {
+	for path, state := range current {
+		if previous[path] != state {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchConfigurations reloads the configuration (see ReloadConfigurations) whenever a signal is received
+// on reload (e.g. SIGHUP) and, if interval is positive, when a configuration file under homeDir has been
+// created, modified or removed since it was last checked. It returns when ctx is cancelled
+func WatchConfigurations(ctx context.Context, wg *sync.WaitGroup, homeDir string, interval time.Duration, reload <-chan os.Signal) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var poll <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			poll = ticker.C
+		}
+		states := getConfigFileStates(homeDir)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-reload:
+				utilities.GetLogger().WithFields(log.Fields{
+					"signal": sig.String(),
+				}).Info("reloading configuration")
+			case <-poll:
+				current := getConfigFileStates(homeDir)
+				if !configFilesChanged(states, current) {
+					continue
+				}
+				utilities.GetLogger().Info("configuration files changed, reloading configuration")
+			}
+			// Files are checked again after the reload, so a rejected file is not read again until it changes
+			states = getConfigFileStates(homeDir)
+			if err := ReloadConfigurations(homeDir); err != nil {
+				utilities.GetLogger().WithFields(log.Fields{
+					"errString": err.Error(),
+				}).Error("failed to reload configuration, keeping the current one")
+			}
+		}
+	}()
+}
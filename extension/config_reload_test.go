@@ -0,0 +1,92 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package extension
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/stretchr/testify/assert"
+)
+
+// freshProcessConfigEnv is set to the configuration file read by TestReadExtensionConfigurationsFreshProcess
+// in a child process, which starts without a logger like the extension does
+const freshProcessConfigEnv = "CLOUDQUERY_TEST_FRESH_PROCESS_CONFIG"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(freshProcessConfigEnv) == "" {
+		utilities.CreateLogger(true, 20, 1, 30)
+	}
+	os.Exit(m.Run())
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0700))
+	assert.Nil(t, os.WriteFile(path, []byte(data), 0600))
+}
+
+func TestReadExtensionConfigurationsFreshProcess(t *testing.T) {
+	if configPath := os.Getenv(freshProcessConfigEnv); configPath != "" {
+		if err := ReadExtensionConfigurations(configPath, false); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	configPath := GetExtensionConfigPath(t.TempDir())
+	writeFile(t, configPath, `{"aws": {"accounts": [{"id": "111111111111"}]}}`)
+	cmd := exec.Command(os.Args[0], "-test.run=^TestReadExtensionConfigurationsFreshProcess$")
+	cmd.Env = append(os.Environ(), freshProcessConfigEnv+"="+configPath)
+	output, err := cmd.CombinedOutput()
+	assert.Nil(t, err, string(output))
+}
+
+func TestReloadConfigurations(t *testing.T) {
+	homeDir := t.TempDir()
+	configPath := GetExtensionConfigPath(homeDir)
+	writeFile(t, configPath, `{"aws": {"accounts": [{"id": "111111111111"}]}}`)
+	writeFile(t, filepath.Join(homeDir, "aws", "s3", "table_config.json"),
+		`{"aws_s3_bucket": {"parsedAttributes": [{"sourceName": "Buckets_MfaDelete", "enabled": false}]}}`)
+	assert.Nil(t, ReadExtensionConfigurations(configPath, false))
+	ReadTableConfigurations(homeDir)
+	columns, err := utilities.CurrentConfiguration().Tables["aws_s3_bucket"].GetColumns()
+	assert.Nil(t, err)
+	setRegisteredColumns("aws_s3_bucket", columns)
+	defer delete(registeredColumns, "aws_s3_bucket")
+	states := getConfigFileStates(homeDir)
+
+	assert.Equal(t, "", utilities.CurrentConfiguration().Tables["aws_s3_bucket"].GetTargetName("Buckets_MfaDelete"))
+
+	// Valid configuration replaces the current one. Columns of disabled attributes are registered,
+	// so enabling an attribute only fills its column
+	writeFile(t, configPath, `{"aws": {"accounts": [{"id": "111111111111"}, {"id": "222222222222"}]}}`)
+	writeFile(t, filepath.Join(homeDir, "aws", "s3", "table_config.json"), `{"aws_s3_bucket": {"cacheTtl": 60}}`)
+	assert.True(t, configFilesChanged(states, getConfigFileStates(homeDir)))
+	assert.Nil(t, ReloadConfigurations(homeDir))
+	assert.Equal(t, 2, len(utilities.CurrentConfiguration().Extension.ExtConfAws.Accounts))
+	assert.Equal(t, 60, utilities.CurrentConfiguration().Tables["aws_s3_bucket"].CacheTTL)
+	assert.Equal(t, "mfa_delete", utilities.CurrentConfiguration().Tables["aws_s3_bucket"].GetTargetName("Buckets_MfaDelete"))
+
+	// Invalid extension configuration is rejected
+	writeFile(t, configPath, `{"aws": {"accounts": [{"id": "111111111111", "includeRegions": ["["]}]}}`)
+	assert.NotNil(t, ReloadConfigurations(homeDir))
+	assert.Equal(t, 2, len(utilities.CurrentConfiguration().Extension.ExtConfAws.Accounts))
+
+	// Attributes without a registered column can't be added
+	writeFile(t, configPath, `{}`)
+	writeFile(t, filepath.Join(homeDir, "aws", "s3", "table_config.json"),
+		`{"aws_s3_bucket": {"parsedAttributes": [{"sourceName": "Extra", "targetName": "extra", "targetType": "TEXT", "enabled": true}]}}`)
+	assert.NotNil(t, ReloadConfigurations(homeDir))
+	assert.Equal(t, 2, len(utilities.CurrentConfiguration().Extension.ExtConfAws.Accounts))
+	assert.Equal(t, 60, utilities.CurrentConfiguration().Tables["aws_s3_bucket"].CacheTTL)
+}
@@ -1,34 +1,22 @@
 package extension
 
 import (
-	"context"
-	"github.com/Uptycs/basequery-go/plugin/table"
-	"github.com/Uptycs/cloudquery/extension/aws/cloudtrail"
-	"github.com/Uptycs/cloudquery/extension/gcp/cloudlog"
-	"sync"
-	"time"
+	"github.com/Uptycs/cloudquery/utilities"
 )
 
-// EventTable interface for eventing tables
-type EventTable interface {
-	GetName() string
-	GetColumns() []table.ColumnDefinition
-	GetGenFunction() table.GenerateFunc
-	Start(ctx context.Context, wg *sync.WaitGroup, socket string, timeout time.Duration)
+// getAllEventTables returns all eventing tables registered by the table packages, including disabled ones
+func getAllEventTables() []utilities.EventTable {
+	return utilities.GetRegisteredEventTables()
 }
 
-var (
-	once           sync.Once
-	eventTableList []EventTable = make([]EventTable, 0)
-)
-
-// GetEventTables return the list of all eventing tables
-func GetEventTables() []EventTable {
-	once.Do(func() {
-		eventTableList = []EventTable{
-			&cloudtrail.CloudTrailEventTable{},
-			&cloudlog.CloudLogEventTable{},
+// GetEventTables return the list of eventing tables which are not disabled in extension configuration
+func GetEventTables() []utilities.EventTable {
+	allTables := getAllEventTables()
+	enabledTables := make([]utilities.EventTable, 0, len(allTables))
+	for _, eventTable := range allTables {
+		if utilities.IsTableEnabled(eventTable.GetName()) {
+			enabledTables = append(enabledTables, eventTable)
 		}
-	})
-	return eventTableList
+	}
+	return enabledTables
 }
@@ -24,12 +24,10 @@ import (
 	osquery "github.com/Uptycs/basequery-go"
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"google.golang.org/api/iterator"
-	"google.golang.org/api/option"
 
 	"sync"
 	"time"
 
-	"github.com/patrickmn/go-cache"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Uptycs/cloudquery/utilities"
@@ -39,22 +37,17 @@ import (
 	"google.golang.org/api/logging/v2"
 )
 
-type ObjectMarker struct {
-	modifiedTime time.Time
-	key          string
-	dirPath      string
-}
-
 // CloudLogEventTable implements EventTable interface
 type CloudLogEventTable struct {
 	// Marker will always be atleast markerDelayMinutes prior to current time
 	markerDelayMinutes int
-	// Map of bucketName+logName => ObjectMarker
-	markerMap map[string]*ObjectMarker
-	// objects which we have processed in last 1 hour
-	objectCache *cache.Cache
+	// Markers (bucketName+logName => CheckpointMarker) and objects processed in last CACHE_TIMEOUT_MINUTES
+	checkpoints *utilities.CheckpointStore
 	client      *osquery.ExtensionManagerClient
 	ctx         context.Context
+	// reload is signalled by ConfigurationChanged
+	reload     chan struct{}
+	reloadOnce sync.Once
 }
 
 var (
@@ -99,17 +92,14 @@ func (cl *CloudLogEventTable) GetGenFunction() table.GenerateFunc {
 func (cl *CloudLogEventTable) initialize(ctx context.Context, socket string, timeout time.Duration) {
 	cl.ctx = ctx
 	cl.markerDelayMinutes = MARKER_DELAY_MINUTES
-	cl.objectCache = cache.New(time.Duration(CACHE_TIMEOUT_MINUTES)*time.Minute, time.Duration(CACHE_TIMEOUT_MINUTES)*time.Minute)
-	cl.markerMap = make(map[string]*ObjectMarker)
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) > 0 {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
-			for _, bucket := range account.CloudLogStorageBuckets {
-				for _, logName := range bucket.LogNames {
-					cl.markerMap[bucket.Name+logName] = nil
-				}
-			}
-		}
+	checkpoints, err := utilities.NewCheckpointStore(utilities.GetCheckpointPath(TABLE_NAME), time.Duration(CACHE_TIMEOUT_MINUTES)*time.Minute)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": TABLE_NAME,
+			"errString": err.Error(),
+		}).Error("failed to load checkpoints")
 	}
+	cl.checkpoints = checkpoints
 	cl.client, _ = osquery.NewClient(socket, timeout)
 }
 
@@ -127,6 +117,11 @@ func (cl *CloudLogEventTable) Start(ctx context.Context, wg *sync.WaitGroup, soc
 			// Shutdown
 			timer1.Stop()
 			return
+		case <-cl.getReloadChannel():
+			timer1.Stop()
+			cl.pruneCheckpoints()
+			cl.runEventLoop()
+			timer1 = time.NewTimer(time.Duration(LOOP_TIMER_SECONDS) * time.Second)
 		case <-timer1.C:
 			cl.runEventLoop()
 			timer1 = time.NewTimer(time.Duration(LOOP_TIMER_SECONDS) * time.Second)
@@ -134,26 +129,66 @@ func (cl *CloudLogEventTable) Start(ctx context.Context, wg *sync.WaitGroup, soc
 	}
 }
 
+func (cl *CloudLogEventTable) getReloadChannel() chan struct{} {
+	cl.reloadOnce.Do(func() {
+		cl.reload = make(chan struct{}, 1)
+	})
+	return cl.reload
+}
+
+// ConfigurationChanged makes the event loop drop the checkpoints of buckets (and logs) which are no longer
+// configured and collect events right away, so that new buckets don't wait for the next loop
+func (cl *CloudLogEventTable) ConfigurationChanged() {
+	select {
+	case cl.getReloadChannel() <- struct{}{}:
+	default:
+		// Already pending
+	}
+}
+
+func (cl *CloudLogEventTable) pruneCheckpoints() {
+	markers := make(map[string]bool)
+	for _, account := range utilities.CurrentConfiguration().Extension.ExtConfGcp.Accounts {
+		for _, bucket := range account.CloudLogStorageBuckets {
+			for _, logName := range bucket.LogNames {
+				markers[bucket.Name+logName] = true
+			}
+		}
+	}
+	for _, marker := range cl.checkpoints.PruneMarkers(markers) {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": TABLE_NAME,
+			"marker":    marker,
+		}).Info("bucket or log is no longer configured")
+	}
+	cl.flushCheckpoints()
+}
+
 // CloudLogGenerate returns empty row
 func (cl *CloudLogEventTable) CloudLogGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	return nil, nil
 }
 
 func (cl *CloudLogEventTable) runEventLoop() {
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) > 0 {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
-			if !extgcp.ShouldProcessProject(TABLE_NAME, account.ProjectID) {
-				continue
-			}
-			utilities.GetLogger().WithFields(log.Fields{
-				"tableName": TABLE_NAME,
-				"projectID": account.ProjectID,
-			}).Info("processing account")
-			cl.processAccountLookupEvents(&account)
-		}
+	config := utilities.CurrentConfiguration()
+	for _, account := range config.Extension.ExtConfGcp.Accounts {
+		cl.processAccount(config, account)
 	}
 }
 
+// processAccount collects the events of a project using given configuration, even if it is replaced meanwhile
+func (cl *CloudLogEventTable) processAccount(config *utilities.Configuration, account utilities.ExtensionConfigurationGcpAccount) {
+	if !extgcp.ShouldProcessProject(TABLE_NAME, account.ProjectID) {
+		return
+	}
+	utilities.GetLogger().WithFields(log.Fields{
+		"tableName": TABLE_NAME,
+		"projectID": account.ProjectID,
+	}).Info("processing account")
+	osqCtx := utilities.ContextWithConfiguration(cl.ctx, config)
+	cl.processAccountLookupEvents(osqCtx, &account)
+}
+
 /*
 Dir: <logName>/YYYY/MM/DD/
 FileName: HH:00:00_HH:59:59_S0.json
@@ -193,7 +228,7 @@ func logEntryToEventRow(entry logging.LogEntry) map[string]string {
 	return event
 }
 
-func (cl *CloudLogEventTable) processRecords(account *utilities.ExtensionConfigurationGcpAccount, bucket utilities.CloudLogStorageBucket,
+func (cl *CloudLogEventTable) processRecords(osqCtx context.Context, account *utilities.ExtensionConfigurationGcpAccount, bucket utilities.CloudLogStorageBucket,
 	logName string, key string, jsonData string, outEvents []map[string]string) []map[string]string {
 	jsonObj := logging.LogEntry{}
 	err := json.Unmarshal([]byte(jsonData), &jsonObj)
@@ -211,7 +246,7 @@ func (cl *CloudLogEventTable) processRecords(account *utilities.ExtensionConfigu
 		return outEvents
 	}
 	event := logEntryToEventRow(jsonObj)
-	if !extgcp.ShouldProcessEvent(TABLE_NAME, account.ProjectID, bucket.Region, event) {
+	if !extgcp.ShouldProcessEvent(osqCtx, TABLE_NAME, account.ProjectID, bucket.Region, event) {
 		return outEvents
 	}
 	return append(outEvents, event)
@@ -255,9 +290,8 @@ func (cl *CloudLogEventTable) getObjectReader(account *utilities.ExtensionConfig
 	}
 }
 
-func (cl *CloudLogEventTable) processSingleObject(client *storage.Client, account *utilities.ExtensionConfigurationGcpAccount, bucket utilities.CloudLogStorageBucket, logName string, obj *storage.ObjectAttrs) error {
-	_, found := cl.objectCache.Get(bucket.Name + obj.Name)
-	if found {
+func (cl *CloudLogEventTable) processSingleObject(osqCtx context.Context, client *storage.Client, account *utilities.ExtensionConfigurationGcpAccount, bucket utilities.CloudLogStorageBucket, logName string, obj *storage.ObjectAttrs) error {
+	if cl.checkpoints.IsObjectProcessed(bucket.Name + obj.Name) {
 		// we have already processed this file
 		return nil
 	}
@@ -287,7 +321,7 @@ func (cl *CloudLogEventTable) processSingleObject(client *storage.Client, accoun
 	for err == nil && !isPrefix {
 		lineStr := string(line)
 		// Collect events
-		events = cl.processRecords(account, bucket, logName, obj.Name, lineStr, events)
+		events = cl.processRecords(osqCtx, account, bucket, logName, obj.Name, lineStr, events)
 		line, isPrefix, err = r.ReadLine()
 	}
 
@@ -330,15 +364,28 @@ func (cl *CloudLogEventTable) processSingleObject(client *storage.Client, accoun
 		return err
 	}
 	utilities.GetLogger().Info("Processed file ", bucket.Name+obj.Name)
-	cl.objectCache.Add(bucket.Name+obj.Name, true, 0)
+	cl.checkpoints.AddProcessedObject(bucket.Name + obj.Name)
+	cl.flushCheckpoints()
 	return nil
 }
 
-func (cl *CloudLogEventTable) processObjects(client *storage.Client, account *utilities.ExtensionConfigurationGcpAccount,
+func (cl *CloudLogEventTable) flushCheckpoints() {
+	if err := cl.checkpoints.Flush(); err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"tableName": TABLE_NAME,
+			"errString": err.Error(),
+		}).Error("failed to save checkpoints")
+	}
+}
+
+func (cl *CloudLogEventTable) processObjects(osqCtx context.Context, client *storage.Client, account *utilities.ExtensionConfigurationGcpAccount,
 	bucket utilities.CloudLogStorageBucket, objs []*storage.ObjectAttrs, dirPath string, logName string) {
 	currentTime := time.Now()
-	currentMarker := cl.markerMap[bucket.Name+logName]
-	if currentMarker != nil && currentMarker.dirPath != dirPath {
+	var currentMarker *utilities.CheckpointMarker
+	if marker, found := cl.checkpoints.GetMarker(bucket.Name + logName); found {
+		currentMarker = &marker
+	}
+	if currentMarker != nil && currentMarker.Prefix != dirPath {
 		// this marker is for different day
 		currentMarker = nil
 	}
@@ -353,23 +400,24 @@ func (cl *CloudLogEventTable) processObjects(client *storage.Client, account *ut
 			continue
 		}
 		// Process object
-		cl.processSingleObject(client, account, bucket, logName, obj)
+		cl.processSingleObject(osqCtx, client, account, bucket, logName, obj)
 		// if object is not within latest cl.markerDelayMinutes
 		// and if it is modified after current marker, update the marker
 		if currentTime.Sub(obj.Updated) >= time.Duration(cl.markerDelayMinutes)*time.Minute {
-			if currentMarker == nil || currentMarker.modifiedTime.Before(obj.Updated) {
+			if currentMarker == nil || currentMarker.ModifiedTime.Before(obj.Updated) {
 				// update marker
-				newMarker := ObjectMarker{
-					modifiedTime: obj.Updated,
-					key:          obj.Name,
-					dirPath:      dirPath,
+				newMarker := utilities.CheckpointMarker{
+					ModifiedTime: obj.Updated,
+					Key:          obj.Name,
+					Prefix:       dirPath,
 				}
 				currentMarker = &newMarker
 			}
 		}
 	}
 	if currentMarker != nil {
-		cl.markerMap[bucket.Name+logName] = currentMarker
+		cl.checkpoints.SetMarker(bucket.Name+logName, *currentMarker)
+		cl.flushCheckpoints()
 	}
 }
 
@@ -379,11 +427,10 @@ func (cl *CloudLogEventTable) getStorageServiceForAccount(account *utilities.Ext
 	var err error
 	if account != nil {
 		projectID = account.ProjectID
-		client, err = storage.NewClient(cl.ctx, option.WithCredentialsFile(account.KeyFile))
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		client, err = storage.NewClient(cl.ctx)
 	}
+	client, err = storage.NewClient(cl.ctx, extgcp.GetClientOptions(cl.ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": TABLE_NAME,
@@ -419,7 +466,7 @@ func (cl *CloudLogEventTable) getObjectList(client *storage.Client, bucketName,
 	return objList
 }
 
-func (cl *CloudLogEventTable) processBucket(account *utilities.ExtensionConfigurationGcpAccount, bucket utilities.CloudLogStorageBucket) {
+func (cl *CloudLogEventTable) processBucket(osqCtx context.Context, account *utilities.ExtensionConfigurationGcpAccount, bucket utilities.CloudLogStorageBucket) {
 	utilities.GetLogger().Info("Processing bucket ", account.ProjectID, ":", bucket.Name)
 	client, _ := cl.getStorageServiceForAccount(account)
 	if client == nil {
@@ -431,22 +478,27 @@ func (cl *CloudLogEventTable) processBucket(account *utilities.ExtensionConfigur
 		currentTime := time.Now()
 		dirPath := cl.getDirPath(logName, currentTime)
 		pastDirPath := cl.getDirPath(logName, currentTime.Add(-time.Duration(cl.markerDelayMinutes)*time.Minute))
+		if marker, found := cl.checkpoints.GetMarker(bucket.Name + logName); found && marker.Prefix != dirPath && marker.Prefix != pastDirPath {
+			// we were stopped on an earlier day, finish the files of that day first
+			storageObjects := cl.getObjectList(client, bucket.Name, marker.Prefix)
+			cl.processObjects(osqCtx, client, account, bucket, storageObjects, marker.Prefix, logName)
+		}
 		if dirPath != pastDirPath {
 			// we just moved to new day, but we need to process last few files in past day as well
 			storageObjects := cl.getObjectList(client, bucket.Name, pastDirPath)
-			cl.processObjects(client, account, bucket, storageObjects, pastDirPath, logName)
+			cl.processObjects(osqCtx, client, account, bucket, storageObjects, pastDirPath, logName)
 		}
 		// process current day
 		storageObjects := cl.getObjectList(client, bucket.Name, dirPath)
-		cl.processObjects(client, account, bucket, storageObjects, dirPath, logName)
+		cl.processObjects(osqCtx, client, account, bucket, storageObjects, dirPath, logName)
 	}
 }
 
-func (cl *CloudLogEventTable) processAccountLookupEvents(account *utilities.ExtensionConfigurationGcpAccount) {
+func (cl *CloudLogEventTable) processAccountLookupEvents(osqCtx context.Context, account *utilities.ExtensionConfigurationGcpAccount) {
 	if account == nil || len(account.CloudLogStorageBuckets) == 0 {
 		return
 	}
-	_, ok := utilities.TableConfigurationMap[TABLE_NAME]
+	_, ok := utilities.GetConfiguration(osqCtx).Tables[TABLE_NAME]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": TABLE_NAME,
@@ -454,6 +506,6 @@ func (cl *CloudLogEventTable) processAccountLookupEvents(account *utilities.Exte
 		return
 	}
 	for _, bucket := range account.CloudLogStorageBuckets {
-		cl.processBucket(account, bucket)
+		cl.processBucket(osqCtx, account, bucket)
 	}
 }
@@ -20,8 +20,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	compute "google.golang.org/api/compute/v1"
 )
 
@@ -29,72 +27,21 @@ type myGcpComputeDisksItemsContainer struct {
 	Items []*compute.Disk `json:"items"`
 }
 
-// GcpComputeDisksColumns returns the list of columns for gcp_compute_disk
-func (handler *GcpComputeHandler) GcpComputeDisksColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("creation_timestamp"),
-		table.TextColumn("description"),
-		table.TextColumn("disk_encryption_key"),
-		//table.TextColumn("disk_encryption_key_kms_key_name"),
-		//table.TextColumn("disk_encryption_key_kms_key_service_account"),
-		//table.TextColumn("disk_encryption_key_raw_key"),
-		//table.TextColumn("disk_encryption_key_sha256"),
-		table.TextColumn("guest_os_features"),
-		//table.TextColumn("guest_os_features_type"),
-		table.BigIntColumn("id"),
-		table.TextColumn("kind"),
-		table.TextColumn("label_fingerprint"),
-		table.TextColumn("labels"),
-		table.TextColumn("last_attach_timestamp"),
-		table.TextColumn("last_detach_timestamp"),
-		table.TextColumn("license_codes"),
-		table.TextColumn("licenses"),
-		table.TextColumn("name"),
-		table.TextColumn("options"),
-		table.BigIntColumn("physical_block_size_bytes"),
-		table.TextColumn("region"),
-		table.TextColumn("replica_zones"),
-		table.TextColumn("resource_policies"),
-		//table.TextColumn("self_link"),
-		table.BigIntColumn("size_gb"),
-		table.TextColumn("source_disk"),
-		table.TextColumn("source_disk_id"),
-		table.TextColumn("source_image"),
-		table.TextColumn("source_image_encryption_key"),
-		//table.TextColumn("source_image_encryption_key_kms_key_name"),
-		//table.TextColumn("source_image_encryption_key_kms_key_service_account"),
-		//table.TextColumn("source_image_encryption_key_raw_key"),
-		//table.TextColumn("source_image_encryption_key_sha256"),
-		table.TextColumn("source_image_id"),
-		table.TextColumn("source_snapshot"),
-		//table.TextColumn("source_snapshot_encryption_key"),
-		//table.TextColumn("source_snapshot_encryption_key_kms_key_name"),
-		//table.TextColumn("source_snapshot_encryption_key_kms_key_service_account"),
-		//table.TextColumn("source_snapshot_encryption_key_raw_key"),
-		//table.TextColumn("source_snapshot_encryption_key_sha256"),
-		table.TextColumn("source_snapshot_id"),
-		table.TextColumn("status"),
-		table.TextColumn("type"),
-		table.TextColumn("users"),
-		table.TextColumn("zone"),
-	}
-}
-
 // GcpComputeDisksGenerate returns the rows in the table for all configured accounts
 func (handler *GcpComputeHandler) GcpComputeDisksGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_disk", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_disk", utilities.DefaultGcpProjectID) {
 		results, err := handler.processAccountGcpComputeDisks(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_compute_disk", account.ProjectID) {
 				continue
 			}
@@ -112,16 +59,12 @@ func (handler *GcpComputeHandler) getGcpComputeDisksNewServiceForAccount(ctx con
 	var projectID string
 	var service *compute.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	}
+	service, err = handler.svcInterface.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_disk",
@@ -184,7 +127,7 @@ func (handler *GcpComputeHandler) processAccountGcpComputeDisks(ctx context.Cont
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_compute_disk"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_compute_disk"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_disk",
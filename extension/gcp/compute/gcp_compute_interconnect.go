@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	compute "google.golang.org/api/compute/v1"
 )
 
@@ -28,60 +26,21 @@ type myGcpComputeInterconnectsItemsContainer struct {
 	Items []*compute.Interconnect `json:"items"`
 }
 
-// GcpComputeInterconnectsColumns returns the list of columns for gcp_compute_interconnect
-func (handler *GcpComputeHandler) GcpComputeInterconnectsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("admin_enabled"),
-		table.TextColumn("circuit_infos"),
-		//table.TextColumn("circuit_infos_customer_demarc_id"),
-		//table.TextColumn("circuit_infos_google_circuit_id"),
-		//table.TextColumn("circuit_infos_google_demarc_id"),
-		table.TextColumn("creation_timestamp"),
-		table.TextColumn("customer_name"),
-		table.TextColumn("description"),
-		table.TextColumn("expected_outages"),
-		//table.TextColumn("expected_outages_affected_circuits"),
-		//table.TextColumn("expected_outages_description"),
-		//table.BigIntColumn("expected_outages_end_time"),
-		//table.TextColumn("expected_outages_issue_type"),
-		//table.TextColumn("expected_outages_name"),
-		//table.TextColumn("expected_outages_source"),
-		//table.BigIntColumn("expected_outages_start_time"),
-		//table.TextColumn("expected_outages_state"),
-		table.TextColumn("google_ip_address"),
-		table.TextColumn("google_reference_id"),
-		table.BigIntColumn("id"),
-		table.TextColumn("interconnect_attachments"),
-		table.TextColumn("interconnect_type"),
-		table.TextColumn("kind"),
-		table.TextColumn("link_type"),
-		table.TextColumn("location"),
-		table.TextColumn("name"),
-		table.TextColumn("noc_contact_email"),
-		table.TextColumn("operational_status"),
-		table.TextColumn("peer_ip_address"),
-		table.BigIntColumn("provisioned_link_count"),
-		table.BigIntColumn("requested_link_count"),
-		//table.TextColumn("self_link"),
-		table.TextColumn("state"),
-	}
-}
-
 // GcpComputeInterconnectsGenerate returns the rows in the table for all configured accounts
 func (handler *GcpComputeHandler) GcpComputeInterconnectsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_interconnect", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_interconnect", utilities.DefaultGcpProjectID) {
 		results, err := handler.processAccountGcpComputeInterconnects(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_compute_interconnect", account.ProjectID) {
 				continue
 			}
@@ -99,16 +58,12 @@ func (handler *GcpComputeHandler) getGcpComputeInterconnectsNewServiceForAccount
 	var projectID string
 	var service *compute.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	}
+	service, err = handler.svcInterface.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_interconnect",
@@ -165,7 +120,7 @@ func (handler *GcpComputeHandler) processAccountGcpComputeInterconnects(ctx cont
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_compute_interconnect"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_compute_interconnect"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_interconnect",
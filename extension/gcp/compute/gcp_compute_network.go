@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	compute "google.golang.org/api/compute/v1"
 )
 
@@ -28,52 +26,21 @@ type myGcpComputeNetworksItemsContainer struct {
 	Items []*compute.Network `json:"items"`
 }
 
-// GcpComputeNetworksColumns returns the list of columns for gcp_compute_network
-func (handler *GcpComputeHandler) GcpComputeNetworksColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("ipv4_range"),
-		table.TextColumn("auto_create_subnetworks"),
-		table.TextColumn("creation_timestamp"),
-		table.TextColumn("description"),
-		table.TextColumn("gateway_ipv4"),
-		table.BigIntColumn("id"),
-		table.TextColumn("kind"),
-		table.BigIntColumn("mtu"),
-		table.TextColumn("name"),
-		table.TextColumn("peerings"),
-		//table.TextColumn("peerings_auto_create_routes"),
-		//table.TextColumn("peerings_exchange_subnet_routes"),
-		//table.TextColumn("peerings_export_custom_routes"),
-		//table.TextColumn("peerings_export_subnet_routes_with_public_ip"),
-		//table.TextColumn("peerings_import_custom_routes"),
-		//table.TextColumn("peerings_import_subnet_routes_with_public_ip"),
-		//table.TextColumn("peerings_name"),
-		//table.TextColumn("peerings_network"),
-		//table.BigIntColumn("peerings_peer_mtu"),
-		//table.TextColumn("peerings_state"),
-		//table.TextColumn("peerings_state_details"),
-		table.TextColumn("routing_config"),
-		//table.TextColumn("routing_config_routing_mode"),
-		//table.TextColumn("self_link"),
-		table.TextColumn("subnetworks"),
-	}
-}
-
 // GcpComputeNetworksGenerate returns the rows in the table for all configured accounts
 func (handler *GcpComputeHandler) GcpComputeNetworksGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_network", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_network", utilities.DefaultGcpProjectID) {
 		results, err := handler.processAccountGcpComputeNetworks(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_compute_network", account.ProjectID) {
 				continue
 			}
@@ -91,16 +58,12 @@ func (handler *GcpComputeHandler) getGcpComputeNetworksNewServiceForAccount(ctx
 	var projectID string
 	var service *compute.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	}
+	service, err = handler.svcInterface.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_network",
@@ -157,7 +120,7 @@ func (handler *GcpComputeHandler) processAccountGcpComputeNetworks(ctx context.C
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_compute_network"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_compute_network"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_network",
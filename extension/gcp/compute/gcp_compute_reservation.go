@@ -20,8 +20,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	compute "google.golang.org/api/compute/v1"
 )
 
@@ -29,49 +27,21 @@ type myGcpComputeReservationsItemsContainer struct {
 	Items []*compute.Reservation `json:"items"`
 }
 
-// GcpComputeReservationsColumns returns the list of columns for gcp_compute_reservation
-func (handler *GcpComputeHandler) GcpComputeReservationsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("commitment"),
-		table.TextColumn("creation_timestamp"),
-		table.TextColumn("description"),
-		table.BigIntColumn("id"),
-		table.TextColumn("kind"),
-		table.TextColumn("name"),
-		//table.TextColumn("self_link"),
-		table.TextColumn("specific_reservation"),
-		table.TextColumn("specific_reservation_required"),
-		//table.BigIntColumn("specific_reservation_count"),
-		//table.BigIntColumn("specific_reservation_in_use_count"),
-		//table.TextColumn("specific_reservation_instance_properties"),
-		//table.TextColumn("specific_reservation_instance_properties_guest_accelerators"),
-		//table.BigIntColumn("specific_reservation_instance_properties_guest_accelerators_accelerator_count"),
-		//table.TextColumn("specific_reservation_instance_properties_guest_accelerators_accelerator_type"),
-		//table.TextColumn("specific_reservation_instance_properties_local_ssds"),
-		//table.BigIntColumn("specific_reservation_instance_properties_local_ssds_disk_size_gb"),
-		//table.TextColumn("specific_reservation_instance_properties_local_ssds_interface"),
-		//table.TextColumn("specific_reservation_instance_properties_machine_type"),
-		//table.TextColumn("specific_reservation_instance_properties_min_cpu_platform"),
-		table.TextColumn("status"),
-		table.TextColumn("zone"),
-	}
-}
-
 // GcpComputeReservationsGenerate returns the rows in the table for all configured accounts
 func (handler *GcpComputeHandler) GcpComputeReservationsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_reservation", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_reservation", utilities.DefaultGcpProjectID) {
 		results, err := handler.processAccountGcpComputeReservations(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_compute_reservation", account.ProjectID) {
 				continue
 			}
@@ -89,16 +59,12 @@ func (handler *GcpComputeHandler) getGcpComputeReservationsNewServiceForAccount(
 	var projectID string
 	var service *compute.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	}
+	service, err = handler.svcInterface.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_reservation",
@@ -161,7 +127,7 @@ func (handler *GcpComputeHandler) processAccountGcpComputeReservations(ctx conte
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_compute_reservation"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_compute_reservation"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_reservation",
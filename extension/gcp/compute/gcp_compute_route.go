@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	compute "google.golang.org/api/compute/v1"
 )
 
@@ -28,51 +26,21 @@ type myGcpComputeRoutesItemsContainer struct {
 	Items []*compute.Route `json:"items"`
 }
 
-// GcpComputeRoutesColumns returns the list of columns for gcp_compute_route
-func (handler *GcpComputeHandler) GcpComputeRoutesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("creation_timestamp"),
-		table.TextColumn("description"),
-		table.TextColumn("dest_range"),
-		table.BigIntColumn("id"),
-		table.TextColumn("kind"),
-		table.TextColumn("name"),
-		table.TextColumn("network"),
-		table.TextColumn("next_hop_gateway"),
-		table.TextColumn("next_hop_ilb"),
-		table.TextColumn("next_hop_instance"),
-		table.TextColumn("next_hop_ip"),
-		table.TextColumn("next_hop_network"),
-		table.TextColumn("next_hop_peering"),
-		table.TextColumn("next_hop_vpn_tunnel"),
-		table.BigIntColumn("priority"),
-		//table.TextColumn("self_link"),
-		table.TextColumn("tags"),
-		table.TextColumn("warnings"),
-		//table.TextColumn("warnings_code"),
-		//table.TextColumn("warnings_data"),
-		//table.TextColumn("warnings_data_key"),
-		//table.TextColumn("warnings_data_value"),
-		//table.TextColumn("warnings_message"),
-
-	}
-}
-
 // GcpComputeRoutesGenerate returns the rows in the table for all configured accounts
 func (handler *GcpComputeHandler) GcpComputeRoutesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_route", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_route", utilities.DefaultGcpProjectID) {
 		results, err := handler.processAccountGcpComputeRoutes(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_compute_route", account.ProjectID) {
 				continue
 			}
@@ -90,16 +58,12 @@ func (handler *GcpComputeHandler) getGcpComputeRoutesNewServiceForAccount(ctx co
 	var projectID string
 	var service *compute.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	}
+	service, err = handler.svcInterface.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_route",
@@ -156,7 +120,7 @@ func (handler *GcpComputeHandler) processAccountGcpComputeRoutes(ctx context.Con
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_compute_route"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_compute_route"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_route",
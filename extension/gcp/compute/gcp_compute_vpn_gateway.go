@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	compute "google.golang.org/api/compute/v1"
 )
 
@@ -28,41 +26,21 @@ type myGcpComputeVpnGatewaysItemsContainer struct {
 	Items []*compute.VpnGateway `json:"items"`
 }
 
-// GcpComputeVpnGatewaysColumns returns the list of columns for gcp_compute_vpn_gateway
-func (handler *GcpComputeHandler) GcpComputeVpnGatewaysColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("creation_timestamp"),
-		table.TextColumn("description"),
-		table.BigIntColumn("id"),
-		table.TextColumn("kind"),
-		table.TextColumn("label_fingerprint"),
-		table.TextColumn("labels"),
-		table.TextColumn("name"),
-		table.TextColumn("network"),
-		table.TextColumn("region"),
-		//table.TextColumn("self_link"),
-		table.TextColumn("vpn_interfaces"),
-		//table.BigIntColumn("vpn_interfaces_id"),
-		//table.TextColumn("vpn_interfaces_ip_address"),
-
-	}
-}
-
 // GcpComputeVpnGatewaysGenerate returns the rows in the table for all configured accounts
 func (handler *GcpComputeHandler) GcpComputeVpnGatewaysGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_vpn_gateway", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_vpn_gateway", utilities.DefaultGcpProjectID) {
 		results, err := handler.processAccountGcpComputeVpnGateways(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_compute_vpn_gateway", account.ProjectID) {
 				continue
 			}
@@ -80,16 +58,12 @@ func (handler *GcpComputeHandler) getGcpComputeVpnGatewaysNewServiceForAccount(c
 	var projectID string
 	var service *compute.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	}
+	service, err = handler.svcInterface.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_vpn_gateway",
@@ -149,7 +123,7 @@ func (handler *GcpComputeHandler) processAccountGcpComputeVpnGateways(ctx contex
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_compute_vpn_gateway"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_compute_vpn_gateway"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_vpn_gateway",
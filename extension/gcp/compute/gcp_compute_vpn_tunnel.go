@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	compute "google.golang.org/api/compute/v1"
 )
 
@@ -28,49 +26,21 @@ type myGcpComputeVpnTunnelsItemsContainer struct {
 	Items []*compute.VpnTunnel `json:"items"`
 }
 
-// GcpComputeVpnTunnelsColumns returns the list of columns for gcp_compute_vpn_tunnel
-func (handler *GcpComputeHandler) GcpComputeVpnTunnelsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("creation_timestamp"),
-		table.TextColumn("description"),
-		table.TextColumn("detailed_status"),
-		table.BigIntColumn("id"),
-		table.BigIntColumn("ike_version"),
-		table.TextColumn("kind"),
-		table.TextColumn("local_traffic_selector"),
-		table.TextColumn("name"),
-		table.TextColumn("peer_external_gateway"),
-		table.BigIntColumn("peer_external_gateway_interface"),
-		table.TextColumn("peer_gcp_gateway"),
-		table.TextColumn("peer_ip"),
-		table.TextColumn("region"),
-		table.TextColumn("remote_traffic_selector"),
-		table.TextColumn("router"),
-		//table.TextColumn("self_link"),
-		table.TextColumn("shared_secret"),
-		table.TextColumn("shared_secret_hash"),
-		table.TextColumn("status"),
-		table.TextColumn("target_vpn_gateway"),
-		table.TextColumn("vpn_gateway"),
-		table.BigIntColumn("vpn_gateway_interface"),
-	}
-}
-
 // GcpComputeVpnTunnelsGenerate returns the rows in the table for all configured accounts
 func (handler *GcpComputeHandler) GcpComputeVpnTunnelsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_vpn_tunnel", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_compute_vpn_tunnel", utilities.DefaultGcpProjectID) {
 		results, err := handler.processAccountGcpComputeVpnTunnels(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_compute_vpn_tunnel", account.ProjectID) {
 				continue
 			}
@@ -88,16 +58,12 @@ func (handler *GcpComputeHandler) getGcpComputeVpnTunnelsNewServiceForAccount(ct
 	var projectID string
 	var service *compute.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = handler.svcInterface.NewService(ctx)
 	}
+	service, err = handler.svcInterface.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_vpn_tunnel",
@@ -157,7 +123,7 @@ func (handler *GcpComputeHandler) processAccountGcpComputeVpnTunnels(ctx context
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_compute_vpn_tunnel"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_compute_vpn_tunnel"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_compute_vpn_tunnel",
@@ -0,0 +1,35 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package compute
+
+import (
+	_ "embed"
+
+	"github.com/Uptycs/cloudquery/utilities"
+)
+
+//go:embed table_config.json
+var defaultTableConfig []byte
+
+func init() {
+	utilities.RegisterTableConfigFile("gcp/compute/table_config.json", defaultTableConfig)
+
+	handler := NewGcpComputeHandler(NewGcpComputeImpl())
+	utilities.RegisterTable(utilities.TableDefinition{Name: "gcp_compute_instance", Generate: handler.GcpComputeInstancesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "gcp_compute_network", Generate: handler.GcpComputeNetworksGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "gcp_compute_disk", Generate: handler.GcpComputeDisksGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "gcp_compute_image", Generate: handler.GcpComputeImagesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "gcp_compute_interconnect", Generate: handler.GcpComputeInterconnectsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "gcp_compute_route", Generate: handler.GcpComputeRoutesGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "gcp_compute_reservation", Generate: handler.GcpComputeReservationsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "gcp_compute_router", Generate: handler.GcpComputeRoutersGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "gcp_compute_vpn_tunnel", Generate: handler.GcpComputeVpnTunnelsGenerate})
+	utilities.RegisterTable(utilities.TableDefinition{Name: "gcp_compute_vpn_gateway", Generate: handler.GcpComputeVpnGatewaysGenerate})
+}
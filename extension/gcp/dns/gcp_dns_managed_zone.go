@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	gcpdns "google.golang.org/api/dns/v1beta2"
 )
 
@@ -28,71 +26,21 @@ type myGcpDNSManagedZonesItemsContainer struct {
 	Items []*gcpdns.ManagedZone `json:"items"`
 }
 
-// GcpDNSManagedZonesColumns returns the list of columns for gcp_dns_managed_zone
-func GcpDNSManagedZonesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("creation_time"),
-		table.TextColumn("description"),
-		table.TextColumn("dns_name"),
-		table.TextColumn("dnssec_config"),
-		//table.TextColumn("dnssec_config_default_key_specs"),
-		//table.TextColumn("dnssec_config_default_key_specs_algorithm"),
-		//table.BigIntColumn("dnssec_config_default_key_specs_key_length"),
-		//table.TextColumn("dnssec_config_default_key_specs_key_type"),
-		//table.TextColumn("dnssec_config_default_key_specs_kind"),
-		//table.TextColumn("dnssec_config_kind"),
-		//table.TextColumn("dnssec_config_non_existence"),
-		//table.TextColumn("dnssec_config_state"),
-		table.TextColumn("forwarding_config"),
-		//table.TextColumn("forwarding_config_kind"),
-		//table.TextColumn("forwarding_config_target_name_servers"),
-		//table.TextColumn("forwarding_config_target_name_servers_forwarding_path"),
-		//table.TextColumn("forwarding_config_target_name_servers_ipv4_address"),
-		//table.TextColumn("forwarding_config_target_name_servers_kind"),
-		table.BigIntColumn("id"),
-		table.TextColumn("kind"),
-		table.TextColumn("labels"),
-		table.TextColumn("name"),
-		table.TextColumn("name_server_set"),
-		table.TextColumn("name_servers"),
-		table.TextColumn("peering_config"),
-		//table.TextColumn("peering_config_kind"),
-		//table.TextColumn("peering_config_target_network"),
-		//table.TextColumn("peering_config_target_network_deactivate_time"),
-		//table.TextColumn("peering_config_target_network_kind"),
-		//table.TextColumn("peering_config_target_network_network_url"),
-		table.TextColumn("private_visibility_config"),
-		//table.TextColumn("private_visibility_config_kind"),
-		//table.TextColumn("private_visibility_config_networks"),
-		//table.TextColumn("private_visibility_config_networks_kind"),
-		//table.TextColumn("private_visibility_config_networks_network_url"),
-		table.TextColumn("reverse_lookup_config"),
-		//table.TextColumn("reverse_lookup_config_kind"),
-		table.TextColumn("service_directory_config"),
-		//table.TextColumn("service_directory_config_kind"),
-		//table.TextColumn("service_directory_config_namespace"),
-		//table.TextColumn("service_directory_config_namespace_deletion_time"),
-		//table.TextColumn("service_directory_config_namespace_kind"),
-		//table.TextColumn("service_directory_config_namespace_namespace_url"),
-		table.TextColumn("visibility"),
-	}
-}
-
 // GcpDNSManagedZonesGenerate returns the rows in the table for all configured accounts
 func GcpDNSManagedZonesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_dns_managed_zone", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_dns_managed_zone", utilities.DefaultGcpProjectID) {
 		results, err := processAccountGcpDNSManagedZones(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_dns_managed_zone", account.ProjectID) {
 				continue
 			}
@@ -110,16 +58,12 @@ func getGcpDNSManagedZonesNewServiceForAccount(ctx context.Context, account *uti
 	var projectID string
 	var service *gcpdns.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = gcpdns.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = gcpdns.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = gcpdns.NewService(ctx)
 	}
+	service, err = gcpdns.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_dns_managed_zone",
@@ -172,7 +116,7 @@ func processAccountGcpDNSManagedZones(ctx context.Context, queryContext table.Qu
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_dns_managed_zone"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_dns_managed_zone"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_dns_managed_zone",
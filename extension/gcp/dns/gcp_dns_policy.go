@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	gcpdns "google.golang.org/api/dns/v1beta2"
 )
 
@@ -28,43 +26,21 @@ type myGcpDNSPoliciesItemsContainer struct {
 	Items []*gcpdns.Policy `json:"items"`
 }
 
-// GcpDNSPoliciesColumns returns the list of columns for gcp_dns_policy
-func GcpDNSPoliciesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("alternative_name_server_config"),
-		//table.TextColumn("alternative_name_server_config_kind"),
-		//table.TextColumn("alternative_name_server_config_target_name_servers"),
-		//table.TextColumn("alternative_name_server_config_target_name_servers_forwarding_path"),
-		//table.TextColumn("alternative_name_server_config_target_name_servers_ipv4_address"),
-		//table.TextColumn("alternative_name_server_config_target_name_servers_kind"),
-		table.TextColumn("description"),
-		table.TextColumn("enable_inbound_forwarding"),
-		table.TextColumn("enable_logging"),
-		table.BigIntColumn("id"),
-		table.TextColumn("kind"),
-		table.TextColumn("name"),
-		table.TextColumn("networks"),
-		//table.TextColumn("networks_kind"),
-		//table.TextColumn("networks_network_url"),
-
-	}
-}
-
 // GcpDNSPoliciesGenerate returns the rows in the table for all configured accounts
 func GcpDNSPoliciesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_dns_policy", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_dns_policy", utilities.DefaultGcpProjectID) {
 		results, err := processAccountGcpDNSPolicies(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_dns_policy", account.ProjectID) {
 				continue
 			}
@@ -82,16 +58,12 @@ func getGcpDNSPoliciesNewServiceForAccount(ctx context.Context, account *utiliti
 	var projectID string
 	var service *gcpdns.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = gcpdns.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = gcpdns.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = gcpdns.NewService(ctx)
 	}
+	service, err = gcpdns.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_dns_policy",
@@ -144,7 +116,7 @@ func processAccountGcpDNSPolicies(ctx context.Context, queryContext table.QueryC
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_dns_policy"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_dns_policy"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_dns_policy",
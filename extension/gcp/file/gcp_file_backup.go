@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	gcpfile "google.golang.org/api/file/v1beta1"
 )
 
@@ -28,38 +26,21 @@ type myGcpFileBackupsItemsContainer struct {
 	Items []*gcpfile.Backup `json:"items"`
 }
 
-// GcpFileBackupsColumns returns the list of columns for gcp_file_backup
-func GcpFileBackupsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.BigIntColumn("capacity_gb"),
-		table.TextColumn("create_time"),
-		table.TextColumn("description"),
-		table.BigIntColumn("download_bytes"),
-		table.TextColumn("labels"),
-		table.TextColumn("name"),
-		table.TextColumn("source_file_share"),
-		table.TextColumn("source_instance"),
-		//table.TextColumn("source_instance_tier"),
-		table.TextColumn("state"),
-		table.BigIntColumn("storage_bytes"),
-	}
-}
-
 // GcpFileBackupsGenerate returns the rows in the table for all configured accounts
 func GcpFileBackupsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_file_backup", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_file_backup", utilities.DefaultGcpProjectID) {
 		results, err := processAccountGcpFileBackups(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_file_backup", account.ProjectID) {
 				continue
 			}
@@ -77,16 +58,12 @@ func getGcpFileBackupsNewServiceForAccount(ctx context.Context, account *utiliti
 	var projectID string
 	var service *gcpfile.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = gcpfile.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = gcpfile.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = gcpfile.NewService(ctx)
 	}
+	service, err = gcpfile.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_file_backup",
@@ -139,7 +116,7 @@ func processAccountGcpFileBackups(ctx context.Context, queryContext table.QueryC
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_file_backup"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_file_backup"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_file_backup",
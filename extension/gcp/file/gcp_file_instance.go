@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	gcpfile "google.golang.org/api/file/v1beta1"
 )
 
@@ -28,50 +26,21 @@ type myGcpFileInstancesItemsContainer struct {
 	Items []*gcpfile.Instance `json:"items"`
 }
 
-// GcpFileInstancesColumns returns the list of columns for gcp_file_instance
-func GcpFileInstancesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("create_time"),
-		table.TextColumn("description"),
-		table.TextColumn("etag"),
-		table.TextColumn("file_shares"),
-		//table.BigIntColumn("file_shares_capacity_gb"),
-		//table.TextColumn("file_shares_name"),
-		//table.TextColumn("file_shares_nfs_export_options"),
-		//table.TextColumn("file_shares_nfs_export_options_access_mode"),
-		//table.BigIntColumn("file_shares_nfs_export_options_anon_gid"),
-		//table.BigIntColumn("file_shares_nfs_export_options_anon_uid"),
-		//table.TextColumn("file_shares_nfs_export_options_ip_ranges"),
-		//table.TextColumn("file_shares_nfs_export_options_squash_mode"),
-		//table.TextColumn("file_shares_source_backup"),
-		table.TextColumn("labels"),
-		table.TextColumn("name"),
-		table.TextColumn("networks"),
-		//table.TextColumn("networks_ip_addresses"),
-		//table.TextColumn("networks_modes"),
-		//table.TextColumn("networks_network"),
-		//table.TextColumn("networks_reserved_ip_range"),
-		table.TextColumn("state"),
-		table.TextColumn("status_message"),
-		table.TextColumn("tier"),
-	}
-}
-
 // GcpFileInstancesGenerate returns the rows in the table for all configured accounts
 func GcpFileInstancesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_file_instance", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_file_instance", utilities.DefaultGcpProjectID) {
 		results, err := processAccountGcpFileInstances(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_file_instance", account.ProjectID) {
 				continue
 			}
@@ -89,16 +58,12 @@ func getGcpFileInstancesNewServiceForAccount(ctx context.Context, account *utili
 	var projectID string
 	var service *gcpfile.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = gcpfile.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = gcpfile.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = gcpfile.NewService(ctx)
 	}
+	service, err = gcpfile.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_file_instance",
@@ -151,7 +116,7 @@ func processAccountGcpFileInstances(ctx context.Context, queryContext table.Quer
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_file_instance"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_file_instance"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_file_instance",
@@ -10,7 +10,9 @@ package gcp
 
 import (
 	"context"
+
 	"github.com/Uptycs/basequery-go/plugin/table"
+	"github.com/Uptycs/cloudquery/utilities"
 )
 
 // ShouldProcessProject returns false if given project is not supposed to be processed for given table
@@ -26,13 +28,13 @@ func ShouldProcessZone(tableName string, projectId string, zone string) bool {
 }
 
 // ShouldProcessRow returns false if given row is not supposed to be processed for given table
-// Default implementation is no-op (return true always). Add custom logic here if required
+// Rows excluded by the filter rules of the table in extension_config.json are skipped
 func ShouldProcessRow(osqCtx context.Context, queryContext table.QueryContext, tableName string, projectId string, zone string, row map[string]interface{}) bool {
-	return true
+	return utilities.MatchesRowFilter(osqCtx, tableName, row)
 }
 
 // ShouldProcessEvent returns false if given event is not supposed to be processed for given table
-// Default implementation is no-op (return true always). Add custom logic here if required
-func ShouldProcessEvent(tableName string, projectId string, zone string, row map[string]string) bool {
-	return true
+// Events excluded by the filter rules of the table in extension_config.json are skipped
+func ShouldProcessEvent(osqCtx context.Context, tableName string, projectId string, zone string, row map[string]string) bool {
+	return utilities.MatchesEventFilter(osqCtx, tableName, row)
 }
@@ -20,8 +20,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	gcpfunction "google.golang.org/api/cloudfunctions/v1beta2"
 )
 
@@ -29,60 +27,21 @@ type myGcpCloudFunctionsItemsContainer struct {
 	Items []*gcpfunction.CloudFunction `json:"items"`
 }
 
-// GcpCloudFunctionsColumns returns the list of columns for gcp_cloud_function
-func GcpCloudFunctionsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.BigIntColumn("available_memory_mb"),
-		table.TextColumn("entry_point"),
-		table.TextColumn("environment_variables"),
-		table.TextColumn("event_trigger"),
-		//table.TextColumn("event_trigger_event_type"),
-		//table.TextColumn("event_trigger_failure_policy"),
-		//table.TextColumn("event_trigger_failure_policy_retry"),
-		//table.TextColumn("event_trigger_resource"),
-		//table.TextColumn("event_trigger_service"),
-		table.TextColumn("https_trigger"),
-		//table.TextColumn("https_trigger_url"),
-		table.TextColumn("labels"),
-		table.TextColumn("latest_operation"),
-		table.BigIntColumn("max_instances"),
-		table.TextColumn("name"),
-		table.TextColumn("network"),
-		table.TextColumn("runtime"),
-		table.TextColumn("service_account"),
-		table.TextColumn("source_archive_url"),
-		table.TextColumn("source_repository"),
-		table.TextColumn("source_repository_url"),
-		//table.TextColumn("source_repository_branch"),
-		//table.TextColumn("source_repository_deployed_revision"),
-		//table.TextColumn("source_repository_repository_url"),
-		//table.TextColumn("source_repository_revision"),
-		//table.TextColumn("source_repository_source_path"),
-		//table.TextColumn("source_repository_tag"),
-		table.TextColumn("source_upload_url"),
-		table.TextColumn("status"),
-		table.TextColumn("timeout"),
-		table.TextColumn("update_time"),
-		table.BigIntColumn("version_id"),
-		table.TextColumn("vpc_connector"),
-	}
-}
-
 // GcpCloudFunctionsGenerate returns the rows in the table for all configured accounts
 func GcpCloudFunctionsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_cloud_function", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_cloud_function", utilities.DefaultGcpProjectID) {
 		results, err := processAccountGcpCloudFunctions(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_cloud_function", account.ProjectID) {
 				continue
 			}
@@ -100,16 +59,12 @@ func getGcpCloudFunctionsNewServiceForAccount(ctx context.Context, account *util
 	var projectID string
 	var service *gcpfunction.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = gcpfunction.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = gcpfunction.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = gcpfunction.NewService(ctx)
 	}
+	service, err = gcpfunction.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_cloud_function",
@@ -160,7 +115,7 @@ func processAccountGcpCloudFunctions(ctx context.Context, queryContext table.Que
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_cloud_function"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_cloud_function"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_cloud_function",
@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	gcpiam "google.golang.org/api/iam/v1"
 )
 
@@ -28,34 +26,21 @@ type myGcpIamRolesItemsContainer struct {
 	Items []*gcpiam.Role `json:"items"`
 }
 
-// GcpIamRolesColumns returns the list of columns for gcp_iam_role
-func GcpIamRolesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("deleted"),
-		table.TextColumn("description"),
-		table.TextColumn("etag"),
-		table.TextColumn("included_permissions"),
-		table.TextColumn("name"),
-		table.TextColumn("stage"),
-		table.TextColumn("title"),
-	}
-}
-
 // GcpIamRolesGenerate returns the rows in the table for all configured accounts
 func GcpIamRolesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_iam_role", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_iam_role", utilities.DefaultGcpProjectID) {
 		results, err := processAccountGcpIamRoles(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_iam_role", account.ProjectID) {
 				continue
 			}
@@ -73,16 +58,12 @@ func getGcpIamRolesNewServiceForAccount(ctx context.Context, account *utilities.
 	var projectID string
 	var service *gcpiam.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = gcpiam.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = gcpiam.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = gcpiam.NewService(ctx)
 	}
+	service, err = gcpiam.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_iam_role",
@@ -135,7 +116,7 @@ func processAccountGcpIamRoles(ctx context.Context, queryContext table.QueryCont
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_iam_role"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_iam_role"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_iam_role",
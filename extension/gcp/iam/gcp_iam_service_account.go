@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	gcpiam "google.golang.org/api/iam/v1"
 )
 
@@ -28,35 +26,21 @@ type myGcpIamServiceAccountsItemsContainer struct {
 	Items []*gcpiam.ServiceAccount `json:"items"`
 }
 
-// GcpIamServiceAccountsColumns returns the list of columns for gcp_iam_service_account
-func GcpIamServiceAccountsColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("description"),
-		table.TextColumn("disabled"),
-		table.TextColumn("display_name"),
-		table.TextColumn("email"),
-		table.TextColumn("etag"),
-		table.TextColumn("name"),
-		table.TextColumn("oauth2_client_id"),
-		table.TextColumn("project_id"),
-		table.TextColumn("unique_id"),
-	}
-}
-
 // GcpIamServiceAccountsGenerate returns the rows in the table for all configured accounts
 func GcpIamServiceAccountsGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_iam_service_account", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_iam_service_account", utilities.DefaultGcpProjectID) {
 		results, err := processAccountGcpIamServiceAccounts(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_iam_service_account", account.ProjectID) {
 				continue
 			}
@@ -74,16 +58,12 @@ func getGcpIamServiceAccountsNewServiceForAccount(ctx context.Context, account *
 	var projectID string
 	var service *gcpiam.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = gcpiam.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = gcpiam.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = gcpiam.NewService(ctx)
 	}
+	service, err = gcpiam.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_iam_service_account",
@@ -136,7 +116,7 @@ func processAccountGcpIamServiceAccounts(ctx context.Context, queryContext table
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_iam_service_account"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_iam_service_account"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_iam_service_account",
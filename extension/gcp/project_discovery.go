@@ -0,0 +1,261 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package gcp
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Uptycs/cloudquery/utilities"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+const (
+	defaultDiscoveryInterval = 3600 // seconds
+	discoveryTimeout         = 10 * time.Minute
+	projectStateActive       = "ACTIVE"
+)
+
+// discoveredProjects holds the projects discovered for an organization or folder
+type discoveredProjects struct {
+	accounts  []utilities.ExtensionConfigurationGcpAccount
+	refreshed time.Time
+}
+
+var (
+	projectMutex sync.Mutex
+	// projectCache is organization or folder resource name => discovered projects
+	projectCache = make(map[string]*discoveredProjects)
+	// projectGeneration is incremented whenever projectCache is invalidated
+	projectGeneration uint64
+	// projectDiscoveryReset wakes up the discovery started with StartProjectDiscovery after projectCache is invalidated
+	projectDiscoveryReset = make(chan struct{}, 1)
+	timeNow               = time.Now
+)
+
+// getDiscoveryParent returns the resource name of the organization or folder to discover projects from,
+// empty string if given account is a single project
+func getDiscoveryParent(account *utilities.ExtensionConfigurationGcpAccount) string {
+	if account.OrganizationID != "" {
+		return "organizations/" + account.OrganizationID
+	}
+	if account.FolderID != "" {
+		return "folders/" + account.FolderID
+	}
+	return ""
+}
+
+func getDiscoveryInterval(account *utilities.ExtensionConfigurationGcpAccount) time.Duration {
+	if account.DiscoveryInterval <= 0 {
+		return defaultDiscoveryInterval * time.Second
+	}
+	return time.Duration(account.DiscoveryInterval) * time.Second
+}
+
+// GetAccounts returns the configured GCP project accounts followed by the projects discovered for the
+// accounts with organizationId or folderId set. Projects are listed again when the discovery interval
+// has passed. If listing fails, previously discovered projects are returned.
+// Projects which are configured explicitly are not duplicated
+func GetAccounts(ctx context.Context) []utilities.ExtensionConfigurationGcpAccount {
+	configured := utilities.GetConfiguration(ctx).Extension.ExtConfGcp.Accounts
+	accounts := make([]utilities.ExtensionConfigurationGcpAccount, 0, len(configured))
+	projectIds := make(map[string]bool)
+	for _, account := range configured {
+		if getDiscoveryParent(&account) == "" {
+			accounts = append(accounts, account)
+			projectIds[account.ProjectID] = true
+		}
+	}
+	for idx := range configured {
+		if getDiscoveryParent(&configured[idx]) == "" {
+			continue
+		}
+		for _, project := range getDiscoveredProjects(ctx, &configured[idx]) {
+			if projectIds[project.ProjectID] {
+				continue
+			}
+			projectIds[project.ProjectID] = true
+			accounts = append(accounts, project)
+		}
+	}
+	return accounts
+}
+
+// InvalidateDiscoveredProjects forgets the discovered projects, e.g. after extension configuration has changed.
+// Projects are listed again when they are needed, or right away by the discovery started with StartProjectDiscovery
+func InvalidateDiscoveredProjects() {
+	projectMutex.Lock()
+	defer projectMutex.Unlock()
+	projectCache = make(map[string]*discoveredProjects)
+	projectGeneration++
+	select {
+	case projectDiscoveryReset <- struct{}{}:
+	default:
+	}
+}
+
+// getRefreshInterval returns the shortest discovery interval of the organizations and folders in the current
+// configuration, the default interval if there are none
+func getRefreshInterval() time.Duration {
+	var interval time.Duration
+	for _, account := range utilities.CurrentConfiguration().Extension.ExtConfGcp.Accounts {
+		if getDiscoveryParent(&account) == "" {
+			continue
+		}
+		accountInterval := getDiscoveryInterval(&account)
+		if interval == 0 || accountInterval < interval {
+			interval = accountInterval
+		}
+	}
+	if interval == 0 {
+		return defaultDiscoveryInterval * time.Second
+	}
+	return interval
+}
+
+// StartProjectDiscovery lists the projects of the organizations and folders in the configuration,
+// then keeps refreshing them in the background until ctx is cancelled.
+// The configuration is read again on every refresh, and a reload triggers a refresh right away
+func StartProjectDiscovery(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			GetAccounts(ctx)
+			timer := time.NewTimer(getRefreshInterval())
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-projectDiscoveryReset:
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// getDiscoveredProjects returns the cached projects of the organization or folder of account, listing them again if the
+// discovery interval has passed. The projects are listed without holding projectMutex, so queries of other tables are
+// not blocked meanwhile. They are listed with a context of their own, as the result is shared by all queries
+func getDiscoveredProjects(ctx context.Context, account *utilities.ExtensionConfigurationGcpAccount) []utilities.ExtensionConfigurationGcpAccount {
+	parent := getDiscoveryParent(account)
+	projectMutex.Lock()
+	projects, found := projectCache[parent]
+	generation := projectGeneration
+	projectMutex.Unlock()
+	if found && timeNow().Sub(projects.refreshed) < getDiscoveryInterval(account) {
+		return projects.accounts
+	}
+
+	discoveryCtx, cancel := context.WithTimeout(utilities.ContextWithConfiguration(context.Background(), utilities.GetConfiguration(ctx)), discoveryTimeout)
+	defer cancel()
+	accounts, err := discoverProjects(discoveryCtx, account, parent)
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"parent":    parent,
+			"task":      "ListProjects",
+			"errString": err.Error(),
+		}).Error("failed to discover projects")
+		var previous []utilities.ExtensionConfigurationGcpAccount
+		if found {
+			previous = projects.accounts
+		}
+		// Retry after the interval rather than on every query
+		storeDiscoveredProjects(parent, generation, &discoveredProjects{accounts: previous, refreshed: timeNow()})
+		return previous
+	}
+	utilities.GetLogger().WithFields(log.Fields{
+		"parent":   parent,
+		"projects": len(accounts),
+	}).Info("discovered projects")
+	storeDiscoveredProjects(parent, generation, &discoveredProjects{accounts: accounts, refreshed: timeNow()})
+	return accounts
+}
+
+// storeDiscoveredProjects caches projects of given organization or folder unless the cache was invalidated since
+// generation was read, in which case they may have been listed with an outdated configuration
+func storeDiscoveredProjects(parent string, generation uint64, projects *discoveredProjects) {
+	projectMutex.Lock()
+	defer projectMutex.Unlock()
+	if generation == projectGeneration {
+		projectCache[parent] = projects
+	}
+}
+
+// discoverProjects lists the ACTIVE projects under parent and its ACTIVE folders
+func discoverProjects(ctx context.Context, account *utilities.ExtensionConfigurationGcpAccount, parent string) ([]utilities.ExtensionConfigurationGcpAccount, error) {
+	var includePattern, excludePattern *regexp.Regexp
+	var err error
+	if account.ProjectIDPattern != "" {
+		if includePattern, err = regexp.Compile(account.ProjectIDPattern); err != nil {
+			return nil, err
+		}
+	}
+	if account.ExcludeProjectIDPattern != "" {
+		if excludePattern, err = regexp.Compile(account.ExcludeProjectIDPattern); err != nil {
+			return nil, err
+		}
+	}
+	service, err := cloudresourcemanager.NewService(ctx, GetClientOptions(ctx, account)...)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]utilities.ExtensionConfigurationGcpAccount, 0)
+	parents := []string{parent}
+	for len(parents) > 0 {
+		parent, parents = parents[0], parents[1:]
+		err := service.Projects.List().Parent(parent).Pages(ctx, func(page *cloudresourcemanager.ListProjectsResponse) error {
+			for _, project := range page.Projects {
+				if project.State != projectStateActive || !matchesProjectLabels(account.ProjectLabels, project.Labels) {
+					continue
+				}
+				if (includePattern != nil && !includePattern.MatchString(project.ProjectId)) ||
+					(excludePattern != nil && excludePattern.MatchString(project.ProjectId)) {
+					continue
+				}
+				accounts = append(accounts, utilities.ExtensionConfigurationGcpAccount{
+					KeyFile:   account.KeyFile,
+					ProjectID: project.ProjectId,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = service.Folders.List().Parent(parent).Pages(ctx, func(page *cloudresourcemanager.ListFoldersResponse) error {
+			for _, folder := range page.Folders {
+				if folder.State == projectStateActive {
+					parents = append(parents, folder.Name)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return accounts, nil
+}
+
+func matchesProjectLabels(expected map[string]string, labels map[string]string) bool {
+	for key, value := range expected {
+		label, found := labels[key]
+		if !found || (value != "" && label != value) {
+			return false
+		}
+	}
+	return true
+}
@@ -19,8 +19,6 @@ import (
 	extgcp "github.com/Uptycs/cloudquery/extension/gcp"
 	"github.com/Uptycs/cloudquery/utilities"
 
-	"google.golang.org/api/option"
-
 	gcpsql "google.golang.org/api/sqladmin/v1beta4"
 )
 
@@ -28,39 +26,21 @@ type myGcpSQLDatabasesItemsContainer struct {
 	Items []*gcpsql.Database `json:"items"`
 }
 
-// GcpSQLDatabasesColumns returns the list of columns for gcp_sql_database
-func GcpSQLDatabasesColumns() []table.ColumnDefinition {
-	return []table.ColumnDefinition{
-		table.TextColumn("project_id"),
-		table.TextColumn("charset"),
-		table.TextColumn("collation"),
-		table.TextColumn("etag"),
-		table.TextColumn("instance"),
-		table.TextColumn("kind"),
-		table.TextColumn("name"),
-		table.TextColumn("project"),
-		//table.TextColumn("self_link"),
-		table.TextColumn("sqlserver_database_details"),
-		//table.BigIntColumn("sqlserver_database_details_compatibility_level"),
-		//table.TextColumn("sqlserver_database_details_recovery_model"),
-
-	}
-}
-
 // GcpSQLDatabasesGenerate returns the rows in the table for all configured accounts
 func GcpSQLDatabasesGenerate(osqCtx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 	ctx, cancel := context.WithCancel(osqCtx)
 	defer cancel()
 
 	resultMap := make([]map[string]string, 0)
+	accounts := extgcp.GetAccounts(ctx)
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_sql_database", utilities.DefaultGcpProjectID) {
+	if len(utilities.GetConfiguration(osqCtx).Extension.ExtConfGcp.Accounts) == 0 && extgcp.ShouldProcessProject("gcp_sql_database", utilities.DefaultGcpProjectID) {
 		results, err := processAccountGcpSQLDatabases(ctx, queryContext, nil)
 		if err == nil {
 			resultMap = append(resultMap, results...)
 		}
 	} else {
-		for _, account := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
+		for _, account := range accounts {
 			if !extgcp.ShouldProcessProject("gcp_sql_database", account.ProjectID) {
 				continue
 			}
@@ -78,16 +58,12 @@ func getGcpSQLDatabasesNewServiceForAccount(ctx context.Context, account *utilit
 	var projectID string
 	var service *gcpsql.Service
 	var err error
-	if account != nil && account.KeyFile != "" {
-		projectID = account.ProjectID
-		service, err = gcpsql.NewService(ctx, option.WithCredentialsFile(account.KeyFile))
-	} else if account != nil && account.ProjectID != "" {
+	if account != nil && account.ProjectID != "" {
 		projectID = account.ProjectID
-		service, err = gcpsql.NewService(ctx)
 	} else {
 		projectID = utilities.DefaultGcpProjectID
-		service, err = gcpsql.NewService(ctx)
 	}
+	service, err = gcpsql.NewService(ctx, extgcp.GetClientOptions(ctx, account)...)
 	if err != nil {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_sql_database",
@@ -181,7 +157,7 @@ func getGcpSQLDatabasesRowsForKey(ctx context.Context, queryContext table.QueryC
 		}).Error("failed to marshal response")
 		return resultMap, err
 	}
-	tableConfig, ok := utilities.TableConfigurationMap["gcp_sql_database"]
+	tableConfig, ok := utilities.GetConfiguration(ctx).Tables["gcp_sql_database"]
 	if !ok {
 		utilities.GetLogger().WithFields(log.Fields{
 			"tableName": "gcp_sql_database",
@@ -10,9 +10,81 @@
 package gcp
 
 import (
+	"context"
+	"io/ioutil"
+	"net/http"
+
 	"github.com/Uptycs/cloudquery/utilities"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 )
 
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// GetClientOptions returns the options to create API clients for given account.
+// Credentials are read from the key file of the account if set, otherwise default credentials are used.
+// If a transport is set with utilities.SetHTTPTransport, requests (including token requests) are sent through it.
+// Credentials are cached per key file (see utilities.GetCachedSession), so their tokens are shared by
+// all projects and clients until they expire.
+func GetClientOptions(ctx context.Context, account *utilities.ExtensionConfigurationGcpAccount) []option.ClientOption {
+	transport := utilities.GetHTTPTransport()
+	keyFile := ""
+	if account != nil {
+		keyFile = account.KeyFile
+	}
+	opts, err := utilities.GetCachedSession(utilities.ProviderGcp, utilities.GetSessionCacheKey(utilities.ProviderGcp, keyFile), func() (interface{}, error) {
+		return newClientOptions(keyFile, transport)
+	})
+	if err != nil {
+		utilities.GetLogger().WithFields(log.Fields{
+			"keyFile":   keyFile,
+			"errString": err.Error(),
+		}).Error("failed to create credentials")
+		// Let the client report the error
+		if keyFile != "" {
+			return []option.ClientOption{option.WithCredentialsFile(keyFile)}
+		}
+		return nil
+	}
+	return opts.([]option.ClientOption)
+}
+
+// newClientOptions reads the credentials of keyFile (default credentials if empty).
+// They are created without the query context, as they outlive the query which created them
+func newClientOptions(keyFile string, transport http.RoundTripper) ([]option.ClientOption, error) {
+	ctx := context.Background()
+	if transport != nil {
+		// Token requests use the client of the context
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport})
+	}
+	var creds *google.Credentials
+	var err error
+	if keyFile != "" {
+		var data []byte
+		if data, err = ioutil.ReadFile(keyFile); err != nil {
+			return nil, err
+		}
+		creds, err = google.CredentialsFromJSON(ctx, data, cloudPlatformScope)
+	} else {
+		creds, err = google.FindDefaultCredentials(ctx, cloudPlatformScope)
+	}
+	if err != nil {
+		return nil, err
+	}
+	opts := []option.ClientOption{option.WithCredentials(creds)}
+	if transport == nil {
+		return opts, nil
+	}
+	authTransport, err := htransport.NewTransport(ctx, transport, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: authTransport})}, nil
+}
+
 // RowToMap converts JSON row into osquery row
 // If configured it will copy some metadata values into appropriate columns
 func RowToMap(row map[string]interface{}, projectID string, zone string, tableConfig *utilities.TableConfig) map[string]string {
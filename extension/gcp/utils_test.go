@@ -10,8 +10,15 @@
 package gcp
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/Uptycs/cloudquery/utilities"
 	"github.com/stretchr/testify/assert"
@@ -39,9 +46,130 @@ func TestRowToMap(t *testing.T) {
 
 	projName, zone := "test-project", "us-east4-zone1"
 	inRow := make(map[string]interface{})
-	tabConfig := utilities.TableConfigurationMap["test_table_1"]
+	tabConfig := utilities.CurrentConfiguration().Tables["test_table_1"]
 	outRow := RowToMap(inRow, projName, zone, tabConfig)
 
 	assert.Equal(t, projName, outRow["project_id"])
 	assert.Equal(t, "", outRow["zone"])
 }
+
+// handlerTransport answers requests with a handler, token requests with a fake token
+type handlerTransport struct {
+	handler http.HandlerFunc
+}
+
+func (transport *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	if req.URL.Host == "oauth2.googleapis.com" {
+		recorder.Header().Set("Content-Type", "application/json")
+		recorder.Write([]byte(`{"access_token": "test", "token_type": "Bearer", "expires_in": 3600}`))
+		return recorder.Result(), nil
+	}
+	transport.handler(recorder, req)
+	return recorder.Result(), nil
+}
+
+func TestGetAccounts_discoverProjects(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key.json")
+	assert.Nil(t, os.WriteFile(keyFile, []byte(`{"type": "authorized_user", "client_id": "test", "client_secret": "test", "refresh_token": "test"}`), 0600))
+	var listCalls int32
+	invalidate := false
+	responses := map[string]string{
+		"/v3/projects?alt=json&parent=organizations%2F1234&prettyPrint=false": `{"projects": [
+			{"projectId": "prod-app", "state": "ACTIVE", "labels": {"env": "prod"}},
+			{"projectId": "prod-old", "state": "DELETE_REQUESTED", "labels": {"env": "prod"}},
+			{"projectId": "dev-app", "state": "ACTIVE", "labels": {"env": "dev"}}
+		]}`,
+		"/v3/folders?alt=json&parent=organizations%2F1234&prettyPrint=false": `{"folders": [{"name": "folders/55", "state": "ACTIVE"}]}`,
+		"/v3/projects?alt=json&parent=folders%2F55&prettyPrint=false": `{"projects": [
+			{"projectId": "prod-data", "state": "ACTIVE", "labels": {"env": "prod"}},
+			{"projectId": "prod-sandbox", "state": "ACTIVE", "labels": {"env": "prod"}},
+			{"projectId": "prod-explicit", "state": "ACTIVE", "labels": {"env": "prod"}}
+		]}`,
+		"/v3/folders?alt=json&parent=folders%2F55&prettyPrint=false": `{}`,
+	}
+	utilities.SetHTTPTransport(&handlerTransport{handler: func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&listCalls, 1)
+		if invalidate {
+			invalidate = false
+			InvalidateDiscoveredProjects()
+		}
+		response, found := responses[r.URL.RequestURI()]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}})
+	savedConfiguration := utilities.CurrentConfiguration()
+	defer func() {
+		utilities.SetHTTPTransport(nil)
+		utilities.UpdateConfiguration(func(config *utilities.Configuration) { config.Extension = savedConfiguration.Extension })
+		projectCache = make(map[string]*discoveredProjects)
+		timeNow = time.Now
+	}()
+	utilities.UpdateConfiguration(func(config *utilities.Configuration) {
+		config.Extension.ExtConfGcp.Accounts = []utilities.ExtensionConfigurationGcpAccount{
+			{
+				KeyFile:                 keyFile,
+				ProjectID:               "admin-project",
+				OrganizationID:          "1234",
+				ProjectLabels:           map[string]string{"env": "prod"},
+				ExcludeProjectIDPattern: "sandbox$",
+			},
+			{ProjectID: "prod-explicit"},
+		}
+	})
+
+	accounts := GetAccounts(context.Background())
+	projectIds := make([]string, 0)
+	for _, account := range accounts {
+		projectIds = append(projectIds, account.ProjectID)
+	}
+	assert.Equal(t, []string{"prod-explicit", "prod-app", "prod-data"}, projectIds)
+	assert.Equal(t, keyFile, accounts[1].KeyFile)
+	assert.Equal(t, int32(4), listCalls)
+
+	// Projects are cached until the discovery interval has passed
+	GetAccounts(context.Background())
+	assert.Equal(t, int32(4), listCalls)
+	timeNow = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	GetAccounts(context.Background())
+	assert.Equal(t, int32(8), listCalls)
+	timeNow = time.Now
+
+	// Projects are not listed with the context of the query, which may be cancelled before the listing ends
+	InvalidateDiscoveredProjects()
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, 3, len(GetAccounts(cancelledCtx)))
+	assert.Equal(t, int32(12), listCalls)
+
+	// Projects listed while the cache is invalidated are returned but not cached.
+	// The cache is not locked during the listing, so invalidating it doesn't wait for the listing to end
+	InvalidateDiscoveredProjects()
+	invalidate = true
+	assert.Equal(t, 3, len(GetAccounts(context.Background())))
+	assert.Equal(t, 3, len(GetAccounts(context.Background())))
+	assert.Equal(t, int32(20), listCalls)
+
+	// Discovery in the background refreshes organizations and folders added by a reload right away
+	InvalidateDiscoveredProjects()
+	utilities.UpdateConfiguration(func(config *utilities.Configuration) {
+		config.Extension.ExtConfGcp.Accounts = nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+	StartProjectDiscovery(ctx, wg)
+	utilities.UpdateConfiguration(func(config *utilities.Configuration) {
+		config.Extension = savedConfiguration.Extension
+		config.Extension.ExtConfGcp.Accounts = []utilities.ExtensionConfigurationGcpAccount{
+			{KeyFile: keyFile, ProjectID: "admin-project", FolderID: "55"},
+		}
+	})
+	InvalidateDiscoveredProjects()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&listCalls) >= 22 }, 5*time.Second, 10*time.Millisecond)
+}
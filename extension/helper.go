@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"regexp"
 
 	"github.com/Uptycs/cloudquery/utilities"
 	log "github.com/sirupsen/logrus"
@@ -21,9 +22,8 @@ import (
 
 // InitializeLogger TODO
 func InitializeLogger(verbose bool) {
-	utilities.CreateLogger(verbose, utilities.ExtConfiguration.ExtConfLog.MaxSize,
-		utilities.ExtConfiguration.ExtConfLog.MaxBackups, utilities.ExtConfiguration.ExtConfLog.MaxAge,
-		utilities.ExtConfiguration.ExtConfLog.FileName)
+	logConfig := utilities.CurrentConfiguration().Extension.ExtConfLog
+	utilities.CreateLogger(verbose, logConfig.MaxSize, logConfig.MaxBackups, logConfig.MaxAge, logConfig.FileName)
 }
 
 func readProjectIDFromCredentialFile(filePath string) string {
@@ -55,39 +55,30 @@ func readProjectIDFromCredentialFile(filePath string) string {
 	return ""
 }
 
+// GetExtensionConfigPath returns the path of extension_config.json under the extension home directory
+func GetExtensionConfigPath(homeDir string) string {
+	return homeDir + string(os.PathSeparator) + "config" + string(os.PathSeparator) + "extension_config.json"
+}
+
 // ReadExtensionConfigurations TODO
 func ReadExtensionConfigurations(filePath string, verbose bool) error {
 	utilities.AwsAccountID = os.Getenv("AWS_ACCOUNT_ID")
-	reader, err := ioutil.ReadFile(filePath)
+	extConfig, err := loadExtensionConfiguration(filePath)
 	if err != nil {
 		fmt.Printf("failed to read configuration file %s. err:%v\n", filePath, err)
 		return err
 	}
-	extConfig := utilities.ExtensionConfiguration{}
-	errUnmarshal := json.Unmarshal(reader, &extConfig)
-	if errUnmarshal != nil {
-		return errUnmarshal
-	}
-	utilities.ExtConfiguration = extConfig
+	utilities.UpdateConfiguration(func(config *utilities.Configuration) {
+		config.Extension = extConfig
+	})
 
 	// Log config is read. Init the logger now.
 	InitializeLogger(verbose)
 
-	// Set projectID for GCP accounts
-	for idx := range utilities.ExtConfiguration.ExtConfGcp.Accounts {
-		keyFilePath := utilities.ExtConfiguration.ExtConfGcp.Accounts[idx].KeyFile
-		if keyFilePath != "" {
-			projectID := readProjectIDFromCredentialFile(keyFilePath)
-			// Read ProjectID from keyFile
-			utilities.ExtConfiguration.ExtConfGcp.Accounts[idx].ProjectID = projectID
-		} else {
-			// This is case where we are not using shared credentials.
-			// ProjectID must be set in config.
-			if utilities.ExtConfiguration.ExtConfGcp.Accounts[idx].ProjectID == "" {
-				utilities.GetLogger().Error("GCP account is missing projectId setting")
-			}
-		}
-	}
+	setGcpProjectIDs(&extConfig)
+	utilities.UpdateConfiguration(func(config *utilities.Configuration) {
+		config.Extension = extConfig
+	})
 
 	// Read project ID from ADC
 	adcFilePath := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
@@ -95,7 +86,7 @@ func ReadExtensionConfigurations(filePath string, verbose bool) error {
 		utilities.DefaultGcpProjectID = readProjectIDFromCredentialFile(adcFilePath)
 	}
 
-	if len(utilities.ExtConfiguration.ExtConfGcp.Accounts) == 0 {
+	if len(extConfig.ExtConfGcp.Accounts) == 0 {
 		if adcFilePath == "" {
 			utilities.GetLogger().Warn("missing env GOOGLE_APPLICATION_CREDENTIALS")
 		} else if utilities.DefaultGcpProjectID == "" {
@@ -107,3 +98,51 @@ func ReadExtensionConfigurations(filePath string, verbose bool) error {
 
 	return nil
 }
+
+// loadExtensionConfiguration reads and validates extension configuration from filePath
+func loadExtensionConfiguration(filePath string) (utilities.ExtensionConfiguration, error) {
+	extConfig := utilities.ExtensionConfiguration{}
+	reader, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return extConfig, err
+	}
+	errUnmarshal := json.Unmarshal(reader, &extConfig)
+	if errUnmarshal != nil {
+		return extConfig, errUnmarshal
+	}
+	for _, account := range extConfig.ExtConfAws.Accounts {
+		if err := utilities.ValidateRegionPatterns(append(account.IncludeRegions, account.ExcludeRegions...)); err != nil {
+			return extConfig, fmt.Errorf("invalid configuration for AWS account %s: %w", account.ID, err)
+		}
+	}
+	for _, account := range extConfig.ExtConfGcp.Accounts {
+		for _, pattern := range []string{account.ProjectIDPattern, account.ExcludeProjectIDPattern} {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return extConfig, fmt.Errorf("invalid project id pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	if err := utilities.ValidateRowFilters(extConfig.ExtConfFilters); err != nil {
+		return extConfig, err
+	}
+	return extConfig, nil
+}
+
+// setGcpProjectIDs sets projectID of GCP accounts from their key files
+func setGcpProjectIDs(extConfig *utilities.ExtensionConfiguration) {
+	for idx := range extConfig.ExtConfGcp.Accounts {
+		keyFilePath := extConfig.ExtConfGcp.Accounts[idx].KeyFile
+		if keyFilePath != "" {
+			projectID := readProjectIDFromCredentialFile(keyFilePath)
+			// Read ProjectID from keyFile
+			extConfig.ExtConfGcp.Accounts[idx].ProjectID = projectID
+		} else {
+			// This is case where we are not using shared credentials.
+			// ProjectID must be set in config, unless projects are discovered.
+			account := extConfig.ExtConfGcp.Accounts[idx]
+			if account.ProjectID == "" && account.OrganizationID == "" && account.FolderID == "" {
+				utilities.GetLogger().Error("GCP account is missing projectId setting")
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package extension
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	"github.com/Uptycs/cloudquery/utilities"
+)
+
+const sessionCacheTableName = "cloudquery_session_cache"
+
+// sessionCacheColumns returns the columns of the table reporting session cache counters per provider
+func sessionCacheColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("provider"),
+		table.IntegerColumn("entries"),
+		table.BigIntColumn("hits"),
+		table.BigIntColumn("misses"),
+	}
+}
+
+// sessionCacheGenerate returns one row per provider which has used the session cache
+func sessionCacheGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	rows := make([]map[string]string, 0)
+	for _, stats := range utilities.GetSessionCacheStats() {
+		rows = append(rows, map[string]string{
+			"provider": stats.Provider,
+			"entries":  strconv.Itoa(stats.Entries),
+			"hits":     strconv.FormatUint(stats.Hits, 10),
+			"misses":   strconv.FormatUint(stats.Misses, 10),
+		})
+	}
+	return rows, nil
+}
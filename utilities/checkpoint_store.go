@@ -0,0 +1,212 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package utilities
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CheckpointMarker is the latest object of a bucket (or log) an event table has moved past
+type CheckpointMarker struct {
+	ModifiedTime time.Time `json:"modifiedTime"`
+	Key          string    `json:"key"`
+	Prefix       string    `json:"prefix"`
+}
+
+// objectOffset is the number of records of a partially processed object which were already emitted
+type objectOffset struct {
+	Records     int       `json:"records"`
+	UpdatedTime time.Time `json:"updatedTime"`
+}
+
+type checkpointData struct {
+	// Markers is the map of bucket (or bucket+log) => CheckpointMarker
+	Markers map[string]CheckpointMarker `json:"markers"`
+	// Objects is the map of processed object => time it was processed
+	Objects map[string]time.Time `json:"objects"`
+	// Offsets is the map of partially processed object => objectOffset
+	Offsets map[string]objectOffset `json:"offsets,omitempty"`
+}
+
+// CheckpointStore keeps the progress of an event table, i.e. markers and recently processed objects.
+// If it has a path, Flush atomically writes it to that file, so that the event table resumes where it
+// left off after a restart. Processed objects are forgotten after retention.
+type CheckpointStore struct {
+	path      string
+	retention time.Duration
+	mutex     sync.Mutex
+	data      checkpointData
+}
+
+// GetCheckpointPath returns the checkpoint file of given event table under the extension home directory.
+// Empty string is returned if home directory is not set
+func GetCheckpointPath(tableName string) string {
+	if HomeDirectory == "" {
+		return ""
+	}
+	return filepath.Join(HomeDirectory, "checkpoints", tableName+".json")
+}
+
+// NewCheckpointStore creates a store backed by path and loads it if the file exists.
+// Store is kept in memory only if path is empty. On error, an empty store is returned along with the error
+func NewCheckpointStore(path string, retention time.Duration) (*CheckpointStore, error) {
+	store := CheckpointStore{
+		path:      path,
+		retention: retention,
+		data: checkpointData{
+			Markers: make(map[string]CheckpointMarker),
+			Objects: make(map[string]time.Time),
+			Offsets: make(map[string]objectOffset),
+		},
+	}
+	if path == "" {
+		return &store, nil
+	}
+	jsonEncoded, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &store, nil
+	} else if err != nil {
+		return &store, err
+	}
+	var data checkpointData
+	if err := json.Unmarshal(jsonEncoded, &data); err != nil {
+		return &store, fmt.Errorf("invalid checkpoint file %s: %w", path, err)
+	}
+	if data.Markers != nil {
+		store.data.Markers = data.Markers
+	}
+	if data.Objects != nil {
+		store.data.Objects = data.Objects
+	}
+	if data.Offsets != nil {
+		store.data.Offsets = data.Offsets
+	}
+	return &store, nil
+}
+
+// GetMarker returns the marker stored for key
+func (store *CheckpointStore) GetMarker(key string) (CheckpointMarker, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	marker, found := store.data.Markers[key]
+	return marker, found
+}
+
+// SetMarker stores the marker for key
+func (store *CheckpointStore) SetMarker(key string, marker CheckpointMarker) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.data.Markers[key] = marker
+}
+
+// PruneMarkers removes the markers whose key is not in keep, e.g. of buckets which are no longer configured.
+// It returns the removed keys
+func (store *CheckpointStore) PruneMarkers(keep map[string]bool) []string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	removed := make([]string, 0)
+	for key := range store.data.Markers {
+		if !keep[key] {
+			delete(store.data.Markers, key)
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// IsObjectProcessed returns true if object was processed within retention
+func (store *CheckpointStore) IsObjectProcessed(object string) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	processedTime, found := store.data.Objects[object]
+	return found && timeNow().Sub(processedTime) < store.retention
+}
+
+// AddProcessedObject records that object was processed
+func (store *CheckpointStore) AddProcessedObject(object string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.data.Objects[object] = timeNow()
+	delete(store.data.Offsets, object)
+}
+
+// GetObjectOffset returns the number of records of object already emitted, if it was partially processed within retention
+func (store *CheckpointStore) GetObjectOffset(object string) int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	offset, found := store.data.Offsets[object]
+	if !found || timeNow().Sub(offset.UpdatedTime) >= store.retention {
+		return 0
+	}
+	return offset.Records
+}
+
+// SetObjectOffset records that the first records of object were emitted, so that they are skipped
+// if processing of the object fails later and is retried
+func (store *CheckpointStore) SetObjectOffset(object string, records int) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.data.Offsets[object] = objectOffset{Records: records, UpdatedTime: timeNow()}
+}
+
+// Flush forgets objects processed before retention and writes the store to its file.
+// The file is replaced atomically, so it is never left partially written
+func (store *CheckpointStore) Flush() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	now := timeNow()
+	for object, processedTime := range store.data.Objects {
+		if now.Sub(processedTime) >= store.retention {
+			delete(store.data.Objects, object)
+		}
+	}
+	for object, offset := range store.data.Offsets {
+		if now.Sub(offset.UpdatedTime) >= store.retention {
+			delete(store.data.Offsets, object)
+		}
+	}
+	if store.path == "" {
+		return nil
+	}
+
+	jsonEncoded, err := json.Marshal(store.data)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(store.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(dir, filepath.Base(store.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(jsonEncoded); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), store.path)
+}
@@ -0,0 +1,87 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package utilities
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+)
+
+// Configuration is the extension configuration together with the table configurations.
+// A Configuration is never modified once it is set, it is replaced as a whole instead.
+type Configuration struct {
+	Extension ExtensionConfiguration
+	Tables    map[string]*TableConfig
+}
+
+type configurationContextKey struct{}
+
+// currentConfiguration holds the *Configuration used by queries starting now
+var currentConfiguration atomic.Value
+
+func init() {
+	currentConfiguration.Store(&Configuration{Tables: map[string]*TableConfig{}})
+}
+
+// CurrentConfiguration returns the latest configuration
+func CurrentConfiguration() *Configuration {
+	return currentConfiguration.Load().(*Configuration)
+}
+
+// ContextWithConfiguration returns a copy of ctx carrying given configuration
+func ContextWithConfiguration(ctx context.Context, config *Configuration) context.Context {
+	return context.WithValue(ctx, configurationContextKey{}, config)
+}
+
+// GetConfiguration returns the configuration attached to ctx by WithConfigurationSnapshot,
+// or the latest configuration if there is none
+func GetConfiguration(ctx context.Context) *Configuration {
+	if ctx != nil {
+		if config, ok := ctx.Value(configurationContextKey{}).(*Configuration); ok {
+			return config
+		}
+	}
+	return CurrentConfiguration()
+}
+
+// WithConfigurationSnapshot wraps generate so that the whole query sees the configuration current when it started,
+// even if it is replaced while the query runs
+func WithConfigurationSnapshot(generate table.GenerateFunc) table.GenerateFunc {
+	return func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		return generate(ContextWithConfiguration(ctx, CurrentConfiguration()), queryContext)
+	}
+}
+
+// SetConfiguration replaces the extension configuration and the table configurations at once.
+// Running queries and event loops keep using the configuration they started with.
+// Cached results and sessions, which may depend on the old configuration, are invalidated.
+func SetConfiguration(extConfig ExtensionConfiguration, tableConfigs map[string]*TableConfig) {
+	currentConfiguration.Store(&Configuration{Extension: extConfig, Tables: tableConfigs})
+
+	InvalidateResultCache("")
+	InvalidateSessionCache("")
+}
+
+// UpdateConfiguration replaces the latest configuration with a copy modified by update.
+// It is meant for startup, when the configuration is read piece by piece; see SetConfiguration for reloads.
+func UpdateConfiguration(update func(config *Configuration)) {
+	current := CurrentConfiguration()
+	config := &Configuration{
+		Extension: current.Extension,
+		Tables:    make(map[string]*TableConfig, len(current.Tables)),
+	}
+	for name, tableConfig := range current.Tables {
+		config.Tables[name] = tableConfig
+	}
+	update(config)
+	currentConfiguration.Store(config)
+}
@@ -23,19 +23,52 @@ type CtS3Bucket struct {
 	Prefix string `json:"prefix"`
 }
 
-// ExtensionConfigurationAwsAccount represents configuration of an AWS account
+// ExtensionConfigurationAwsAccount represents configuration of an AWS account.
+// EndpointURL sends the requests of all services to given URL instead of AWS (e.g. LocalStack or moto).
+// Regions limits the regions processed. If DisableRegionDiscovery is set, Regions are used without
+// calling DescribeRegions. IncludeRegions and ExcludeRegions are glob patterns (e.g. "eu-*") selecting
+// the regions processed for the account, exclusions take precedence.
+// SourceRoleArn (with SourceExternalID) is assumed before RoleArn, allowing to chain roles.
+// If DiscoverOrganization is set, the account is an organization management account and the ACTIVE members
+// of the organization are processed as well:
+//   - MemberRoleName is the role assumed in member accounts (default OrganizationAccountAccessRole),
+//     "{accountId}" and "{accountName}" are replaced by the id and name of the member
+//   - IncludeOrganizationalUnits and ExcludeOrganizationalUnits select members by the ids of their
+//     parent OUs (or root) at any level, exclusions take precedence
+//   - Members are listed again every DiscoveryInterval seconds (default 3600)
 type ExtensionConfigurationAwsAccount struct {
-	ID             string       `json:"id"`
-	CredentialFile string       `json:"credentialFile"`
-	ProfileName    string       `json:"profileName"`
-	RoleArn        string       `json:"roleArn"`
-	ExternalID     string       `json:"externalId"`
-	CtS3Buckets    []CtS3Bucket `json:"ctS3Buckets"`
+	ID                         string       `json:"id"`
+	CredentialFile             string       `json:"credentialFile"`
+	ProfileName                string       `json:"profileName"`
+	RoleArn                    string       `json:"roleArn"`
+	ExternalID                 string       `json:"externalId"`
+	CtS3Buckets                []CtS3Bucket `json:"ctS3Buckets"`
+	EndpointURL                string       `json:"endpointUrl"`
+	Regions                    []string     `json:"regions"`
+	DisableRegionDiscovery     bool         `json:"disableRegionDiscovery"`
+	IncludeRegions             []string     `json:"includeRegions"`
+	ExcludeRegions             []string     `json:"excludeRegions"`
+	SourceRoleArn              string       `json:"sourceRoleArn"`
+	SourceExternalID           string       `json:"sourceExternalId"`
+	DiscoverOrganization       bool         `json:"discoverOrganization"`
+	MemberRoleName             string       `json:"memberRoleName"`
+	MemberExternalID           string       `json:"memberExternalId"`
+	IncludeOrganizationalUnits []string     `json:"includeOrganizationalUnits"`
+	ExcludeOrganizationalUnits []string     `json:"excludeOrganizationalUnits"`
+	DiscoveryInterval          int          `json:"discoveryInterval"`
+}
+
+// ExtensionConfigurationAwsConcurrency limits the number of API workers used by AWS tables.
+// MaxWorkers is shared by all queries, MaxWorkersPerAccount limits regions of one account processed in parallel
+type ExtensionConfigurationAwsConcurrency struct {
+	MaxWorkers           int `json:"maxWorkers"`
+	MaxWorkersPerAccount int `json:"maxWorkersPerAccount"`
 }
 
 // ExtensionConfigurationAws holds Accounts which is a list of AWS account configurations
 type ExtensionConfigurationAws struct {
-	Accounts []ExtensionConfigurationAwsAccount `json:"accounts"`
+	Accounts    []ExtensionConfigurationAwsAccount   `json:"accounts"`
+	Concurrency ExtensionConfigurationAwsConcurrency `json:"concurrency"`
 }
 
 type CloudLogStorageBucket struct {
@@ -44,11 +77,22 @@ type CloudLogStorageBucket struct {
 	LogNames []string `json:"logNames"`
 }
 
-// ExtensionConfigurationGcpAccount represents configuration of a GCP account
+// ExtensionConfigurationGcpAccount represents configuration of a GCP account.
+// If OrganizationID or FolderID is set, the ACTIVE projects under it (at any level) are queried instead,
+// using the credentials of the account:
+//   - ProjectLabels selects projects having all given labels, an empty value matches any value
+//   - ProjectIDPattern and ExcludeProjectIDPattern are regular expressions on project ids
+//   - Projects are listed at startup and again every DiscoveryInterval seconds (default 3600)
 type ExtensionConfigurationGcpAccount struct {
-	KeyFile                string                  `json:"keyFile"`
-	ProjectID              string                  `json:"projectId"`
-	CloudLogStorageBuckets []CloudLogStorageBucket `json:"cloudLogStorageBuckets"`
+	KeyFile                 string                  `json:"keyFile"`
+	ProjectID               string                  `json:"projectId"`
+	CloudLogStorageBuckets  []CloudLogStorageBucket `json:"cloudLogStorageBuckets"`
+	OrganizationID          string                  `json:"organizationId"`
+	FolderID                string                  `json:"folderId"`
+	ProjectLabels           map[string]string       `json:"projectLabels"`
+	ProjectIDPattern        string                  `json:"projectIdPattern"`
+	ExcludeProjectIDPattern string                  `json:"excludeProjectIdPattern"`
+	DiscoveryInterval       int                     `json:"discoveryInterval"`
 }
 
 // ExtensionConfigurationGcp holds Accounts which is a list of GCP account configurations
@@ -56,22 +100,81 @@ type ExtensionConfigurationGcp struct {
 	Accounts []ExtensionConfigurationGcpAccount `json:"accounts"`
 }
 
-// ExtensionConfigurationAzureAccount represents configuration of an Azure account
+// ExtensionConfigurationAzureAccount represents configuration of an Azure account.
+// Credentials are given by ClientID and TenantID with either ClientSecret or CertificatePath (and
+// CertificatePassword), read from the AZURE_* environment variables if UseEnvironment is set,
+// or read from AuthFile. SubscriptionID takes precedence over the subscription of the auth file.
+// If DiscoverSubscriptions is set, all Enabled subscriptions visible to the credentials are queried:
+//   - IncludeSubscriptions and ExcludeSubscriptions are glob patterns on subscription ids or names,
+//     exclusions take precedence
+//   - Subscriptions are listed again every DiscoveryInterval seconds (default 3600)
 type ExtensionConfigurationAzureAccount struct {
-	SubscriptionID string `json:"subscriptionId"`
-	TenantID       string `json:"tenantId"`
-	AuthFile       string `json:"authFile"`
+	SubscriptionID        string   `json:"subscriptionId"`
+	TenantID              string   `json:"tenantId"`
+	AuthFile              string   `json:"authFile"`
+	ClientID              string   `json:"clientId"`
+	ClientSecret          string   `json:"clientSecret"`
+	CertificatePath       string   `json:"certificatePath"`
+	CertificatePassword   string   `json:"certificatePassword"`
+	UseEnvironment        bool     `json:"useEnvironment"`
+	DiscoverSubscriptions bool     `json:"discoverSubscriptions"`
+	IncludeSubscriptions  []string `json:"includeSubscriptions"`
+	ExcludeSubscriptions  []string `json:"excludeSubscriptions"`
+	DiscoveryInterval     int      `json:"discoveryInterval"`
+}
+
+// ExtensionConfigurationAzureConcurrency limits the number of resource groups processed in parallel
+type ExtensionConfigurationAzureConcurrency struct {
+	MaxWorkers int `json:"maxWorkers"`
 }
 
 // ExtensionConfigurationAzure holds Accounts which is a list of Azure account configurations
 type ExtensionConfigurationAzure struct {
-	Accounts []ExtensionConfigurationAzureAccount `json:"accounts"`
+	Accounts    []ExtensionConfigurationAzureAccount   `json:"accounts"`
+	Concurrency ExtensionConfigurationAzureConcurrency `json:"concurrency"`
+}
+
+// ExtensionConfigurationCache controls the table result cache and the session cache.
+// Disabled bypasses the result cache for all tables regardless of their cacheTtl.
+// SessionTTL is the number of seconds cloud sessions are reused (default 3600, negative disables)
+type ExtensionConfigurationCache struct {
+	Disabled   bool `json:"disabled"`
+	SessionTTL int  `json:"sessionTtl"`
+}
+
+// ExtensionConfigurationTables enables or disables whole providers (aws, gcp, azure) or individual tables.
+// EnabledTables takes precedence, so a single table of a disabled provider can be enabled
+type ExtensionConfigurationTables struct {
+	DisabledProviders []string `json:"disabledProviders"`
+	DisabledTables    []string `json:"disabledTables"`
+	EnabledTables     []string `json:"enabledTables"`
+}
+
+// ExtensionConfigurationFilterRule is a condition on a flattened attribute (sourceName) of a row,
+// or on a column of an event. Operator is one of eq, ne, regex, lt, le, gt, ge or tag.
+// lt, le, gt and ge compare numerically. tag matches a tag with given Key (and Value if set) in
+// the attribute holding the tags, either a list of Key/Value objects (AWS) or a map (GCP labels, Azure tags)
+type ExtensionConfigurationFilterRule struct {
+	Attribute string      `json:"attribute"`
+	Operator  string      `json:"operator"`
+	Key       string      `json:"key"`
+	Value     interface{} `json:"value"`
+}
+
+// ExtensionConfigurationTableFilter selects the rows (or events) of a table.
+// A row is processed if it matches all Include rules and none of the Exclude rules
+type ExtensionConfigurationTableFilter struct {
+	Include []ExtensionConfigurationFilterRule `json:"include"`
+	Exclude []ExtensionConfigurationFilterRule `json:"exclude"`
 }
 
 // ExtensionConfiguration represents the configuration for cloudquery extension
 type ExtensionConfiguration struct {
-	ExtConfLog   ExtensionConfigurationLogging `json:"logging"`
-	ExtConfAws   ExtensionConfigurationAws     `json:"aws"`
-	ExtConfGcp   ExtensionConfigurationGcp     `json:"gcp"`
-	ExtConfAzure ExtensionConfigurationAzure   `json:"azure"`
+	ExtConfLog     ExtensionConfigurationLogging                `json:"logging"`
+	ExtConfCache   ExtensionConfigurationCache                  `json:"cache"`
+	ExtConfTables  ExtensionConfigurationTables                 `json:"tables"`
+	ExtConfAws     ExtensionConfigurationAws                    `json:"aws"`
+	ExtConfGcp     ExtensionConfigurationGcp                    `json:"gcp"`
+	ExtConfAzure   ExtensionConfigurationAzure                  `json:"azure"`
+	ExtConfFilters map[string]ExtensionConfigurationTableFilter `json:"filters"`
 }
@@ -0,0 +1,37 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package utilities
+
+import (
+	"net/http"
+	"sync"
+)
+
+var (
+	httpTransportMutex sync.RWMutex
+	httpTransport      http.RoundTripper
+)
+
+// SetHTTPTransport makes AWS, GCP and Azure clients send their requests through transport.
+// nil restores the default transports. It is meant for tests (see utilities/vcr).
+func SetHTTPTransport(transport http.RoundTripper) {
+	httpTransportMutex.Lock()
+	httpTransport = transport
+	httpTransportMutex.Unlock()
+	// Cached sessions hold clients using the previous transport
+	InvalidateSessionCache("")
+}
+
+// GetHTTPTransport returns the transport set with SetHTTPTransport, nil if none
+func GetHTTPTransport() http.RoundTripper {
+	httpTransportMutex.RLock()
+	defer httpTransportMutex.RUnlock()
+	return httpTransport
+}
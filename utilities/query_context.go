@@ -0,0 +1,49 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package utilities
+
+import (
+	"github.com/Uptycs/basequery-go/plugin/table"
+)
+
+// GetEqualsConstraints returns the values of all equality constraints on given column.
+// osquery passes "column IN (...)" as a list of equality constraints, so the result
+// is treated as a set of allowed values. Empty result means column is unconstrained.
+func GetEqualsConstraints(queryContext table.QueryContext, column string) []string {
+	values := make([]string, 0)
+	if len(column) == 0 {
+		return values
+	}
+	constraintList, ok := queryContext.Constraints[column]
+	if !ok {
+		return values
+	}
+	for _, constraint := range constraintList.Constraints {
+		if constraint.Operator == table.OperatorEquals {
+			values = append(values, constraint.Expression)
+		}
+	}
+	return values
+}
+
+// MatchesEqualsConstraints returns false only if given column has equality constraints
+// and value does not match any of them
+func MatchesEqualsConstraints(queryContext table.QueryContext, column string, value string) bool {
+	values := GetEqualsConstraints(queryContext, column)
+	if len(values) == 0 {
+		return true
+	}
+	for _, val := range values {
+		if val == value {
+			return true
+		}
+	}
+	return false
+}
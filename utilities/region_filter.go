@@ -0,0 +1,43 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package utilities
+
+import (
+	"fmt"
+	"path"
+)
+
+// ValidateRegionPatterns returns an error if any of the region glob patterns is malformed
+func ValidateRegionPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid region pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// MatchesRegionFilter returns true if region matches one of includeRegions (or includeRegions is empty)
+// and none of excludeRegions. Patterns use glob syntax, e.g. "eu-*" or "us-?ast-1"
+func MatchesRegionFilter(includeRegions []string, excludeRegions []string, region string) bool {
+	if matchesAnyRegionPattern(excludeRegions, region) {
+		return false
+	}
+	return len(includeRegions) == 0 || matchesAnyRegionPattern(includeRegions, region)
+}
+
+func matchesAnyRegionPattern(patterns []string, region string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, region); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package utilities
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	log "github.com/sirupsen/logrus"
+)
+
+// CacheBypassColumn is the hidden column of cached tables which bypasses the result cache for a query,
+// e.g. select * from aws_s3_bucket where cache_bypass = 1. Its rows are generated again and replace the cached ones
+const CacheBypassColumn = "cache_bypass"
+
+type resultCacheEntry struct {
+	rows      []map[string]string
+	expiresAt time.Time
+}
+
+var (
+	resultCacheMutex sync.Mutex
+	resultCache      = make(map[string]resultCacheEntry)
+	// timeNow is replaced by tests
+	timeNow = time.Now
+)
+
+// getResultCacheKey returns table name followed by all constraints sorted by column,
+// so that the same query always maps to the same key
+func getResultCacheKey(tableName string, queryContext table.QueryContext) string {
+	columns := make([]string, 0, len(queryContext.Constraints))
+	for column := range queryContext.Constraints {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	var builder strings.Builder
+	builder.WriteString(tableName)
+	for _, column := range columns {
+		constraints := make([]string, 0)
+		for _, constraint := range queryContext.Constraints[column].Constraints {
+			constraints = append(constraints, strconv.Itoa(int(constraint.Operator))+":"+constraint.Expression)
+		}
+		sort.Strings(constraints)
+		builder.WriteString("|" + column)
+		for _, constraint := range constraints {
+			builder.WriteString("," + constraint)
+		}
+	}
+	return builder.String()
+}
+
+func getCacheTTL(ctx context.Context, tableName string) time.Duration {
+	config := GetConfiguration(ctx)
+	if config.Extension.ExtConfCache.Disabled {
+		return 0
+	}
+	tableConfig, ok := config.Tables[tableName]
+	if !ok || tableConfig.CacheTTL <= 0 {
+		return 0
+	}
+	return time.Duration(tableConfig.CacheTTL) * time.Second
+}
+
+// WithResultCache wraps generate so that its rows are reused for "cacheTtl" seconds (see TableConfig)
+// for queries on tableName with the same constraints. Errors are never cached.
+// Caching is skipped if cacheTtl is not set or cache is disabled in extension configuration.
+// A query constraining CacheBypassColumn skips the cached rows (see withoutCacheBypass)
+func WithResultCache(tableName string, generate table.GenerateFunc) table.GenerateFunc {
+	return func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		queryContext, bypass := withoutCacheBypass(queryContext)
+		ttl := getCacheTTL(ctx, tableName)
+		if ttl <= 0 {
+			rows, err := generate(ctx, queryContext)
+			return withCacheBypass(rows, bypass), err
+		}
+
+		key := getResultCacheKey(tableName, queryContext)
+		now := timeNow()
+		resultCacheMutex.Lock()
+		entry, ok := resultCache[key]
+		resultCacheMutex.Unlock()
+		if ok && now.Before(entry.expiresAt) && bypass == "" {
+			GetLogger().WithFields(log.Fields{
+				"tableName": tableName,
+			}).Debug("returning cached result")
+			return entry.rows, nil
+		}
+
+		rows, err := generate(ctx, queryContext)
+		if err != nil {
+			return withCacheBypass(rows, bypass), err
+		}
+
+		resultCacheMutex.Lock()
+		defer resultCacheMutex.Unlock()
+		for cachedKey, cachedEntry := range resultCache {
+			if !now.Before(cachedEntry.expiresAt) {
+				delete(resultCache, cachedKey)
+			}
+		}
+		resultCache[key] = resultCacheEntry{rows: rows, expiresAt: now.Add(ttl)}
+		return withCacheBypass(rows, bypass), nil
+	}
+}
+
+// InvalidateResultCache removes cached results of given table. All tables are invalidated if tableName is empty
+func InvalidateResultCache(tableName string) {
+	resultCacheMutex.Lock()
+	defer resultCacheMutex.Unlock()
+	for key := range resultCache {
+		if tableName == "" || key == tableName || strings.HasPrefix(key, tableName+"|") {
+			delete(resultCache, key)
+		}
+	}
+}
+
+// withoutCacheBypass returns queryContext without the constraints on CacheBypassColumn, which tables don't know,
+// and the value the column must be equal to (empty if the cache is not bypassed)
+func withoutCacheBypass(queryContext table.QueryContext) (table.QueryContext, string) {
+	if _, found := queryContext.Constraints[CacheBypassColumn]; !found {
+		return queryContext, ""
+	}
+	bypass := ""
+	if values := GetEqualsConstraints(queryContext, CacheBypassColumn); len(values) > 0 {
+		bypass = values[0]
+	}
+	constraints := make(map[string]table.ConstraintList, len(queryContext.Constraints))
+	for column, constraintList := range queryContext.Constraints {
+		if column != CacheBypassColumn {
+			constraints[column] = constraintList
+		}
+	}
+	return table.QueryContext{Constraints: constraints}, bypass
+}
+
+// withCacheBypass returns copies of rows with CacheBypassColumn set to bypass, as osquery checks the constraints
+// of the query on returned rows. Rows are returned as is if the cache is not bypassed
+func withCacheBypass(rows []map[string]string, bypass string) []map[string]string {
+	if bypass == "" {
+		return rows
+	}
+	result := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		copied := make(map[string]string, len(row)+1)
+		for column, value := range row {
+			copied[column] = value
+		}
+		copied[CacheBypassColumn] = bypass
+		result = append(result, copied)
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package utilities
+
+import (
+	"context"
+	"sync"
+)
+
+// RowCollector gathers rows produced by concurrent workers of a single query.
+// At most maxWorkers functions started with Go run at a time. Once ctx is
+// cancelled (e.g. osquery gave up on the query) no new workers are started.
+type RowCollector struct {
+	ctx     context.Context
+	mutex   sync.Mutex
+	rows    []map[string]string
+	wg      sync.WaitGroup
+	workers chan struct{}
+}
+
+// NewRowCollector creates a collector bound to ctx. maxWorkers <= 0 means unbounded.
+func NewRowCollector(ctx context.Context, maxWorkers int) *RowCollector {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	collector := RowCollector{
+		ctx:  ctx,
+		rows: make([]map[string]string, 0),
+	}
+	if maxWorkers > 0 {
+		collector.workers = make(chan struct{}, maxWorkers)
+	}
+	return &collector
+}
+
+// Context returns the context workers should pass to API calls
+func (collector *RowCollector) Context() context.Context {
+	return collector.ctx
+}
+
+// Add appends a row. It is safe to call from multiple goroutines.
+func (collector *RowCollector) Add(row map[string]string) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.rows = append(collector.rows, row)
+}
+
+// AddRows appends rows. It is safe to call from multiple goroutines.
+func (collector *RowCollector) AddRows(rows []map[string]string) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.rows = append(collector.rows, rows...)
+}
+
+// Go runs fn in a new goroutine as soon as a worker is available.
+// fn is skipped if the context is cancelled before that.
+func (collector *RowCollector) Go(fn func()) {
+	collector.wg.Add(1)
+	go func() {
+		defer collector.wg.Done()
+		if collector.workers != nil {
+			select {
+			case collector.workers <- struct{}{}:
+				defer func() { <-collector.workers }()
+			case <-collector.ctx.Done():
+				return
+			}
+		}
+		if collector.ctx.Err() != nil {
+			return
+		}
+		fn()
+	}()
+}
+
+// Wait waits for all workers started with Go and returns the collected rows
+func (collector *RowCollector) Wait() []map[string]string {
+	collector.wg.Wait()
+	return collector.Rows()
+}
+
+// Rows returns a copy of the rows collected so far
+func (collector *RowCollector) Rows() []map[string]string {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	rows := make([]map[string]string, len(collector.rows))
+	copy(rows, collector.rows)
+	return rows
+}
@@ -0,0 +1,209 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package utilities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Filter rule operators
+const (
+	FilterOperatorEquals         = "eq"
+	FilterOperatorNotEquals      = "ne"
+	FilterOperatorRegex          = "regex"
+	FilterOperatorLessThan       = "lt"
+	FilterOperatorLessOrEqual    = "le"
+	FilterOperatorGreaterThan    = "gt"
+	FilterOperatorGreaterOrEqual = "ge"
+	FilterOperatorTag            = "tag"
+)
+
+// Compiled regular expressions of the filter rules, by pattern
+var filterRegexps sync.Map
+
+// ValidateRowFilters returns an error if any of the filter rules is invalid
+func ValidateRowFilters(filters map[string]ExtensionConfigurationTableFilter) error {
+	for tableName, filter := range filters {
+		for _, rule := range append(append([]ExtensionConfigurationFilterRule{}, filter.Include...), filter.Exclude...) {
+			if err := validateFilterRule(rule); err != nil {
+				return fmt.Errorf("invalid filter rule for table %s: %+v: %w", tableName, rule, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateFilterRule(rule ExtensionConfigurationFilterRule) error {
+	if rule.Attribute == "" {
+		return fmt.Errorf("attribute is not set")
+	}
+	switch rule.Operator {
+	case FilterOperatorEquals, FilterOperatorNotEquals:
+		return nil
+	case FilterOperatorRegex:
+		_, err := getFilterRegexp(GetStringValue(rule.Value))
+		return err
+	case FilterOperatorLessThan, FilterOperatorLessOrEqual, FilterOperatorGreaterThan, FilterOperatorGreaterOrEqual:
+		if _, ok := getFloatValue(rule.Value); !ok {
+			return fmt.Errorf("value is not a number")
+		}
+		return nil
+	case FilterOperatorTag:
+		if rule.Key == "" {
+			return fmt.Errorf("key is not set")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown operator %q", rule.Operator)
+}
+
+func getFilterRegexp(pattern string) (*regexp.Regexp, error) {
+	if compiled, ok := filterRegexps.Load(pattern); ok {
+		return compiled.(*regexp.Regexp), nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	filterRegexps.Store(pattern, compiled)
+	return compiled, nil
+}
+
+// MatchesRowFilter returns false if given row is excluded by the filter rules of given table.
+// Rules refer to the flattened attributes (sourceName) of the row
+func MatchesRowFilter(ctx context.Context, tableName string, row map[string]interface{}) bool {
+	filter, ok := GetConfiguration(ctx).Extension.ExtConfFilters[tableName]
+	if !ok {
+		return true
+	}
+	return matchesFilter(tableName, filter, row)
+}
+
+// MatchesEventFilter returns false if given event is excluded by the filter rules of given table.
+// Rules refer to the columns of the event
+func MatchesEventFilter(ctx context.Context, tableName string, event map[string]string) bool {
+	filter, ok := GetConfiguration(ctx).Extension.ExtConfFilters[tableName]
+	if !ok {
+		return true
+	}
+	row := make(map[string]interface{}, len(event))
+	for key, value := range event {
+		row[key] = value
+	}
+	return matchesFilter(tableName, filter, row)
+}
+
+func matchesFilter(tableName string, filter ExtensionConfigurationTableFilter, row map[string]interface{}) bool {
+	for _, rule := range filter.Include {
+		if !matchesFilterRule(tableName, rule, row[rule.Attribute]) {
+			return false
+		}
+	}
+	for _, rule := range filter.Exclude {
+		if matchesFilterRule(tableName, rule, row[rule.Attribute]) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesFilterRule(tableName string, rule ExtensionConfigurationFilterRule, value interface{}) bool {
+	switch rule.Operator {
+	case FilterOperatorEquals:
+		return value != nil && GetStringValue(value) == GetStringValue(rule.Value)
+	case FilterOperatorNotEquals:
+		return value == nil || GetStringValue(value) != GetStringValue(rule.Value)
+	case FilterOperatorRegex:
+		compiled, err := getFilterRegexp(GetStringValue(rule.Value))
+		if err != nil {
+			GetLogger().WithFields(log.Fields{
+				"tableName": tableName,
+				"attribute": rule.Attribute,
+				"errString": err.Error(),
+			}).Error("invalid filter regex")
+			return false
+		}
+		return value != nil && compiled.MatchString(GetStringValue(value))
+	case FilterOperatorLessThan, FilterOperatorLessOrEqual, FilterOperatorGreaterThan, FilterOperatorGreaterOrEqual:
+		return matchesNumericRule(rule, value)
+	case FilterOperatorTag:
+		return matchesTagRule(rule, value)
+	}
+	return false
+}
+
+func matchesNumericRule(rule ExtensionConfigurationFilterRule, value interface{}) bool {
+	number, ok := getFloatValue(value)
+	if !ok {
+		return false
+	}
+	ruleNumber, ok := getFloatValue(rule.Value)
+	if !ok {
+		return false
+	}
+	switch rule.Operator {
+	case FilterOperatorLessThan:
+		return number < ruleNumber
+	case FilterOperatorLessOrEqual:
+		return number <= ruleNumber
+	case FilterOperatorGreaterThan:
+		return number > ruleNumber
+	}
+	return number >= ruleNumber
+}
+
+// matchesTagRule looks for the tag in a list of Key/Value objects or in a map, either as is or JSON encoded
+func matchesTagRule(rule ExtensionConfigurationFilterRule, value interface{}) bool {
+	if encoded, ok := value.(string); ok {
+		if err := json.Unmarshal([]byte(encoded), &value); err != nil {
+			return false
+		}
+	}
+	matchesValue := func(tagValue interface{}) bool {
+		return rule.Value == nil || GetStringValue(tagValue) == GetStringValue(rule.Value)
+	}
+	switch tags := value.(type) {
+	case map[string]interface{}:
+		if keyValue, found := getCaseInsensitive(tags, "key"); found && len(tags) <= 2 {
+			// Single Key/Value object, e.g. when a list of tags is flattened into one row per tag
+			tagValue, _ := getCaseInsensitive(tags, "value")
+			return GetStringValue(keyValue) == rule.Key && matchesValue(tagValue)
+		}
+		tagValue, found := tags[rule.Key]
+		return found && matchesValue(tagValue)
+	case []interface{}:
+		for _, tag := range tags {
+			if tagMap, ok := tag.(map[string]interface{}); ok {
+				keyValue, _ := getCaseInsensitive(tagMap, "key")
+				tagValue, _ := getCaseInsensitive(tagMap, "value")
+				if GetStringValue(keyValue) == rule.Key && matchesValue(tagValue) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func getCaseInsensitive(m map[string]interface{}, key string) (interface{}, bool) {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
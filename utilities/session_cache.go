@@ -0,0 +1,166 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package utilities
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Cloud providers, also the prefix of their table names
+const (
+	ProviderAws   = "aws"
+	ProviderGcp   = "gcp"
+	ProviderAzure = "azure"
+)
+
+const defaultSessionTTL = 3600 // seconds
+
+// sessionCacheEntry is a cached session. While the session is created, ready is open and other callers
+// asking for the same key wait for it to be closed
+type sessionCacheEntry struct {
+	session   interface{}
+	err       error
+	expiresAt time.Time
+	pending   bool
+	ready     chan struct{}
+}
+
+// SessionCacheStats holds the session cache counters of a provider
+type SessionCacheStats struct {
+	Provider string
+	Entries  int
+	Hits     uint64
+	Misses   uint64
+}
+
+var (
+	sessionCacheMutex sync.Mutex
+	// sessionCache is provider|account(|region) => session
+	sessionCache = make(map[string]*sessionCacheEntry)
+	// sessionCacheStats is provider => counters
+	sessionCacheStats = make(map[string]*SessionCacheStats)
+)
+
+// GetSessionCacheKey returns the key of a session: provider followed by the parts identifying
+// the account (and region, if sessions are regional)
+func GetSessionCacheKey(provider string, parts ...string) string {
+	return provider + "|" + strings.Join(parts, "|")
+}
+
+func getSessionTTL() time.Duration {
+	ttl := CurrentConfiguration().Extension.ExtConfCache.SessionTTL
+	if ttl == 0 {
+		ttl = defaultSessionTTL
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// GetCachedSession returns the session (AWS config, GCP client options, Azure authorizer etc.) cached for
+// key of given provider. If there is none or it has expired, it is created with create and cached
+// for "sessionTtl" seconds of the cache configuration. Errors are never cached.
+// Sessions of different keys are created concurrently, callers asking for a key whose session is being
+// created wait for it instead of creating it again.
+// Caching is skipped if sessionTtl is negative.
+func GetCachedSession(provider string, key string, create func() (interface{}, error)) (interface{}, error) {
+	ttl := getSessionTTL()
+	if ttl <= 0 {
+		return create()
+	}
+
+	sessionCacheMutex.Lock()
+	stats, ok := sessionCacheStats[provider]
+	if !ok {
+		stats = &SessionCacheStats{Provider: provider}
+		sessionCacheStats[provider] = stats
+	}
+	if entry, found := sessionCache[key]; found && (entry.pending || timeNow().Before(entry.expiresAt)) {
+		stats.Hits++
+		sessionCacheMutex.Unlock()
+		<-entry.ready
+		return entry.session, entry.err
+	}
+
+	stats.Misses++
+	entry := &sessionCacheEntry{pending: true, ready: make(chan struct{})}
+	sessionCache[key] = entry
+	sessionCacheMutex.Unlock()
+
+	if logger := GetLogger(); logger != nil {
+		logger.WithFields(log.Fields{
+			"provider": provider,
+			"key":      key,
+		}).Debug("creating session")
+	}
+	session, err := create()
+
+	sessionCacheMutex.Lock()
+	now := timeNow()
+	entry.session, entry.err, entry.expiresAt, entry.pending = session, err, now.Add(ttl), false
+	for cachedKey, cachedEntry := range sessionCache {
+		if !cachedEntry.pending && !now.Before(cachedEntry.expiresAt) {
+			delete(sessionCache, cachedKey)
+		}
+	}
+	// Errors are not cached. The entry may already have been removed by InvalidateSessionCache
+	if cachedEntry, found := sessionCache[key]; err != nil && found && cachedEntry == entry {
+		delete(sessionCache, key)
+	}
+	sessionCacheMutex.Unlock()
+	close(entry.ready)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// InvalidateSessionCache removes the cached sessions of given provider, e.g. after credentials or
+// accounts in extension configuration have changed. All providers are invalidated if provider is empty.
+// Counters are kept.
+func InvalidateSessionCache(provider string) {
+	sessionCacheMutex.Lock()
+	defer sessionCacheMutex.Unlock()
+	for key := range sessionCache {
+		if provider == "" || strings.HasPrefix(key, provider+"|") {
+			delete(sessionCache, key)
+		}
+	}
+	// The logger may not be created yet
+	if logger := GetLogger(); logger != nil {
+		logger.WithFields(log.Fields{
+			"provider": provider,
+		}).Debug("invalidated session cache")
+	}
+}
+
+// GetSessionCacheStats returns the session cache counters of all providers, sorted by provider
+func GetSessionCacheStats() []SessionCacheStats {
+	sessionCacheMutex.Lock()
+	defer sessionCacheMutex.Unlock()
+	result := make([]SessionCacheStats, 0, len(sessionCacheStats))
+	for provider, stats := range sessionCacheStats {
+		entry := *stats
+		entry.Entries = 0
+		for key := range sessionCache {
+			if strings.HasPrefix(key, provider+"|") {
+				entry.Entries++
+			}
+		}
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Provider < result[j].Provider
+	})
+	return result
+}
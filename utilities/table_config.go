@@ -10,7 +10,11 @@
 package utilities
 
 import (
+	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
 )
 
 // ParsedAttributeConfig represents the attributes for a table
@@ -22,10 +26,13 @@ type ParsedAttributeConfig struct {
 }
 
 // AwsConfig represents the additional attributes for AWS table
+// IncludeRegions and ExcludeRegions override the region patterns of the account for this table
 type AwsConfig struct {
-	RegionAttribute     string `json:"regionAttribute"`
-	RegionCodeAttribute string `json:"regionCodeAttribute"`
-	AccountIDAttribute  string `json:"accountIdAttribute"`
+	RegionAttribute     string   `json:"regionAttribute"`
+	RegionCodeAttribute string   `json:"regionCodeAttribute"`
+	AccountIDAttribute  string   `json:"accountIdAttribute"`
+	IncludeRegions      []string `json:"includeRegions,omitempty"`
+	ExcludeRegions      []string `json:"excludeRegions,omitempty"`
 }
 
 // GcpConfig represents the additional attributes for GCP table
@@ -48,6 +55,7 @@ type TableConfig struct {
 	API              string                  `json:"api"`
 	Paginated        bool                    `json:"paginated"`
 	TemplateFile     string                  `json:"templateFile"`
+	CacheTTL         int                     `json:"cacheTtl"`
 	Aws              AwsConfig               `json:"aws"`
 	Gcp              GcpConfig               `json:"gcp"`
 	Azure            AzureConfig             `json:"azure"`
@@ -72,3 +80,93 @@ func (tableConfig *TableConfig) initParsedAttributeConfigMap() {
 func (tableConfig *TableConfig) getParsedAttributeConfigMap() map[string]ParsedAttributeConfig {
 	return tableConfig.parsedAttributeConfigMap
 }
+
+// GetTargetName returns the column name for given source attribute.
+// Empty string is returned if attribute is not configured or not enabled
+func (tableConfig *TableConfig) GetTargetName(sourceName string) string {
+	attr, ok := tableConfig.parsedAttributeConfigMap[sourceName]
+	if !ok || !attr.Enabled {
+		return ""
+	}
+	return attr.TargetName
+}
+
+// ValidateColumns checks that given column definitions agree with the table configuration.
+// Every attribute, enabled or not, must have a column with the type derived from its targetType.
+// Returned error lists all mismatches.
+func (tableConfig *TableConfig) ValidateColumns(columns []table.ColumnDefinition) error {
+	columnTypes := make(map[string]table.ColumnType)
+	for _, column := range columns {
+		columnTypes[column.Name] = column.Type
+	}
+	problems := make([]string, 0)
+	for _, attr := range tableConfig.ParsedAttributes {
+		expectedType, err := GetColumnType(attr.TargetType)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", attr.SourceName, err.Error()))
+			continue
+		}
+		columnType, found := columnTypes[attr.TargetName]
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: column %s is not defined", attr.SourceName, attr.TargetName))
+		} else if columnType != expectedType {
+			problems = append(problems, fmt.Sprintf("%s: column %s is %s but targetType %s requires %s",
+				attr.SourceName, attr.TargetName, columnType, attr.TargetType, expectedType))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("columns do not match table configuration: %s", strings.Join(problems, "; "))
+}
+
+// GetColumns returns the column definitions of the table derived from its configuration:
+// the provider metadata attributes (e.g. account_id, region_code) followed by all parsedAttributes
+// in configuration order. Columns are typed by targetType (see GetColumnType).
+// Columns of disabled attributes are kept empty, so enabling an attribute fills its column
+// without changing the columns of the table.
+func (tableConfig *TableConfig) GetColumns() ([]table.ColumnDefinition, error) {
+	columns := make([]table.ColumnDefinition, 0)
+	columnTypes := make(map[string]table.ColumnType)
+	addColumn := func(name string, columnType table.ColumnType) error {
+		if name == "" {
+			return nil
+		}
+		if existingType, found := columnTypes[name]; found {
+			if existingType != columnType {
+				return fmt.Errorf("column %s is defined as both %s and %s", name, existingType, columnType)
+			}
+			return nil
+		}
+		columnTypes[name] = columnType
+		columns = append(columns, table.ColumnDefinition{Name: name, Type: columnType})
+		return nil
+	}
+
+	metadataAttributes := []string{
+		tableConfig.Aws.AccountIDAttribute,
+		tableConfig.Aws.RegionCodeAttribute,
+		tableConfig.Aws.RegionAttribute,
+		tableConfig.Gcp.ProjectIDAttribute,
+		tableConfig.Gcp.ZoneAttribute,
+		tableConfig.Azure.SubscriptionIDAttribute,
+		tableConfig.Azure.TenantIDAttribute,
+		tableConfig.Azure.ResourceGroupAttribute,
+	}
+	for _, name := range metadataAttributes {
+		if err := addColumn(name, table.ColumnTypeText); err != nil {
+			return nil, err
+		}
+	}
+	for _, attr := range tableConfig.ParsedAttributes {
+		columnType, err := GetColumnType(attr.TargetType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", attr.SourceName, err)
+		}
+		if err := addColumn(attr.TargetName, columnType); err != nil {
+			return nil, err
+		}
+	}
+	return columns, nil
+}
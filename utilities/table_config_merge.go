@@ -0,0 +1,102 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package utilities
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MergeTableConfig merges json encoded table configurations in override over the ones in defaults.
+// Tables only present in override are added as is. For tables present in both, every setting in
+// override replaces the default one, objects (e.g. "aws") are merged key by key and parsedAttributes
+// are merged by sourceName. So an override can be as small as:
+//
+//	{"aws_s3_bucket": {"parsedAttributes": [{"sourceName": "MfaDelete", "enabled": false}]}}
+func MergeTableConfig(defaults []byte, override []byte) ([]byte, error) {
+	var defaultTables map[string]map[string]interface{}
+	if err := json.Unmarshal(defaults, &defaultTables); err != nil {
+		return nil, fmt.Errorf("invalid default table configuration: %w", err)
+	}
+	var overrideTables map[string]map[string]interface{}
+	if err := json.Unmarshal(override, &overrideTables); err != nil {
+		return nil, fmt.Errorf("invalid table configuration: %w", err)
+	}
+	if defaultTables == nil {
+		defaultTables = make(map[string]map[string]interface{})
+	}
+
+	for tableName, overrideTable := range overrideTables {
+		defaultTable, found := defaultTables[tableName]
+		if !found {
+			defaultTables[tableName] = overrideTable
+			continue
+		}
+		for key, value := range overrideTable {
+			if key == "parsedAttributes" {
+				merged, err := mergeParsedAttributes(defaultTable[key], value)
+				if err != nil {
+					return nil, fmt.Errorf("table %s: %w", tableName, err)
+				}
+				defaultTable[key] = merged
+				continue
+			}
+			defaultTable[key] = mergeValue(defaultTable[key], value)
+		}
+	}
+	return json.Marshal(defaultTables)
+}
+
+// mergeValue merges objects key by key. Any other override value replaces the default
+func mergeValue(defaultValue interface{}, overrideValue interface{}) interface{} {
+	defaultMap, defaultOk := defaultValue.(map[string]interface{})
+	overrideMap, overrideOk := overrideValue.(map[string]interface{})
+	if !defaultOk || !overrideOk {
+		return overrideValue
+	}
+	for key, value := range overrideMap {
+		defaultMap[key] = mergeValue(defaultMap[key], value)
+	}
+	return defaultMap
+}
+
+func mergeParsedAttributes(defaultValue interface{}, overrideValue interface{}) (interface{}, error) {
+	overrideAttrs, ok := overrideValue.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("parsedAttributes must be a list")
+	}
+	defaultAttrs, ok := defaultValue.([]interface{})
+	if !ok {
+		return overrideAttrs, nil
+	}
+
+	indexBySourceName := make(map[string]int)
+	for idx, attr := range defaultAttrs {
+		if attrMap, ok := attr.(map[string]interface{}); ok {
+			if sourceName, ok := attrMap["sourceName"].(string); ok {
+				indexBySourceName[sourceName] = idx
+			}
+		}
+	}
+	for _, attr := range overrideAttrs {
+		attrMap, ok := attr.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid parsedAttribute entry: %v", attr)
+		}
+		sourceName, _ := attrMap["sourceName"].(string)
+		if idx, found := indexBySourceName[sourceName]; found {
+			defaultAttrs[idx] = mergeValue(defaultAttrs[idx], attrMap)
+			continue
+		}
+		indexBySourceName[sourceName] = len(defaultAttrs)
+		defaultAttrs = append(defaultAttrs, attrMap)
+	}
+	return defaultAttrs, nil
+}
@@ -0,0 +1,156 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package utilities
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+)
+
+// TableDefinition describes a table provided by a table package.
+// Table packages register their tables from init() using RegisterTable.
+// Columns of the table are derived from its table configuration (see TableConfig.GetColumns).
+type TableDefinition struct {
+	Name     string
+	Generate table.GenerateFunc
+}
+
+// TableConfigFile is the default table_config.json of a table package embedded into the binary.
+// Path is relative to the extension home directory, e.g. "aws/ec2/table_config.json"
+type TableConfigFile struct {
+	Path string
+	Data []byte
+}
+
+// EventTable is a table whose rows are streamed by an event loop instead of being generated by queries.
+// Table packages register their event tables from init() using RegisterEventTable.
+type EventTable interface {
+	GetName() string
+	GetColumns() []table.ColumnDefinition
+	GetGenFunction() table.GenerateFunc
+	Start(ctx context.Context, wg *sync.WaitGroup, socket string, timeout time.Duration)
+	// ConfigurationChanged is called after configuration is reloaded, e.g. to pick up new buckets
+	ConfigurationChanged()
+}
+
+var (
+	tableRegistryMutex sync.Mutex
+	tableRegistry      = make(map[string]TableDefinition)
+	eventTableRegistry = make(map[string]EventTable)
+	tableConfigFiles   = make(map[string]TableConfigFile)
+)
+
+// RegisterTable adds a table to the registry. It panics if a table with the same name is already registered
+func RegisterTable(definition TableDefinition) {
+	tableRegistryMutex.Lock()
+	defer tableRegistryMutex.Unlock()
+	if _, found := tableRegistry[definition.Name]; found {
+		panic(fmt.Sprintf("table %s is registered twice", definition.Name))
+	}
+	tableRegistry[definition.Name] = definition
+}
+
+// RegisterEventTable adds an event table to the registry. It panics if an event table with the same name is already registered
+func RegisterEventTable(eventTable EventTable) {
+	tableRegistryMutex.Lock()
+	defer tableRegistryMutex.Unlock()
+	if _, found := eventTableRegistry[eventTable.GetName()]; found {
+		panic(fmt.Sprintf("event table %s is registered twice", eventTable.GetName()))
+	}
+	eventTableRegistry[eventTable.GetName()] = eventTable
+}
+
+// RegisterTableConfigFile adds the default table configuration of a table package to the registry.
+// It panics if a file with the same path is already registered
+func RegisterTableConfigFile(path string, data []byte) {
+	tableRegistryMutex.Lock()
+	defer tableRegistryMutex.Unlock()
+	if _, found := tableConfigFiles[path]; found {
+		panic(fmt.Sprintf("table config file %s is registered twice", path))
+	}
+	tableConfigFiles[path] = TableConfigFile{Path: path, Data: data}
+}
+
+// GetRegisteredTables returns all registered tables sorted by name
+func GetRegisteredTables() []TableDefinition {
+	tableRegistryMutex.Lock()
+	defer tableRegistryMutex.Unlock()
+	definitions := make([]TableDefinition, 0, len(tableRegistry))
+	for _, definition := range tableRegistry {
+		definitions = append(definitions, definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+	return definitions
+}
+
+// GetRegisteredEventTables returns all registered event tables sorted by name
+func GetRegisteredEventTables() []EventTable {
+	tableRegistryMutex.Lock()
+	defer tableRegistryMutex.Unlock()
+	eventTables := make([]EventTable, 0, len(eventTableRegistry))
+	for _, eventTable := range eventTableRegistry {
+		eventTables = append(eventTables, eventTable)
+	}
+	sort.Slice(eventTables, func(i, j int) bool {
+		return eventTables[i].GetName() < eventTables[j].GetName()
+	})
+	return eventTables
+}
+
+// GetTableConfigFiles returns all registered table config files sorted by path
+func GetTableConfigFiles() []TableConfigFile {
+	tableRegistryMutex.Lock()
+	defer tableRegistryMutex.Unlock()
+	files := make([]TableConfigFile, 0, len(tableConfigFiles))
+	for _, file := range tableConfigFiles {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// GetTableProvider returns the cloud provider of a table, which is the prefix of its name (aws, gcp or azure)
+func GetTableProvider(tableName string) string {
+	return strings.SplitN(tableName, "_", 2)[0]
+}
+
+// IsTableEnabled returns false if given table is disabled in extension configuration.
+// A table listed in enabledTables is always enabled, otherwise it is disabled if it is listed
+// in disabledTables or its provider is listed in disabledProviders
+func IsTableEnabled(tableName string) bool {
+	tablesConfig := CurrentConfiguration().Extension.ExtConfTables
+	for _, name := range tablesConfig.EnabledTables {
+		if name == tableName {
+			return true
+		}
+	}
+	for _, name := range tablesConfig.DisabledTables {
+		if name == tableName {
+			return false
+		}
+	}
+	provider := GetTableProvider(tableName)
+	for _, name := range tablesConfig.DisabledProviders {
+		if name == provider {
+			return false
+		}
+	}
+	return true
+}
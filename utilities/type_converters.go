@@ -11,9 +11,152 @@ package utilities
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+)
+
+// Supported values of targetType in parsedAttributes
+const (
+	TargetTypeText      = "TEXT"
+	TargetTypeInteger   = "INTEGER"
+	TargetTypeBigInt    = "BIGINT"
+	TargetTypeDouble    = "DOUBLE"
+	TargetTypeBoolean   = "BOOLEAN"
+	TargetTypeTimestamp = "TIMESTAMP"
 )
 
+// timestampLayouts are tried in order when converting a string to TIMESTAMP
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000Z0700",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// GetColumnType returns the osquery column type used for given targetType.
+// BOOLEAN values are stored as 0/1 and TIMESTAMP values as seconds since epoch.
+func GetColumnType(targetType string) (table.ColumnType, error) {
+	switch strings.ToUpper(targetType) {
+	case TargetTypeText:
+		return table.ColumnTypeText, nil
+	case TargetTypeInteger, TargetTypeBoolean:
+		return table.ColumnTypeInteger, nil
+	case TargetTypeBigInt, TargetTypeTimestamp:
+		return table.ColumnTypeBigInt, nil
+	case TargetTypeDouble:
+		return table.ColumnTypeDouble, nil
+	}
+	return table.ColumnTypeText, fmt.Errorf("unsupported targetType %s", targetType)
+}
+
+// ConvertValue returns the string representation of given value for a column of given targetType.
+// Empty string is returned if value can't be converted to a numeric targetType.
+func ConvertValue(value interface{}, targetType string) string {
+	switch strings.ToUpper(targetType) {
+	case TargetTypeInteger, TargetTypeBigInt:
+		if number, ok := getIntValue(value); ok {
+			return strconv.FormatInt(number, 10)
+		}
+		return ""
+	case TargetTypeDouble:
+		if number, ok := getFloatValue(value); ok {
+			return strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		return ""
+	case TargetTypeBoolean:
+		if number, ok := getFloatValue(value); ok {
+			if number != 0 {
+				return "1"
+			}
+			return "0"
+		}
+		return ""
+	case TargetTypeTimestamp:
+		return getTimestampValue(value)
+	}
+	return GetStringValue(value)
+}
+
+// getIntValue returns given value as int64. Integers are converted without going through float64
+// so that large values (e.g. ids) don't lose precision. Fractions are truncated.
+func getIntValue(value interface{}) (int64, bool) {
+	switch value := value.(type) {
+	case int:
+		return int64(value), true
+	case int32:
+		return int64(value), true
+	case int64:
+		return value, true
+	case uint32:
+		return int64(value), true
+	case json.Number:
+		if number, err := value.Int64(); err == nil {
+			return number, true
+		}
+	case string:
+		if number, err := strconv.ParseInt(GetStringValue(value), 10, 64); err == nil {
+			return number, true
+		}
+	}
+	number, ok := getFloatValue(value)
+	return int64(number), ok
+}
+
+// getFloatValue returns given number, numeric string or boolean (as 0/1) as float64
+func getFloatValue(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case nil:
+		return 0, false
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	case json.Number:
+		number, err := value.Float64()
+		return number, err == nil
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	}
+
+	strValue := GetStringValue(value)
+	if number, err := strconv.ParseFloat(strValue, 64); err == nil && !math.IsNaN(number) {
+		return number, true
+	}
+	if boolean, err := strconv.ParseBool(strValue); err == nil {
+		return getFloatValue(boolean)
+	}
+	return 0, false
+}
+
+// getTimestampValue returns seconds since epoch for a time string or a number (assumed to be epoch already)
+func getTimestampValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if timeValue, ok := value.(time.Time); ok {
+		return strconv.FormatInt(timeValue.Unix(), 10)
+	}
+	strValue := GetStringValue(value)
+	for _, layout := range timestampLayouts {
+		if timeValue, err := time.Parse(layout, strValue); err == nil {
+			return strconv.FormatInt(timeValue.Unix(), 10)
+		}
+	}
+	if number, ok := getFloatValue(value); ok {
+		return strconv.FormatInt(int64(number), 10)
+	}
+	return ""
+}
+
 // GetStringValue returns the string representation of given interface
 func GetStringValue(value interface{}) string {
 	if value == nil {
@@ -29,8 +172,7 @@ func GetStringValue(value interface{}) string {
 		}
 		return value
 	case json.Number:
-		val, _ := value.Int64()
-		return strconv.FormatInt(val, 10)
+		return value.String()
 	case bool:
 		return strconv.FormatBool(value)
 	}
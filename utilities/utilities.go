@@ -19,23 +19,35 @@ import (
 )
 
 var (
-	// TableConfigurationMap is the map of tableName->TableConfig
-	TableConfigurationMap = map[string]*TableConfig{}
 	// AwsAccountID is read from env variable AWS_ACCOUNT_ID
 	AwsAccountID string
-	// ExtConfiguration holds extension's config data including credential files` path
-	ExtConfiguration ExtensionConfiguration
 	// DefaultGcpProjectID is projectID read from file set in env var GOOGLE_APPLICATION_CREDENTIALS
 	DefaultGcpProjectID string
+	// HomeDirectory is the extension home directory, read from env variable CLOUDQUERY_EXT_HOME
+	HomeDirectory string
 )
 
 // ReadTableConfig parses json encoded data to read list TableConfig entries
-// These are available for reading from utilities.TableConfigurationMap[]
+// These are available for reading from CurrentConfiguration().Tables[]
 func ReadTableConfig(jsonEncoded []byte) error {
+	configurations, err := ParseTableConfig(jsonEncoded)
+	if err != nil {
+		return err
+	}
+	UpdateConfiguration(func(config *Configuration) {
+		for tableName, tableConfig := range configurations {
+			config.Tables[tableName] = tableConfig
+		}
+	})
+	return nil
+}
+
+// ParseTableConfig parses and validates json encoded TableConfig entries, without adding them to the configuration
+func ParseTableConfig(jsonEncoded []byte) (map[string]*TableConfig, error) {
 	var configurations map[string]*TableConfig
 	errUnmarshal := json.Unmarshal(jsonEncoded, &configurations)
 	if errUnmarshal != nil {
-		return errUnmarshal
+		return nil, errUnmarshal
 	}
 	for tableName, config := range configurations {
 		GetLogger().WithFields(log.Fields{
@@ -43,21 +55,28 @@ func ReadTableConfig(jsonEncoded []byte) error {
 		}).Debug("found table configuration")
 		for _, attr := range config.ParsedAttributes {
 			if attr.SourceName == "" || attr.TargetName == "" || attr.TargetType == "" {
-				return fmt.Errorf("invalid parsedAttribute entry: %+v", attr)
+				return nil, fmt.Errorf("invalid parsedAttribute entry: %+v", attr)
 			}
+			if _, err := GetColumnType(attr.TargetType); err != nil {
+				return nil, fmt.Errorf("invalid parsedAttribute entry: %+v: %w", attr, err)
+			}
+		}
+		if _, err := config.GetColumns(); err != nil {
+			return nil, fmt.Errorf("invalid configuration for table %s: %w", tableName, err)
+		}
+		if err := ValidateRegionPatterns(append(config.Aws.IncludeRegions, config.Aws.ExcludeRegions...)); err != nil {
+			return nil, fmt.Errorf("invalid configuration for table %s: %w", tableName, err)
 		}
 		config.initParsedAttributeConfigMap()
-		TableConfigurationMap[tableName] = config
 	}
-	
-	return nil
+	return configurations, nil
 }
 
-// RowToMap converts JSON row into osquery row
+// RowToMap converts JSON row into osquery row. Values are converted as per targetType of the attributes
 func RowToMap(inMap map[string]string, row map[string]interface{}, tableConfig *TableConfig) map[string]string {
 	for key, value := range tableConfig.getParsedAttributeConfigMap() {
 		if row[key] != nil {
-			inMap[value.TargetName] = GetStringValue(row[key])
+			inMap[value.TargetName] = ConvertValue(row[key], value.TargetType)
 		}
 	}
 	return inMap
@@ -10,10 +10,17 @@
 package utilities
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/Uptycs/basequery-go/plugin/table"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -136,7 +143,7 @@ func TestReadTableConfig(t *testing.T) {
 	readErr := ReadTableConfig([]byte(tableConfigJSON))
 	assert.Nil(t, readErr)
 
-	myTable1, found := TableConfigurationMap["test_table_1"]
+	myTable1, found := CurrentConfiguration().Tables["test_table_1"]
 	assert.True(t, found)
 
 	assert.Equal(t, 4, len(myTable1.ParsedAttributes))
@@ -144,18 +151,18 @@ func TestReadTableConfig(t *testing.T) {
 	// Col "Item_Object_Name" is deepest enabled attributes with level 2
 	assert.Equal(t, 2, myTable1.MaxLevel)
 
-	for _, v := range TableConfigurationMap {
+	for _, v := range CurrentConfiguration().Tables {
 		assert.Equal(t, len(v.parsedAttributeConfigMap), len(v.ParsedAttributes))
 	}
 
-	assert.Equal(t, 3, len(TableConfigurationMap))
+	assert.Equal(t, 3, len(CurrentConfiguration().Tables))
 }
 
 func TestRowToMap(t *testing.T) {
 	readErr := ReadTableConfig([]byte(tableConfigJSON))
 	assert.Nil(t, readErr)
 
-	tabConfig, found := TableConfigurationMap["test_table_1"]
+	tabConfig, found := CurrentConfiguration().Tables["test_table_1"]
 	assert.True(t, found)
 
 	inRow := make(map[string]interface{})
@@ -279,7 +286,7 @@ func TestNewTable(t *testing.T) {
 	readErr := ReadTableConfig([]byte(tableConfigJSON))
 	assert.Nil(t, readErr)
 
-	myTable1, found := TableConfigurationMap["table_test_table_1"]
+	myTable1, found := CurrentConfiguration().Tables["table_test_table_1"]
 	assert.True(t, found)
 
 	tableWithConfig := NewTable([]byte(tableJSON1), myTable1)
@@ -288,3 +295,560 @@ func TestNewTable(t *testing.T) {
 	table := NewTable([]byte(tableJSON1), nil)
 	assert.Equal(t, 2, len(table.Rows))
 }
+
+func TestGetEqualsConstraints(t *testing.T) {
+	queryContext := table.QueryContext{
+		Constraints: map[string]table.ConstraintList{
+			"region_code": {
+				Affinity: table.ColumnTypeText,
+				Constraints: []table.Constraint{
+					{Operator: table.OperatorEquals, Expression: "us-east-1"},
+					{Operator: table.OperatorLike, Expression: "eu-%"},
+					{Operator: table.OperatorEquals, Expression: "us-west-2"},
+				},
+			},
+		},
+	}
+	assert.Equal(t, []string{"us-east-1", "us-west-2"}, GetEqualsConstraints(queryContext, "region_code"))
+	assert.Equal(t, 0, len(GetEqualsConstraints(queryContext, "account_id")))
+
+	assert.True(t, MatchesEqualsConstraints(queryContext, "region_code", "us-west-2"))
+	assert.False(t, MatchesEqualsConstraints(queryContext, "region_code", "eu-west-1"))
+	assert.True(t, MatchesEqualsConstraints(queryContext, "account_id", "123456789012"))
+}
+
+func TestRowCollector(t *testing.T) {
+	maxWorkers := 4
+	collector := NewRowCollector(context.Background(), maxWorkers)
+	var running, maxRunning int32
+	for i := 0; i < 100; i++ {
+		id := strconv.Itoa(i)
+		collector.Go(func() {
+			current := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+					break
+				}
+			}
+			collector.Add(map[string]string{"id": id})
+			collector.AddRows([]map[string]string{{"id": id}})
+			atomic.AddInt32(&running, -1)
+		})
+	}
+	rows := collector.Wait()
+	assert.Equal(t, 200, len(rows))
+	assert.LessOrEqual(t, int(maxRunning), maxWorkers)
+}
+
+func TestRowCollector_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	collector := NewRowCollector(ctx, 1)
+	for i := 0; i < 10; i++ {
+		collector.Go(func() {
+			collector.Add(map[string]string{"id": "1"})
+		})
+	}
+	assert.Equal(t, 0, len(collector.Wait()))
+}
+
+func TestWithConfigurationSnapshot(t *testing.T) {
+	savedConfiguration := CurrentConfiguration()
+	defer SetConfiguration(savedConfiguration.Extension, savedConfiguration.Tables)
+	SetConfiguration(ExtensionConfiguration{}, map[string]*TableConfig{"old_table": {}})
+
+	started := make(chan struct{})
+	replaced := make(chan struct{})
+	generate := WithConfigurationSnapshot(func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		close(started)
+		<-replaced
+		// Configuration replaced meanwhile is not seen by the running query
+		_, found := GetConfiguration(ctx).Tables["old_table"]
+		assert.True(t, found)
+		return nil, nil
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		generate(context.Background(), table.QueryContext{})
+	}()
+	<-started
+	SetConfiguration(ExtensionConfiguration{}, map[string]*TableConfig{"new_table": {}})
+	close(replaced)
+	<-done
+
+	_, found := GetConfiguration(context.Background()).Tables["new_table"]
+	assert.True(t, found)
+}
+
+func TestWithResultCache(t *testing.T) {
+	err := ReadTableConfig([]byte(`{"cached_table": {"cacheTtl": 60, "parsedAttributes": []}, "uncached_table": {"parsedAttributes": []}}`))
+	assert.Nil(t, err)
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	calls := 0
+	var genErr error
+	generate := func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+		calls++
+		return []map[string]string{{"call": strconv.Itoa(calls)}}, genErr
+	}
+	queryContext1 := table.QueryContext{Constraints: map[string]table.ConstraintList{
+		"id": {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: "1"}}},
+	}}
+	queryContext2 := table.QueryContext{Constraints: map[string]table.ConstraintList{
+		"id": {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: "2"}}},
+	}}
+
+	cached := WithResultCache("cached_table", generate)
+	cached(context.Background(), queryContext1)
+	rows, _ := cached(context.Background(), queryContext1)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "1", rows[0]["call"])
+	// Different constraints
+	cached(context.Background(), queryContext2)
+	assert.Equal(t, 2, calls)
+	// Expired
+	now = now.Add(61 * time.Second)
+	cached(context.Background(), queryContext1)
+	assert.Equal(t, 3, calls)
+	// Invalidated
+	InvalidateResultCache("cached_table")
+	cached(context.Background(), queryContext1)
+	assert.Equal(t, 4, calls)
+	// Bypassed
+	UpdateConfiguration(func(config *Configuration) {
+		config.Extension.ExtConfCache.Disabled = true
+	})
+	cached(context.Background(), queryContext1)
+	assert.Equal(t, 5, calls)
+	UpdateConfiguration(func(config *Configuration) {
+		config.Extension.ExtConfCache.Disabled = false
+	})
+	// Bypassed by a query. Rows replace the cached ones and fill the bypass column for osquery to match them
+	bypassContext := table.QueryContext{Constraints: map[string]table.ConstraintList{
+		"id":              queryContext1.Constraints["id"],
+		CacheBypassColumn: {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: "1"}}},
+	}}
+	rows, _ = cached(context.Background(), bypassContext)
+	assert.Equal(t, 6, calls)
+	assert.Equal(t, map[string]string{"call": "6", CacheBypassColumn: "1"}, rows[0])
+	rows, _ = cached(context.Background(), queryContext1)
+	assert.Equal(t, 6, calls)
+	assert.Equal(t, map[string]string{"call": "6"}, rows[0])
+	// Errors are not cached
+	InvalidateResultCache("")
+	genErr = fmt.Errorf("failed")
+	cached(context.Background(), queryContext1)
+	cached(context.Background(), queryContext1)
+	assert.Equal(t, 8, calls)
+	genErr = nil
+
+	uncached := WithResultCache("uncached_table", generate)
+	uncached(context.Background(), queryContext1)
+	uncached(context.Background(), queryContext1)
+	assert.Equal(t, 10, calls)
+}
+
+func TestGetCachedSession(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	InvalidateSessionCache("")
+
+	calls := 0
+	var createErr error
+	create := func() (interface{}, error) {
+		calls++
+		return calls, createErr
+	}
+	key1 := GetSessionCacheKey(ProviderAws, "111111111111")
+	key2 := GetSessionCacheKey(ProviderAws, "222222222222")
+
+	GetCachedSession(ProviderAws, key1, create)
+	session, _ := GetCachedSession(ProviderAws, key1, create)
+	assert.Equal(t, 1, session)
+	GetCachedSession(ProviderAws, key2, create)
+	assert.Equal(t, 2, calls)
+	GetCachedSession(ProviderGcp, GetSessionCacheKey(ProviderGcp, "key.json"), create)
+	// Expired
+	now = now.Add(time.Duration(defaultSessionTTL+1) * time.Second)
+	GetCachedSession(ProviderAws, key1, create)
+	assert.Equal(t, 4, calls)
+	// Invalidated by provider
+	InvalidateSessionCache(ProviderAws)
+	GetCachedSession(ProviderAws, key1, create)
+	assert.Equal(t, 5, calls)
+	// Errors are not cached
+	InvalidateSessionCache("")
+	createErr = fmt.Errorf("failed")
+	_, err := GetCachedSession(ProviderAws, key1, create)
+	assert.NotNil(t, err)
+	GetCachedSession(ProviderAws, key1, create)
+	assert.Equal(t, 7, calls)
+	createErr = nil
+	// Disabled
+	UpdateConfiguration(func(config *Configuration) {
+		config.Extension.ExtConfCache.SessionTTL = -1
+	})
+	GetCachedSession(ProviderAws, key1, create)
+	GetCachedSession(ProviderAws, key1, create)
+	assert.Equal(t, 9, calls)
+	UpdateConfiguration(func(config *Configuration) {
+		config.Extension.ExtConfCache.SessionTTL = 0
+	})
+
+	GetCachedSession(ProviderAws, key1, create)
+	stats := GetSessionCacheStats()
+	assert.Equal(t, []SessionCacheStats{
+		{Provider: ProviderAws, Entries: 1, Hits: 1, Misses: 7},
+		{Provider: ProviderGcp, Entries: 0, Hits: 0, Misses: 1},
+	}, stats)
+}
+
+func TestGetCachedSessionConcurrent(t *testing.T) {
+	InvalidateSessionCache("")
+	defer InvalidateSessionCache("")
+
+	var calls int32
+	release := make(chan struct{})
+	slowCreate := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "slow", nil
+	}
+	var wg sync.WaitGroup
+	sessions := make([]interface{}, 4)
+	for i := range sessions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sessions[i], _ = GetCachedSession(ProviderGcp, GetSessionCacheKey(ProviderGcp, "slow.json"), slowCreate)
+		}(i)
+	}
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// Other keys are not blocked by the session being created
+	session, err := GetCachedSession(ProviderGcp, GetSessionCacheKey(ProviderGcp, "fast.json"), func() (interface{}, error) {
+		return "fast", nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "fast", session)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, []interface{}{"slow", "slow", "slow", "slow"}, sessions)
+}
+
+func TestIsTableEnabled(t *testing.T) {
+	defer func() {
+		UpdateConfiguration(func(config *Configuration) {
+			config.Extension.ExtConfTables = ExtensionConfigurationTables{}
+		})
+	}()
+
+	assert.True(t, IsTableEnabled("aws_s3_bucket"))
+	UpdateConfiguration(func(config *Configuration) {
+		config.Extension.ExtConfTables = ExtensionConfigurationTables{
+			DisabledProviders: []string{"azure"},
+			DisabledTables:    []string{"aws_s3_bucket"},
+			EnabledTables:     []string{"azure_compute_vm"},
+		}
+	})
+	assert.False(t, IsTableEnabled("aws_s3_bucket"))
+	assert.True(t, IsTableEnabled("aws_ec2_instance"))
+	assert.False(t, IsTableEnabled("azure_compute_disk"))
+	assert.True(t, IsTableEnabled("azure_compute_vm"))
+	assert.True(t, IsTableEnabled("gcp_compute_disk"))
+}
+
+func TestRegisterTable(t *testing.T) {
+	RegisterTable(TableDefinition{Name: "test_registry_table"})
+	assert.Panics(t, func() { RegisterTable(TableDefinition{Name: "test_registry_table"}) })
+	RegisterTableConfigFile("test/registry/table_config.json", []byte("{}"))
+	assert.Panics(t, func() { RegisterTableConfigFile("test/registry/table_config.json", []byte("{}")) })
+
+	found := false
+	for _, definition := range GetRegisteredTables() {
+		found = found || definition.Name == "test_registry_table"
+	}
+	assert.True(t, found)
+	assert.Equal(t, "test", GetTableProvider("test_registry_table"))
+
+	RegisterEventTable(&testEventTable{name: "test_registry_events"})
+	assert.Panics(t, func() { RegisterEventTable(&testEventTable{name: "test_registry_events"}) })
+	found = false
+	for _, eventTable := range GetRegisteredEventTables() {
+		found = found || eventTable.GetName() == "test_registry_events"
+	}
+	assert.True(t, found)
+}
+
+type testEventTable struct {
+	name string
+}
+
+func (eventTable *testEventTable) GetName() string                      { return eventTable.name }
+func (eventTable *testEventTable) GetColumns() []table.ColumnDefinition { return nil }
+func (eventTable *testEventTable) GetGenFunction() table.GenerateFunc   { return nil }
+func (eventTable *testEventTable) Start(ctx context.Context, wg *sync.WaitGroup, socket string, timeout time.Duration) {
+}
+func (eventTable *testEventTable) ConfigurationChanged() {}
+
+func TestMergeTableConfig(t *testing.T) {
+	defaults := `{
+		"merge_table_1": {
+			"aws": {"regionAttribute": "region", "accountIdAttribute": "account_id"},
+			"parsedAttributes": [
+				{"sourceName": "Name", "targetName": "name", "targetType": "TEXT", "enabled": true},
+				{"sourceName": "Size", "targetName": "size", "targetType": "BIGINT", "enabled": true}
+			]
+		},
+		"merge_table_2": {"parsedAttributes": []}
+	}`
+	override := `{
+		"merge_table_1": {
+			"cacheTtl": 30,
+			"aws": {"regionAttribute": "region_name"},
+			"parsedAttributes": [
+				{"sourceName": "Size", "enabled": false},
+				{"sourceName": "Owner", "targetName": "owner", "targetType": "TEXT", "enabled": true}
+			]
+		},
+		"merge_table_3": {"parsedAttributes": []}
+	}`
+	merged, err := MergeTableConfig([]byte(defaults), []byte(override))
+	assert.Nil(t, err)
+	assert.Nil(t, ReadTableConfig(merged))
+
+	table1 := CurrentConfiguration().Tables["merge_table_1"]
+	assert.Equal(t, 30, table1.CacheTTL)
+	assert.Equal(t, "region_name", table1.Aws.RegionAttribute)
+	assert.Equal(t, "account_id", table1.Aws.AccountIDAttribute)
+	assert.Equal(t, 3, len(table1.ParsedAttributes))
+	assert.Equal(t, ParsedAttributeConfig{SourceName: "Size", TargetName: "size", TargetType: "BIGINT", Enabled: false}, table1.ParsedAttributes[1])
+	assert.Equal(t, "owner", table1.GetTargetName("Owner"))
+	assert.NotNil(t, CurrentConfiguration().Tables["merge_table_2"])
+	assert.NotNil(t, CurrentConfiguration().Tables["merge_table_3"])
+
+	_, err = MergeTableConfig([]byte(defaults), []byte(`{"merge_table_1": {"parsedAttributes": {}}}`))
+	assert.NotNil(t, err)
+	_, err = MergeTableConfig([]byte(defaults), []byte(`not json`))
+	assert.NotNil(t, err)
+}
+
+func TestConvertValue(t *testing.T) {
+	list := []struct {
+		In         interface{}
+		TargetType string
+		Expected   string
+	}{
+		{"astring", "TEXT", "astring"},
+		{json.Number("12.5"), "TEXT", "12.5"},
+		{float64(1000002), "INTEGER", "1000002"},
+		{float64(1634567890123), "BIGINT", "1634567890123"},
+		{json.Number("9007199254740993"), "BIGINT", "9007199254740993"},
+		{"\"42\"", "integer", "42"},
+		{12.9, "INTEGER", "12"},
+		{"not a number", "INTEGER", ""},
+		{json.Number("12.5"), "DOUBLE", "12.5"},
+		{float64(1000002.5), "DOUBLE", "1000002.5"},
+		{true, "BOOLEAN", "1"},
+		{false, "BOOLEAN", "0"},
+		{"true", "BOOLEAN", "1"},
+		{true, "INTEGER", "1"},
+		{"2021-10-18T12:51:30Z", "TIMESTAMP", "1634561490"},
+		{"2021-10-18T12:51:30.123+00:00", "TIMESTAMP", "1634561490"},
+		{float64(1634561490), "TIMESTAMP", "1634561490"},
+		{"yesterday", "TIMESTAMP", ""},
+		{nil, "BIGINT", ""},
+	}
+	for _, entry := range list {
+		assert.Equal(t, entry.Expected, ConvertValue(entry.In, entry.TargetType), "%v as %s", entry.In, entry.TargetType)
+	}
+}
+
+func TestValidateColumns(t *testing.T) {
+	err := ReadTableConfig([]byte(`{"typed_table": {"parsedAttributes": [
+		{"sourceName": "Name", "targetName": "name", "targetType": "TEXT", "enabled": true},
+		{"sourceName": "Size", "targetName": "size", "targetType": "BIGINT", "enabled": true},
+		{"sourceName": "Public", "targetName": "public", "targetType": "BOOLEAN", "enabled": true},
+		{"sourceName": "Created", "targetName": "created", "targetType": "TIMESTAMP", "enabled": true},
+		{"sourceName": "Owner", "targetName": "owner", "targetType": "TEXT", "enabled": false}
+	]}}`))
+	assert.Nil(t, err)
+	tableConfig := CurrentConfiguration().Tables["typed_table"]
+
+	columns := []table.ColumnDefinition{
+		table.TextColumn("name"),
+		table.BigIntColumn("size"),
+		table.IntegerColumn("public"),
+		table.BigIntColumn("created"),
+		table.TextColumn("owner"),
+	}
+	assert.Nil(t, tableConfig.ValidateColumns(columns))
+	// Columns of disabled attributes are required too
+	err = tableConfig.ValidateColumns(columns[:4])
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "column owner is not defined")
+
+	columns[1] = table.TextColumn("size")
+	err = tableConfig.ValidateColumns(columns[:3])
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "column size is TEXT")
+	assert.Contains(t, err.Error(), "column created is not defined")
+
+	err = ReadTableConfig([]byte(`{"typed_table_2": {"parsedAttributes": [
+		{"sourceName": "Name", "targetName": "name", "targetType": "VARCHAR", "enabled": true}
+	]}}`))
+	assert.NotNil(t, err)
+}
+
+func TestGetColumns(t *testing.T) {
+	err := ReadTableConfig([]byte(`{"derived_table": {
+		"aws": {"regionAttribute": "region", "regionCodeAttribute": "region_code", "accountIdAttribute": "account_id"},
+		"parsedAttributes": [
+			{"sourceName": "InstanceId", "targetName": "instance_id", "targetType": "TEXT", "enabled": true},
+			{"sourceName": "AmiLaunchIndex", "targetName": "ami_launch_index", "targetType": "INTEGER", "enabled": true},
+			{"sourceName": "MetadataOptions_HttpTokens", "targetName": "metadata_options_http_tokens", "targetType": "TEXT", "enabled": true},
+			{"sourceName": "LaunchTime", "targetName": "launch_time", "targetType": "TIMESTAMP", "enabled": true},
+			{"sourceName": "Platform", "targetName": "platform", "targetType": "TEXT", "enabled": false}
+		]}}`))
+	assert.Nil(t, err)
+
+	columns, err := CurrentConfiguration().Tables["derived_table"].GetColumns()
+	assert.Nil(t, err)
+	assert.Equal(t, []table.ColumnDefinition{
+		table.TextColumn("account_id"),
+		table.TextColumn("region_code"),
+		table.TextColumn("region"),
+		table.TextColumn("instance_id"),
+		table.IntegerColumn("ami_launch_index"),
+		table.TextColumn("metadata_options_http_tokens"),
+		table.BigIntColumn("launch_time"),
+		table.TextColumn("platform"),
+	}, columns)
+	assert.Nil(t, CurrentConfiguration().Tables["derived_table"].ValidateColumns(columns))
+
+	err = ReadTableConfig([]byte(`{"derived_table_2": {"parsedAttributes": [
+		{"sourceName": "Size", "targetName": "size", "targetType": "TEXT", "enabled": true},
+		{"sourceName": "SizeGb", "targetName": "size", "targetType": "BIGINT", "enabled": true}
+	]}}`))
+	assert.NotNil(t, err)
+}
+
+func TestCheckpointStore(t *testing.T) {
+	path := t.TempDir() + string(os.PathSeparator) + "checkpoints" + string(os.PathSeparator) + "test_events.json"
+	store, err := NewCheckpointStore(path, time.Hour)
+	assert.Nil(t, err)
+	_, found := store.GetMarker("bucket")
+	assert.False(t, found)
+
+	marker := CheckpointMarker{ModifiedTime: time.Unix(1600000000, 0).UTC(), Key: "prefix/2020/09/13/file.json.gz", Prefix: "prefix/2020/09/13"}
+	store.SetMarker("bucket", marker)
+	store.AddProcessedObject("bucketprefix/2020/09/13/file.json.gz")
+	store.SetObjectOffset("bucketprefix/2020/09/13/partial.json.gz", 500)
+	assert.Nil(t, store.Flush())
+
+	// Restart
+	store, err = NewCheckpointStore(path, time.Hour)
+	assert.Nil(t, err)
+	loadedMarker, found := store.GetMarker("bucket")
+	assert.True(t, found)
+	assert.Equal(t, marker, loadedMarker)
+	assert.True(t, store.IsObjectProcessed("bucketprefix/2020/09/13/file.json.gz"))
+	assert.False(t, store.IsObjectProcessed("bucketprefix/2020/09/13/other.json.gz"))
+	assert.Equal(t, 500, store.GetObjectOffset("bucketprefix/2020/09/13/partial.json.gz"))
+	assert.Equal(t, 0, store.GetObjectOffset("bucketprefix/2020/09/13/other.json.gz"))
+	// Offset is dropped once the object is fully processed
+	store.AddProcessedObject("bucketprefix/2020/09/13/partial.json.gz")
+	assert.Equal(t, 0, store.GetObjectOffset("bucketprefix/2020/09/13/partial.json.gz"))
+	store.SetObjectOffset("bucketprefix/2020/09/13/other.json.gz", 1000)
+
+	// Processed objects are forgotten after retention
+	defer func() { timeNow = time.Now }()
+	timeNow = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	assert.False(t, store.IsObjectProcessed("bucketprefix/2020/09/13/file.json.gz"))
+	assert.Nil(t, store.Flush())
+	store, err = NewCheckpointStore(path, time.Hour)
+	assert.Nil(t, err)
+	assert.Empty(t, store.data.Objects)
+	assert.Empty(t, store.data.Offsets)
+	_, found = store.GetMarker("bucket")
+	assert.True(t, found)
+
+	// Markers of buckets which are no longer configured are removed
+	store.SetMarker("other-bucket", marker)
+	assert.Equal(t, []string{"other-bucket"}, store.PruneMarkers(map[string]bool{"bucket": true}))
+	_, found = store.GetMarker("bucket")
+	assert.True(t, found)
+
+	assert.Nil(t, os.WriteFile(path, []byte("{"), 0600))
+	_, err = NewCheckpointStore(path, time.Hour)
+	assert.NotNil(t, err)
+}
+
+func TestMatchesRowFilter(t *testing.T) {
+	var filters map[string]ExtensionConfigurationTableFilter
+	err := json.Unmarshal([]byte(`{
+		"test_filter_table": {
+			"include": [
+				{"attribute": "State_Name", "operator": "regex", "value": "^(running|stopped)$"},
+				{"attribute": "CpuOptions_CoreCount", "operator": "ge", "value": 2}
+			],
+			"exclude": [
+				{"attribute": "Tags", "operator": "tag", "key": "env", "value": "dev"},
+				{"attribute": "InstanceType", "operator": "eq", "value": "t2.micro"}
+			]
+		},
+		"test_event_table": {
+			"exclude": [{"attribute": "event_source", "operator": "eq", "value": "s3.amazonaws.com"}]
+		}
+	}`), &filters)
+	assert.Nil(t, err)
+	assert.Nil(t, ValidateRowFilters(filters))
+	savedConfiguration := CurrentConfiguration()
+	defer func() {
+		UpdateConfiguration(func(config *Configuration) { config.Extension = savedConfiguration.Extension })
+	}()
+	UpdateConfiguration(func(config *Configuration) {
+		config.Extension.ExtConfFilters = filters
+	})
+
+	row := map[string]interface{}{
+		"State_Name":           "\"running\"",
+		"CpuOptions_CoreCount": float64(4),
+		"InstanceType":         "m5.large",
+		"Tags":                 `[{"Key":"env","Value":"prod"},{"Key":"team","Value":"sec"}]`,
+	}
+	assert.True(t, MatchesRowFilter(context.Background(), "test_filter_table", row))
+	row["Tags"] = `[{"Key":"team","Value":"sec"},{"Key":"env","Value":"dev"}]`
+	assert.False(t, MatchesRowFilter(context.Background(), "test_filter_table", row))
+	row["Tags"] = map[string]interface{}{"env": "prod"}
+	assert.True(t, MatchesRowFilter(context.Background(), "test_filter_table", row))
+	row["CpuOptions_CoreCount"] = "1"
+	assert.False(t, MatchesRowFilter(context.Background(), "test_filter_table", row))
+	delete(row, "CpuOptions_CoreCount")
+	assert.False(t, MatchesRowFilter(context.Background(), "test_filter_table", row))
+	assert.True(t, MatchesRowFilter(context.Background(), "unknown_table", row))
+
+	assert.False(t, MatchesEventFilter(context.Background(), "test_event_table", map[string]string{"event_source": "s3.amazonaws.com"}))
+	assert.True(t, MatchesEventFilter(context.Background(), "test_event_table", map[string]string{"event_source": "ec2.amazonaws.com"}))
+	// The configuration of the context applies, not the latest one
+	snapshotCtx := ContextWithConfiguration(context.Background(), &Configuration{})
+	assert.True(t, MatchesEventFilter(snapshotCtx, "test_event_table", map[string]string{"event_source": "s3.amazonaws.com"}))
+
+	assert.NotNil(t, ValidateRowFilters(map[string]ExtensionConfigurationTableFilter{
+		"test_filter_table": {Include: []ExtensionConfigurationFilterRule{{Attribute: "Name", Operator: "regex", Value: "("}}},
+	}))
+	assert.NotNil(t, ValidateRowFilters(map[string]ExtensionConfigurationTableFilter{
+		"test_filter_table": {Exclude: []ExtensionConfigurationFilterRule{{Attribute: "Size", Operator: "gt", Value: "large"}}},
+	}))
+	assert.NotNil(t, ValidateRowFilters(map[string]ExtensionConfigurationTableFilter{
+		"test_filter_table": {Exclude: []ExtensionConfigurationFilterRule{{Attribute: "Size", Operator: "like"}}},
+	}))
+}
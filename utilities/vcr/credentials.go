@@ -0,0 +1,68 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package vcr
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tokenResponse answers OAuth2 token requests of GCP and Azure clients during replay.
+// expires_on (Azure) is far in the future so that the token is requested once per session
+var tokenResponse = Response{
+	StatusCode: http.StatusOK,
+	Headers:    http.Header{"Content-Type": {"application/json"}},
+	Body:       `{"access_token": "replay", "token_type": "Bearer", "expires_in": "3600", "expires_on": "4102444800"}`,
+}
+
+// replayGcpCredentials are the default GCP credentials during replay. Their token is requested with
+// a refresh token, so no private key is needed
+const replayGcpCredentials = `{
+  "type": "authorized_user",
+  "client_id": "replay",
+  "client_secret": "replay",
+  "refresh_token": "replay"
+}`
+
+// isTokenRequest returns true if req requests an OAuth2 token from Google or Azure Active Directory
+func isTokenRequest(req *http.Request) bool {
+	switch req.URL.Host {
+	case "oauth2.googleapis.com":
+		return req.URL.Path == "/token"
+	case "login.microsoftonline.com":
+		return strings.HasSuffix(req.URL.Path, "/oauth2/token") || strings.HasSuffix(req.URL.Path, "/oauth2/v2.0/token")
+	}
+	return false
+}
+
+// setReplayCredentials makes the default credentials of AWS and GCP clients fake ones for the duration
+// of the test, so that replayed tests neither need nor use the credentials of the developer
+func setReplayCredentials(t testing.TB) {
+	dir := t.TempDir()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDREPLAY")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "replay")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_DEFAULT_PROFILE", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "aws_config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "aws_credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	// The HTTP client of the recorder can't be given a CA bundle
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	gcpCredentials := filepath.Join(dir, "gcp_credentials.json")
+	if err := os.WriteFile(gcpCredentials, []byte(replayGcpCredentials), 0600); err != nil {
+		t.Fatalf("failed to write GCP credentials: %s", err.Error())
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", gcpCredentials)
+}
@@ -0,0 +1,124 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package vcr
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/Uptycs/basequery-go/plugin/table"
+	"github.com/Uptycs/cloudquery/utilities"
+	"github.com/stretchr/testify/assert"
+)
+
+// UpdateGoldenEnv is the environment variable which makes AssertGoldenRows rewrite golden files
+// from replayed responses, e.g. after a table configuration change
+const UpdateGoldenEnv = "CLOUDQUERY_VCR_UPDATE_GOLDEN"
+
+// Start injects a recorder for the cassette at path (see utilities.SetHTTPTransport) for the duration of the test.
+// Mode is read from ModeEnv. The test fails if a request is not found in the cassette.
+// During replay, default AWS and GCP credentials are fake ones and token requests are answered by the recorder.
+// The cassette is saved when the test ends in ModeRecord.
+func Start(t testing.TB, path string) *Recorder {
+	mode := GetMode()
+	recorder, err := New(path, mode)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %s", err.Error())
+	}
+	if mode == ModeReplay {
+		setReplayCredentials(t)
+	}
+	utilities.SetHTTPTransport(recorder)
+	t.Cleanup(func() {
+		utilities.SetHTTPTransport(nil)
+		for _, miss := range recorder.Misses() {
+			t.Errorf("no interaction in cassette for %s", miss)
+		}
+		if err := recorder.Stop(); err != nil {
+			t.Errorf("failed to save cassette: %s", err.Error())
+		}
+	})
+	return recorder
+}
+
+// AssertGoldenRows compares rows, in any order, with the ones saved in the golden file at path.
+// The golden file is rewritten instead in ModeRecord or if UpdateGoldenEnv is set.
+func AssertGoldenRows(t testing.TB, path string, rows []map[string]string) {
+	sortedRows := sortRows(rows)
+	if GetMode() == ModeRecord || os.Getenv(UpdateGoldenEnv) != "" {
+		jsonEncoded, err := json.MarshalIndent(sortedRows, "", "  ")
+		if err != nil {
+			t.Fatalf("failed to marshal rows: %s", err.Error())
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create golden file: %s", err.Error())
+		}
+		if err := ioutil.WriteFile(path, append(jsonEncoded, '\n'), 0644); err != nil {
+			t.Fatalf("failed to write golden file: %s", err.Error())
+		}
+		return
+	}
+
+	jsonEncoded, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %s", err.Error())
+	}
+	expected := make([]map[string]string, 0)
+	if err := json.Unmarshal(jsonEncoded, &expected); err != nil {
+		t.Fatalf("invalid golden file %s: %s", path, err.Error())
+	}
+	assert.Equal(t, sortRows(expected), sortedRows)
+}
+
+// RunGoldenTests runs the generate function of each table in a subtest named after the table. Responses are replayed from
+// testdata/<table name>.cassette.json (see Start) and the rows are compared with testdata/<table name>.golden.json
+// (see AssertGoldenRows). Table and extension configurations must be set by the caller.
+func RunGoldenTests(t *testing.T, generateFuncs map[string]table.GenerateFunc) {
+	tableNames := make([]string, 0, len(generateFuncs))
+	for tableName := range generateFuncs {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+	for _, tableName := range tableNames {
+		generate := generateFuncs[tableName]
+		t.Run(tableName, func(t *testing.T) {
+			Start(t, filepath.Join("testdata", tableName+".cassette.json"))
+			rows, err := generate(context.Background(), table.QueryContext{})
+			assert.Nil(t, err)
+			AssertGoldenRows(t, filepath.Join("testdata", tableName+".golden.json"), rows)
+		})
+	}
+}
+
+func sortRows(rows []map[string]string) []map[string]string {
+	type keyedRow struct {
+		key string
+		row map[string]string
+	}
+	keyedRows := make([]keyedRow, 0, len(rows))
+	for _, row := range rows {
+		// maps are marshalled with sorted keys
+		key, _ := json.Marshal(row)
+		keyedRows = append(keyedRows, keyedRow{key: string(key), row: row})
+	}
+	sort.Slice(keyedRows, func(i, j int) bool {
+		return keyedRows[i].key < keyedRows[j].key
+	})
+	sorted := make([]map[string]string, 0, len(rows))
+	for _, keyed := range keyedRows {
+		sorted = append(sorted, keyed.row)
+	}
+	return sorted
+}
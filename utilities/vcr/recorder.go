@@ -0,0 +1,265 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+// Package vcr records HTTP interactions of cloud API clients into cassette files and replays them,
+// so that tables can be tested offline.
+//
+// Tests call Start, which injects a Recorder with utilities.SetHTTPTransport. By default the cassette
+// is replayed and no credentials are needed. With CLOUDQUERY_VCR_MODE=record, requests are sent to the
+// cloud with the configured credentials and the cassette (and golden files) are rewritten.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ModeEnv is the environment variable selecting the recorder mode
+const ModeEnv = "CLOUDQUERY_VCR_MODE"
+
+// Mode of a Recorder
+type Mode string
+
+const (
+	// ModeReplay answers requests from the cassette
+	ModeReplay Mode = "replay"
+	// ModeRecord sends requests to the cloud and saves them into the cassette
+	ModeRecord Mode = "record"
+)
+
+// Request is the recorded part of an HTTP request. It is also used to match requests during replay
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Interaction is a request and its response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette is the content of a cassette file
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper which records or replays interactions of a cassette file
+type Recorder struct {
+	mode      Mode
+	path      string
+	transport http.RoundTripper
+	mutex     sync.Mutex
+	cassette  Cassette
+	used      []bool
+	misses    []string
+}
+
+// recordedHeaders are the response headers saved in cassettes. Others (request ids, dates, ...) only add noise
+var recordedHeaders = []string{"Content-Type"}
+
+// GetMode returns the mode set in ModeEnv. Default is ModeReplay
+func GetMode() Mode {
+	if Mode(os.Getenv(ModeEnv)) == ModeRecord {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// New creates a recorder for the cassette at path. In ModeReplay the cassette must exist.
+// In ModeRecord requests are sent using http.DefaultTransport.
+func New(path string, mode Mode) (*Recorder, error) {
+	recorder := Recorder{
+		mode:      mode,
+		path:      path,
+		transport: http.DefaultTransport,
+	}
+	if mode == ModeRecord {
+		return &recorder, nil
+	}
+	jsonEncoded, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(jsonEncoded, &recorder.cassette); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	recorder.used = make([]bool, len(recorder.cassette.Interactions))
+	return &recorder, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	request := Request{
+		Method: req.Method,
+		URL:    normalizeURL(req.URL),
+		Body:   normalizeBody(req.Header.Get("Content-Type"), body),
+	}
+	if isTokenRequest(req) {
+		// Tokens are neither saved nor matched: recorded ones would be secrets, and requests signed with a
+		// client assertion are never the same
+		if recorder.mode == ModeRecord {
+			return recorder.transport.RoundTrip(req)
+		}
+		return newResponse(req, tokenResponse), nil
+	}
+	if recorder.mode == ModeRecord {
+		return recorder.record(req, request)
+	}
+	return recorder.replay(req, request)
+}
+
+func (recorder *Recorder) replay(req *http.Request, request Request) (*http.Response, error) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	// Interactions are used in order, the last matching one is reused for repeated requests
+	found := -1
+	for idx, interaction := range recorder.cassette.Interactions {
+		if interaction.Request != request {
+			continue
+		}
+		found = idx
+		if !recorder.used[idx] {
+			break
+		}
+	}
+	var response Response
+	if found < 0 {
+		// Answer with an error SDKs don't retry, Misses tells the test what is missing
+		miss := fmt.Sprintf("%s %s with body %q", request.Method, request.URL, request.Body)
+		recorder.misses = append(recorder.misses, miss)
+		response = Response{StatusCode: http.StatusNotFound, Body: "vcr: no interaction for " + miss + " in " + recorder.path}
+	} else {
+		recorder.used[found] = true
+		response = recorder.cassette.Interactions[found].Response
+	}
+	return newResponse(req, response), nil
+}
+
+func newResponse(req *http.Request, response Response) *http.Response {
+	headers := http.Header{}
+	for key, values := range response.Headers {
+		headers[http.CanonicalHeaderKey(key)] = values
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+		StatusCode:    response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        headers,
+		Body:          ioutil.NopCloser(strings.NewReader(response.Body)),
+		ContentLength: int64(len(response.Body)),
+		Request:       req,
+	}
+}
+
+func (recorder *Recorder) record(req *http.Request, request Request) (*http.Response, error) {
+	resp, err := recorder.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	response := Response{
+		StatusCode: resp.StatusCode,
+		Headers:    http.Header{},
+		Body:       string(body),
+	}
+	for _, key := range recordedHeaders {
+		if values, found := resp.Header[key]; found {
+			response.Headers[key] = values
+		}
+	}
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.cassette.Interactions = append(recorder.cassette.Interactions, Interaction{Request: request, Response: response})
+	return resp, nil
+}
+
+// Misses returns the requests which were not found in the cassette during replay
+func (recorder *Recorder) Misses() []string {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return append([]string{}, recorder.misses...)
+}
+
+// Stop saves the cassette in ModeRecord. It does nothing in ModeReplay
+func (recorder *Recorder) Stop() error {
+	if recorder.mode != ModeRecord {
+		return nil
+	}
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	jsonEncoded, err := json.MarshalIndent(recorder.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(recorder.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(recorder.path, append(jsonEncoded, '\n'), 0644)
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// normalizeURL sorts query parameters
+func normalizeURL(u *url.URL) string {
+	normalized := *u
+	normalized.RawQuery = u.Query().Encode()
+	return normalized.String()
+}
+
+// normalizeBody sorts form parameters (e.g. AWS query API) and removes surrounding white space
+func normalizeBody(contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-www-form-urlencoded" {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			return values.Encode()
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,95 @@
+/**
+ * Copyright (c) 2020-present, The cloudquery authors
+ *
+ * This source code is licensed as defined by the LICENSE file found in the
+ * root directory of this source tree.
+ *
+ * SPDX-License-Identifier: (Apache-2.0 OR GPL-2.0-only)
+ */
+
+package vcr
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Request-Id", "noise")
+		fmt.Fprintf(w, "%s %s %s call %d", r.Method, r.URL.Path, string(body), calls)
+	}))
+	defer server.Close()
+	path := t.TempDir() + "/cassette.json"
+
+	send := func(recorder *Recorder, query string, form url.Values) (int, string) {
+		client := http.Client{Transport: recorder}
+		var resp *http.Response
+		var err error
+		if form == nil {
+			resp, err = client.Get(server.URL + "/items?" + query)
+		} else {
+			resp, err = client.PostForm(server.URL+"/", form)
+		}
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	recorder, err := New(path, ModeRecord)
+	assert.Nil(t, err)
+	_, body := send(recorder, "b=2&a=1", nil)
+	assert.Equal(t, "GET /items  call 1", body)
+	_, body = send(recorder, "", url.Values{"Action": {"ListQueues"}, "Version": {"2012-11-05"}})
+	assert.Equal(t, "POST / Action=ListQueues&Version=2012-11-05 call 2", body)
+	_, body = send(recorder, "b=2&a=1", nil)
+	assert.Equal(t, "GET /items  call 3", body)
+	assert.Nil(t, recorder.Stop())
+
+	cassette, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(cassette), "X-Request-Id")
+
+	recorder, err = New(path, ModeReplay)
+	assert.Nil(t, err)
+	// Query and form parameters are matched in any order, interactions are used in order
+	_, body = send(recorder, "a=1&b=2", nil)
+	assert.Equal(t, "GET /items  call 1", body)
+	_, body = send(recorder, "b=2&a=1", nil)
+	assert.Equal(t, "GET /items  call 3", body)
+	_, body = send(recorder, "a=1&b=2", nil)
+	assert.Equal(t, "GET /items  call 3", body)
+	_, body = send(recorder, "", url.Values{"Version": {"2012-11-05"}, "Action": {"ListQueues"}})
+	assert.Equal(t, "POST / Action=ListQueues&Version=2012-11-05 call 2", body)
+	assert.Empty(t, recorder.Misses())
+	assert.Equal(t, 3, calls)
+
+	status, body := send(recorder, "a=3", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.True(t, strings.HasPrefix(body, "vcr: no interaction for GET"))
+	assert.Equal(t, 1, len(recorder.Misses()))
+
+	// Token requests are answered without cassette
+	resp, err := (&http.Client{Transport: recorder}).PostForm("https://oauth2.googleapis.com/token", url.Values{"grant_type": {"refresh_token"}})
+	assert.Nil(t, err)
+	token, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(token), `"access_token": "replay"`)
+	assert.Equal(t, 1, len(recorder.Misses()))
+
+	_, err = New(t.TempDir()+"/missing.json", ModeReplay)
+	assert.NotNil(t, err)
+}